@@ -0,0 +1,387 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/RishiKendai/aegis/internal/configs/env"
+	"github.com/RishiKendai/aegis/internal/models"
+	"github.com/RishiKendai/aegis/internal/preprocess"
+	"github.com/RishiKendai/aegis/similarity"
+)
+
+const (
+	// maxRegionsShown bounds the matched regions printed per pair in table output
+	maxRegionsShown = 5
+
+	compareModeAstra = "astra"
+	compareModeLocal = "local"
+)
+
+// compareSource is one submission file loaded for an offline comparison
+type compareSource struct {
+	path       string
+	question   string
+	artifact   *models.Artifact
+	tokenLines []int // nil when lines are unknown (Astra tokens)
+}
+
+// compareRegion is a matched token run, with source lines when known
+type compareRegion struct {
+	TokensA [2]int `json:"tokensA"`
+	TokensB [2]int `json:"tokensB"`
+	LinesA  [2]int `json:"linesA,omitempty"`
+	LinesB  [2]int `json:"linesB,omitempty"`
+	Length  int    `json:"length"`
+}
+
+// comparePair is one scored pair of the report
+type comparePair struct {
+	Question        string                  `json:"question"`
+	Language        string                  `json:"language"`
+	FileA           string                  `json:"fileA"`
+	FileB           string                  `json:"fileB"`
+	FinalScore      float64                 `json:"final_score"`
+	Scores          models.LayerScores      `json:"scores"`
+	Containment     models.LayerContainment `json:"containment"`
+	Risk            string                  `json:"risk"`
+	FunctionMatches []models.FunctionMatch  `json:"function_matches"`
+	Normalization   []string                `json:"normalization,omitempty"`
+	Regions         []compareRegion         `json:"regions"`
+}
+
+// compareReport is the JSON output of aegis compare
+type compareReport struct {
+	Mode     string           `json:"mode"`
+	Profile  string           `json:"profile"`
+	Pairs    []comparePair    `json:"pairs"`
+	Clusters []models.Cluster `json:"clusters"`
+}
+
+// runCompare implements `aegis compare`: it preprocesses two files or a directory laid out
+// as <question>/<candidate>.<ext>, runs the cascade and prints scores, regions and clusters
+func runCompare(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("compare", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: aegis compare [flags] <fileA> <fileB>")
+		fmt.Fprintln(stderr, "       aegis compare [flags] <dir>   (layout: <question>/<candidate>.<ext>)")
+		flags.PrintDefaults()
+	}
+
+	format := flags.String("format", "table", "output format: table or json")
+	difficulty := flags.String("difficulty", "medium", "scoring profile: easy, medium or hard")
+	allPairs := flags.Bool("all", false, "compare every pair in a question instead of worthy pairs only")
+	astraURL := flags.String("astra-url", env.GetEnv("ASTRA_BASE_URL", ""), "Astra base URL; when empty, a built-in lexical preprocessor is used")
+	astraKey := flags.String("astra-key", env.GetEnv("ASTRA_API_KEY", ""), "Astra API key")
+	normalize := flags.String("normalize", "all", "normalization passes: all, none or a list of loops,commutative,dead_code,declaration_order")
+
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if *format != "table" && *format != "json" {
+		fmt.Fprintf(stderr, "unknown format %q\n", *format)
+		return 2
+	}
+	passes, err := similarity.ParseNormalizationPasses(*normalize)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+
+	sources, err := collectCompareSources(flags.Args())
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		flags.Usage()
+		return 2
+	}
+
+	mode := compareModeLocal
+	var astraClient *preprocess.AstraClient
+	if *astraURL != "" {
+		mode = compareModeAstra
+		astraClient = preprocess.NewAstraClient(*astraURL, *astraKey)
+	}
+
+	ctx := context.Background()
+	for _, source := range sources {
+		if err := preprocessSource(ctx, source, *difficulty, astraClient); err != nil {
+			fmt.Fprintf(stderr, "failed to preprocess %s: %v\n", source.path, err)
+			return 1
+		}
+	}
+
+	profile := similarity.DifficultyProfile(*difficulty)
+	profile.Normalization = passes
+	if mode == compareModeLocal {
+		// The local preprocessor produces no AST or CFG
+		profile = profile.LexicalOnly()
+	}
+
+	report := buildCompareReport(sources, profile, *difficulty, *allPairs || len(flags.Args()) == 2)
+	report.Mode = mode
+
+	if *format == "json" {
+		encoder := json.NewEncoder(stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(report); err != nil {
+			fmt.Fprintln(stderr, err)
+			return 1
+		}
+		return 0
+	}
+
+	printCompareTable(stdout, report)
+	return 0
+}
+
+// collectCompareSources resolves the command arguments to submission files
+func collectCompareSources(args []string) ([]*compareSource, error) {
+	switch len(args) {
+	case 2:
+		return []*compareSource{
+			{path: args[0], question: "-"},
+			{path: args[1], question: "-"},
+		}, nil
+	case 1:
+		root := args[0]
+		files, err := filepath.Glob(filepath.Join(root, "*", "*"))
+		if err != nil {
+			return nil, err
+		}
+
+		sources := make([]*compareSource, 0, len(files))
+		for _, file := range files {
+			info, err := os.Stat(file)
+			if err != nil || info.IsDir() {
+				continue
+			}
+			if _, err := preprocess.LanguageFromPath(file); err != nil {
+				continue
+			}
+			sources = append(sources, &compareSource{
+				path:     file,
+				question: filepath.Base(filepath.Dir(file)),
+			})
+		}
+		if len(sources) < 2 {
+			return nil, fmt.Errorf("found fewer than two submissions under %s", root)
+		}
+
+		sort.Slice(sources, func(i, j int) bool { return sources[i].path < sources[j].path })
+		return sources, nil
+	default:
+		return nil, fmt.Errorf("expected two files or one directory")
+	}
+}
+
+// preprocessSource reads a file and fills in its artifact via Astra or the local preprocessor
+func preprocessSource(ctx context.Context, source *compareSource, difficulty string, astraClient *preprocess.AstraClient) error {
+	code, err := os.ReadFile(source.path)
+	if err != nil {
+		return err
+	}
+	language, err := preprocess.LanguageFromPath(source.path)
+	if err != nil {
+		return err
+	}
+
+	candidate := strings.TrimSuffix(filepath.Base(source.path), filepath.Ext(source.path))
+	artifact := &models.Artifact{
+		Email:      candidate,
+		AttemptID:  source.path,
+		Difficulty: difficulty,
+		SourceCode: string(code),
+		Language:   language,
+	}
+
+	if astraClient != nil {
+		resp, err := astraClient.Preprocess(ctx, &preprocess.PreprocessRequest{
+			EmailID:   candidate,
+			AttemptID: source.path,
+			Code:      string(code),
+			Language:  language,
+		})
+		if err != nil {
+			return err
+		}
+		artifact.Tokens = resp.Preprocessing.Tokens
+		artifact.NormalizedTokens = resp.Preprocessing.NormalizedTokens
+		artifact.AST = resp.Preprocessing.AST
+		artifact.CFG = resp.Preprocessing.CFG
+		artifact.Fingerprints = resp.Preprocessing.Fingerprints
+	} else {
+		local := preprocess.PreprocessLocally(string(code), language)
+		artifact.Tokens = local.Data.Tokens
+		artifact.NormalizedTokens = local.Data.NormalizedTokens
+		artifact.Fingerprints = local.Data.Fingerprints
+		source.tokenLines = local.TokenLines
+	}
+
+	source.artifact = artifact
+	return nil
+}
+
+// buildCompareReport scores pairs per question and language and detects clusters
+func buildCompareReport(sources []*compareSource, profile similarity.Profile, difficulty string, allPairs bool) *compareReport {
+	byAttempt := make(map[string]*compareSource, len(sources))
+	buckets := make(map[string][]*models.Artifact)
+	bucketKeys := make([]string, 0)
+	for _, source := range sources {
+		byAttempt[source.artifact.AttemptID] = source
+		key := source.question + "\x00" + source.artifact.Language
+		if _, exists := buckets[key]; !exists {
+			bucketKeys = append(bucketKeys, key)
+		}
+		buckets[key] = append(buckets[key], source.artifact)
+	}
+	sort.Strings(bucketKeys)
+
+	features := similarity.NewFeatureCache(profile.Normalization...)
+	report := &compareReport{
+		Profile:  profile.Name,
+		Pairs:    make([]comparePair, 0),
+		Clusters: []models.Cluster{},
+	}
+	significant := make([]similarity.PairSimilarity, 0)
+
+	for _, key := range bucketKeys {
+		artifacts := buckets[key]
+		var pairs []similarity.Pair
+		if allPairs {
+			for i := 0; i < len(artifacts); i++ {
+				for j := i + 1; j < len(artifacts); j++ {
+					pairs = append(pairs, similarity.Pair{ArtifactA: artifacts[i], ArtifactB: artifacts[j]})
+				}
+			}
+		} else {
+			gii := similarity.BuildGII(artifacts, profile.Normalization...)
+			pairs = similarity.GetWorthyPairs(gii, artifacts, difficulty, profile.Normalization...)
+		}
+
+		for _, pair := range pairs {
+			sourceA := byAttempt[pair.ArtifactA.AttemptID]
+			sourceB := byAttempt[pair.ArtifactB.AttemptID]
+			result := similarity.CascadePipeline(features.Get(pair.ArtifactA), features.Get(pair.ArtifactB), profile)
+
+			normalization := make([]string, 0, len(result.Normalization))
+			for _, pass := range result.Normalization {
+				normalization = append(normalization, string(pass))
+			}
+
+			report.Pairs = append(report.Pairs, comparePair{
+				Question:   sourceA.question,
+				Language:   pair.ArtifactA.Language,
+				FileA:      sourceA.path,
+				FileB:      sourceB.path,
+				FinalScore: result.FinalScore,
+				Scores: models.LayerScores{
+					Fingerprint: result.Scores.Fingerprint,
+					Token:       result.Scores.Token,
+					AST:         result.Scores.AST,
+					CFG:         result.Scores.CFG,
+				},
+				Containment:     result.Containment,
+				Risk:            similarity.GetRiskLevel(result.FinalScore),
+				FunctionMatches: result.FunctionMatches,
+				Normalization:   normalization,
+				Regions:         matchedRegions(sourceA, sourceB),
+			})
+
+			if result.FinalScore >= similarity.SignificantSimilarityThreshold {
+				significant = append(significant, similarity.PairSimilarity{
+					ArtifactA:  pair.ArtifactA,
+					ArtifactB:  pair.ArtifactB,
+					FinalScore: result.FinalScore,
+					QID:        sourceA.question,
+					Language:   pair.ArtifactA.Language,
+					Difficulty: difficulty,
+				})
+			}
+		}
+	}
+
+	sort.SliceStable(report.Pairs, func(i, j int) bool {
+		return report.Pairs[i].FinalScore > report.Pairs[j].FinalScore
+	})
+	report.Clusters = similarity.DetectClusters(significant)
+
+	return report
+}
+
+// matchedRegions maps GST tiles to token ranges and, when known, source lines
+func matchedRegions(sourceA, sourceB *compareSource) []compareRegion {
+	tiles := similarity.MatchedTiles(sourceA.artifact.NormalizedTokens, sourceB.artifact.NormalizedTokens)
+
+	regions := make([]compareRegion, 0, len(tiles))
+	for _, tile := range tiles {
+		region := compareRegion{
+			TokensA: [2]int{tile.StartA, tile.StartA + tile.Length - 1},
+			TokensB: [2]int{tile.StartB, tile.StartB + tile.Length - 1},
+			Length:  tile.Length,
+		}
+		if sourceA.tokenLines != nil && sourceB.tokenLines != nil {
+			region.LinesA = [2]int{sourceA.tokenLines[region.TokensA[0]], sourceA.tokenLines[region.TokensA[1]]}
+			region.LinesB = [2]int{sourceB.tokenLines[region.TokensB[0]], sourceB.tokenLines[region.TokensB[1]]}
+		}
+		regions = append(regions, region)
+	}
+	return regions
+}
+
+// printCompareTable renders the report for a terminal
+func printCompareTable(w io.Writer, report *compareReport) {
+	fmt.Fprintf(w, "Mode: %s, profile: %s\n\n", report.Mode, report.Profile)
+
+	table := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(table, "QUESTION\tFILE A\tFILE B\tFINAL\tFP\tTOKEN\tAST\tCFG\tRISK")
+	for _, pair := range report.Pairs {
+		fmt.Fprintf(table, "%s\t%s\t%s\t%.2f\t%.2f\t%.2f\t%.2f\t%.2f\t%s\n",
+			pair.Question, pair.FileA, pair.FileB, pair.FinalScore,
+			pair.Scores.Fingerprint, pair.Scores.Token, pair.Scores.AST, pair.Scores.CFG, pair.Risk)
+	}
+	table.Flush()
+
+	for _, pair := range report.Pairs {
+		if pair.FinalScore < similarity.SignificantSimilarityThreshold || len(pair.Regions) == 0 {
+			continue
+		}
+		fmt.Fprintf(w, "\nMatched regions: %s <-> %s\n", pair.FileA, pair.FileB)
+		for i, region := range pair.Regions {
+			if i == maxRegionsShown {
+				fmt.Fprintf(w, "  ... %d more\n", len(pair.Regions)-maxRegionsShown)
+				break
+			}
+			if region.LinesA != [2]int{} {
+				fmt.Fprintf(w, "  lines %d-%d <-> lines %d-%d (%d tokens)\n",
+					region.LinesA[0], region.LinesA[1], region.LinesB[0], region.LinesB[1], region.Length)
+			} else {
+				fmt.Fprintf(w, "  tokens %d-%d <-> tokens %d-%d (%d tokens)\n",
+					region.TokensA[0], region.TokensA[1], region.TokensB[0], region.TokensB[1], region.Length)
+			}
+		}
+		for _, match := range pair.FunctionMatches {
+			fmt.Fprintf(w, "  function %s <-> %s (%.2f)\n", match.FunctionA, match.FunctionB, match.Score)
+		}
+		if len(pair.Normalization) > 0 {
+			fmt.Fprintf(w, "  normalized: %s\n", strings.Join(pair.Normalization, ", "))
+		}
+	}
+
+	if len(report.Clusters) > 0 {
+		fmt.Fprintln(w, "\nClusters:")
+		for _, cluster := range report.Clusters {
+			fmt.Fprintf(w, "  %s [%s] avg %.2f, source %s: %s\n",
+				cluster.ClusterID, cluster.Shape, cluster.AverageScore, cluster.LikelySource,
+				strings.Join(cluster.MemberEmails, ", "))
+		}
+	}
+}
@@ -0,0 +1,532 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/RishiKendai/aegis/internal/configs/env"
+	"github.com/RishiKendai/aegis/internal/preprocess"
+	"github.com/RishiKendai/aegis/similarity"
+)
+
+const (
+	evalLabelPlagiarised = "plagiarised"
+	evalLabelOriginal    = "original"
+)
+
+// evalCase is one labelled pair of the dataset (one JSON object per line)
+type evalCase struct {
+	A           string `json:"a"`
+	B           string `json:"b"`
+	Label       string `json:"label"`       // plagiarised or original
+	Obfuscation string `json:"obfuscation"` // e.g. rename, reorder, dead_code; empty for originals
+}
+
+// evalScore holds the scores of one case under one profile
+type evalScore struct {
+	positive    bool
+	obfuscation string
+	final       float64
+	layers      [4]float64 // fingerprint, token, ast, cfg (uncascaded, after the profile's normalization)
+}
+
+// evalLayerReport describes how much one layer separates the two classes
+type evalLayerReport struct {
+	Layer              string  `json:"layer"`
+	Weight             float64 `json:"weight"`
+	AUC                float64 `json:"auc"`
+	MeanPlagiarised    float64 `json:"mean_plagiarised"`
+	MeanOriginal       float64 `json:"mean_original"`
+	WeightedSeparation float64 `json:"weighted_separation"` // weight * (mean_plagiarised - mean_original)
+}
+
+// evalProfileReport holds the metrics of one scoring profile
+type evalProfileReport struct {
+	Profile             string             `json:"profile"`
+	Threshold           float64            `json:"threshold"`
+	TP                  int                `json:"tp"`
+	FP                  int                `json:"fp"`
+	TN                  int                `json:"tn"`
+	FN                  int                `json:"fn"`
+	Precision           float64            `json:"precision"`
+	Recall              float64            `json:"recall"`
+	F1                  float64            `json:"f1"`
+	AUC                 float64            `json:"auc"`
+	ROC                 []evalROCPoint     `json:"roc"`
+	RecallByObfuscation map[string]float64 `json:"recall_by_obfuscation"`
+	Layers              []evalLayerReport  `json:"layers"`
+
+	// Suggested threshold for the target false-positive rate
+	TargetFPR          float64 `json:"target_fpr"`
+	SuggestedThreshold float64 `json:"suggested_threshold"`
+	SuggestedRecall    float64 `json:"suggested_recall"`
+}
+
+// evalROCPoint is one point of the ROC curve
+type evalROCPoint struct {
+	Threshold float64 `json:"threshold"`
+	FPR       float64 `json:"fpr"`
+	TPR       float64 `json:"tpr"`
+}
+
+// evalScoreBasis describes the scores eval measures. The service flags pairs through
+// per-question calibration before its threshold; a labelled dataset has no question
+// buckets to calibrate against, so eval measures the raw cascade score at one threshold.
+const evalScoreBasis = "raw"
+
+// evalReport is the JSON output of aegis eval
+type evalReport struct {
+	Mode       string              `json:"mode"`
+	ScoreBasis string              `json:"score_basis"` // always raw: uncalibrated cascade scores at one global threshold
+	Cases      int                 `json:"cases"`
+	Positive   int                 `json:"plagiarised"`
+	Negative   int                 `json:"original"`
+	Profiles   []evalProfileReport `json:"profiles"`
+}
+
+// runEval implements `aegis eval`: it scores a labelled dataset of pairs under one or more
+// profiles and reports precision, recall, F1, ROC/AUC, per-layer contribution and a
+// threshold that meets a target false-positive rate
+func runEval(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("eval", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: aegis eval [flags] <dataset.jsonl>")
+		fmt.Fprintln(stderr, `Each line: {"a": "path", "b": "path", "label": "plagiarised|original", "obfuscation": "rename"}`)
+		fmt.Fprintln(stderr, "Paths are relative to the dataset file.")
+		flags.PrintDefaults()
+	}
+
+	format := flags.String("format", "table", "output format: table or json")
+	profileNames := flags.String("profiles", "easy,medium,hard", "comma-separated built-in profiles to evaluate")
+	profileFile := flags.String("profile-file", "", "JSON file with additional profiles ([]Profile)")
+	threshold := flags.Float64("threshold", similarity.SignificantSimilarityThreshold, "score at or above which a pair is flagged")
+	targetFPR := flags.Float64("target-fpr", 0.01, "false-positive rate the suggested threshold must not exceed")
+	astraURL := flags.String("astra-url", env.GetEnv("ASTRA_BASE_URL", ""), "Astra base URL; when empty, a built-in lexical preprocessor is used")
+	astraKey := flags.String("astra-key", env.GetEnv("ASTRA_API_KEY", ""), "Astra API key")
+	normalize := flags.String("normalize", "", "override the normalization passes of every profile: all, none or a comma-separated list")
+
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if *format != "table" && *format != "json" {
+		fmt.Fprintf(stderr, "unknown format %q\n", *format)
+		return 2
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return 2
+	}
+
+	cases, err := loadEvalCases(flags.Arg(0))
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+
+	profiles, err := loadEvalProfiles(*profileNames, *profileFile)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+
+	if *normalize != "" {
+		passes, err := similarity.ParseNormalizationPasses(*normalize)
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			return 2
+		}
+		for i := range profiles {
+			profiles[i].Normalization = passes
+		}
+	}
+
+	mode := compareModeLocal
+	var astraClient *preprocess.AstraClient
+	if *astraURL != "" {
+		mode = compareModeAstra
+		astraClient = preprocess.NewAstraClient(*astraURL, *astraKey)
+	}
+
+	// Preprocess every file once
+	ctx := context.Background()
+	sources := make(map[string]*compareSource)
+	for _, c := range cases {
+		for _, path := range []string{c.A, c.B} {
+			if _, done := sources[path]; done {
+				continue
+			}
+			source := &compareSource{path: path}
+			if err := preprocessSource(ctx, source, "", astraClient); err != nil {
+				fmt.Fprintf(stderr, "failed to preprocess %s: %v\n", path, err)
+				return 1
+			}
+			sources[path] = source
+		}
+	}
+
+	report := &evalReport{
+		Mode:       mode,
+		ScoreBasis: evalScoreBasis,
+		Cases:      len(cases),
+		Profiles:   make([]evalProfileReport, 0, len(profiles)),
+	}
+	for _, c := range cases {
+		if c.Label == evalLabelPlagiarised {
+			report.Positive++
+		} else {
+			report.Negative++
+		}
+	}
+
+	for _, profile := range profiles {
+		if mode == compareModeLocal {
+			profile = profile.LexicalOnly()
+		}
+		features := similarity.NewFeatureCache(profile.Normalization...)
+
+		scores := make([]evalScore, 0, len(cases))
+		for _, c := range cases {
+			featuresA := features.Get(sources[c.A].artifact)
+			featuresB := features.Get(sources[c.B].artifact)
+			result := similarity.CascadePipeline(featuresA, featuresB, profile)
+			layers := similarity.LayerScores(featuresA, featuresB)
+			scores = append(scores, evalScore{
+				positive:    c.Label == evalLabelPlagiarised,
+				obfuscation: c.Obfuscation,
+				final:       result.FinalScore,
+				layers:      [4]float64{layers.Fingerprint, layers.Token, layers.AST, layers.CFG},
+			})
+		}
+
+		report.Profiles = append(report.Profiles, evaluateProfile(profile, scores, *threshold, *targetFPR))
+	}
+
+	if *format == "json" {
+		encoder := json.NewEncoder(stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(report); err != nil {
+			fmt.Fprintln(stderr, err)
+			return 1
+		}
+		return 0
+	}
+
+	printEvalTable(stdout, report)
+	return 0
+}
+
+// loadEvalCases reads the JSONL dataset and resolves paths against its directory
+func loadEvalCases(path string) ([]evalCase, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open dataset: %w", err)
+	}
+	defer file.Close()
+
+	baseDir := filepath.Dir(path)
+	cases := make([]evalCase, 0)
+	scanner := bufio.NewScanner(file)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		var c evalCase
+		if err := json.Unmarshal([]byte(line), &c); err != nil {
+			return nil, fmt.Errorf("dataset line %d: %w", lineNumber, err)
+		}
+		if c.Label != evalLabelPlagiarised && c.Label != evalLabelOriginal {
+			return nil, fmt.Errorf("dataset line %d: label must be %q or %q", lineNumber, evalLabelPlagiarised, evalLabelOriginal)
+		}
+		if !filepath.IsAbs(c.A) {
+			c.A = filepath.Join(baseDir, c.A)
+		}
+		if !filepath.IsAbs(c.B) {
+			c.B = filepath.Join(baseDir, c.B)
+		}
+		cases = append(cases, c)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read dataset: %w", err)
+	}
+	if len(cases) == 0 {
+		return nil, fmt.Errorf("dataset %s is empty", path)
+	}
+
+	return cases, nil
+}
+
+// loadEvalProfiles returns the named built-in profiles followed by any from profileFile
+func loadEvalProfiles(names, profileFile string) ([]similarity.Profile, error) {
+	profiles := make([]similarity.Profile, 0)
+	for _, name := range strings.Split(names, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		switch name {
+		case "easy", "medium", "hard":
+			profiles = append(profiles, similarity.DifficultyProfile(name))
+		default:
+			return nil, fmt.Errorf("unknown built-in profile %q", name)
+		}
+	}
+
+	if profileFile != "" {
+		custom, err := similarity.LoadProfiles(profileFile)
+		if err != nil {
+			return nil, err
+		}
+		profiles = append(profiles, custom...)
+	}
+
+	if len(profiles) == 0 {
+		return nil, fmt.Errorf("no profiles to evaluate")
+	}
+	return profiles, nil
+}
+
+// evaluateProfile computes classification metrics, ROC/AUC and layer contributions
+func evaluateProfile(profile similarity.Profile, scores []evalScore, threshold, targetFPR float64) evalProfileReport {
+	report := evalProfileReport{
+		Profile:             profile.Name,
+		Threshold:           threshold,
+		RecallByObfuscation: make(map[string]float64),
+		TargetFPR:           targetFPR,
+	}
+
+	finals := make([]float64, len(scores))
+	labels := make([]bool, len(scores))
+	for i, score := range scores {
+		finals[i] = score.final
+		labels[i] = score.positive
+	}
+
+	report.TP, report.FP, report.TN, report.FN = confusion(finals, labels, threshold)
+	report.Precision = safeRatio(report.TP, report.TP+report.FP)
+	report.Recall = safeRatio(report.TP, report.TP+report.FN)
+	if report.Precision+report.Recall > 0 {
+		report.F1 = 2 * report.Precision * report.Recall / (report.Precision + report.Recall)
+	}
+	report.ROC = rocCurve(finals, labels)
+	report.AUC = rocAUC(finals, labels)
+	report.SuggestedThreshold, report.SuggestedRecall = thresholdForFPR(finals, labels, targetFPR)
+
+	// Recall per obfuscation type
+	detected := make(map[string]int)
+	total := make(map[string]int)
+	for _, score := range scores {
+		if !score.positive {
+			continue
+		}
+		obfuscation := score.obfuscation
+		if obfuscation == "" {
+			obfuscation = "none"
+		}
+		total[obfuscation]++
+		if score.final >= threshold {
+			detected[obfuscation]++
+		}
+	}
+	for obfuscation, count := range total {
+		report.RecallByObfuscation[obfuscation] = safeRatio(detected[obfuscation], count)
+	}
+
+	// Per-layer contribution, from uncascaded layer scores
+	layerNames := [4]string{"fingerprint", "token", "ast", "cfg"}
+	layerWeights := [4]float64{profile.Weights.Fingerprint, profile.Weights.Token, profile.Weights.AST, profile.Weights.CFG}
+	for layer := 0; layer < 4; layer++ {
+		values := make([]float64, len(scores))
+		sumPositive, sumNegative := 0.0, 0.0
+		positives, negatives := 0, 0
+		for i, score := range scores {
+			values[i] = score.layers[layer]
+			if score.positive {
+				sumPositive += score.layers[layer]
+				positives++
+			} else {
+				sumNegative += score.layers[layer]
+				negatives++
+			}
+		}
+
+		layerReport := evalLayerReport{
+			Layer:  layerNames[layer],
+			Weight: layerWeights[layer],
+			AUC:    rocAUC(values, labels),
+		}
+		if positives > 0 {
+			layerReport.MeanPlagiarised = sumPositive / float64(positives)
+		}
+		if negatives > 0 {
+			layerReport.MeanOriginal = sumNegative / float64(negatives)
+		}
+		layerReport.WeightedSeparation = layerReport.Weight * (layerReport.MeanPlagiarised - layerReport.MeanOriginal)
+		report.Layers = append(report.Layers, layerReport)
+	}
+
+	return report
+}
+
+func confusion(scores []float64, labels []bool, threshold float64) (tp, fp, tn, fn int) {
+	for i, score := range scores {
+		flagged := score >= threshold
+		switch {
+		case flagged && labels[i]:
+			tp++
+		case flagged && !labels[i]:
+			fp++
+		case !flagged && labels[i]:
+			fn++
+		default:
+			tn++
+		}
+	}
+	return tp, fp, tn, fn
+}
+
+// rocCurve returns one point per distinct score, from the strictest threshold down
+func rocCurve(scores []float64, labels []bool) []evalROCPoint {
+	thresholds := distinctScoresDescending(scores)
+	positives, negatives := classCounts(labels)
+
+	// Start above the highest possible score, where nothing is flagged
+	points := []evalROCPoint{{Threshold: math.Nextafter(1.0, 2.0), FPR: 0, TPR: 0}}
+	for _, threshold := range thresholds {
+		tp, fp, _, _ := confusion(scores, labels, threshold)
+		points = append(points, evalROCPoint{
+			Threshold: threshold,
+			FPR:       safeRatio(fp, negatives),
+			TPR:       safeRatio(tp, positives),
+		})
+	}
+	return points
+}
+
+// rocAUC is the probability that a random plagiarised pair outscores a random
+// original one (ties count half), i.e. the Mann-Whitney U statistic
+func rocAUC(scores []float64, labels []bool) float64 {
+	positives, negatives := classCounts(labels)
+	if positives == 0 || negatives == 0 {
+		return 0.0
+	}
+
+	wins := 0.0
+	for i := range scores {
+		if !labels[i] {
+			continue
+		}
+		for j := range scores {
+			if labels[j] {
+				continue
+			}
+			switch {
+			case scores[i] > scores[j]:
+				wins++
+			case scores[i] == scores[j]:
+				wins += 0.5
+			}
+		}
+	}
+	return wins / float64(positives*negatives)
+}
+
+// thresholdForFPR returns the lowest threshold whose false-positive rate stays within
+// target, and the recall it achieves
+func thresholdForFPR(scores []float64, labels []bool, target float64) (float64, float64) {
+	positives, negatives := classCounts(labels)
+
+	bestThreshold, bestRecall := math.Nextafter(1.0, 2.0), 0.0
+	for _, threshold := range distinctScoresDescending(scores) {
+		tp, fp, _, _ := confusion(scores, labels, threshold)
+		if safeRatio(fp, negatives) > target {
+			break
+		}
+		bestThreshold = threshold
+		bestRecall = safeRatio(tp, positives)
+	}
+	return bestThreshold, bestRecall
+}
+
+func distinctScoresDescending(scores []float64) []float64 {
+	seen := make(map[float64]bool)
+	distinct := make([]float64, 0, len(scores))
+	for _, score := range scores {
+		if !seen[score] {
+			seen[score] = true
+			distinct = append(distinct, score)
+		}
+	}
+	sort.Sort(sort.Reverse(sort.Float64Slice(distinct)))
+	return distinct
+}
+
+func classCounts(labels []bool) (positives, negatives int) {
+	for _, label := range labels {
+		if label {
+			positives++
+		} else {
+			negatives++
+		}
+	}
+	return positives, negatives
+}
+
+func safeRatio(numerator, denominator int) float64 {
+	if denominator == 0 {
+		return 0.0
+	}
+	return float64(numerator) / float64(denominator)
+}
+
+// printEvalTable renders the evaluation for a terminal
+func printEvalTable(w io.Writer, report *evalReport) {
+	fmt.Fprintf(w, "Mode: %s, cases: %d (%d plagiarised, %d original)\n",
+		report.Mode, report.Cases, report.Positive, report.Negative)
+	fmt.Fprintln(w, "Scores: raw, uncalibrated cascade scores at one global threshold; the service calibrates per question before flagging")
+	fmt.Fprintln(w)
+
+	table := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(table, "PROFILE\tTHRESHOLD\tPRECISION\tRECALL\tF1\tAUC\tTP\tFP\tTN\tFN\tSUGGESTED\tRECALL@SUGGESTED")
+	for _, p := range report.Profiles {
+		fmt.Fprintf(table, "%s\t%.2f\t%.3f\t%.3f\t%.3f\t%.3f\t%d\t%d\t%d\t%d\t%.3f\t%.3f\n",
+			p.Profile, p.Threshold, p.Precision, p.Recall, p.F1, p.AUC, p.TP, p.FP, p.TN, p.FN,
+			p.SuggestedThreshold, p.SuggestedRecall)
+	}
+	table.Flush()
+
+	for _, p := range report.Profiles {
+		fmt.Fprintf(w, "\nProfile %s (suggested threshold for FPR <= %.3f: %.3f)\n", p.Profile, p.TargetFPR, p.SuggestedThreshold)
+
+		layers := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(layers, "  LAYER\tWEIGHT\tAUC\tMEAN PLAGIARISED\tMEAN ORIGINAL\tWEIGHTED SEPARATION")
+		for _, layer := range p.Layers {
+			fmt.Fprintf(layers, "  %s\t%.2f\t%.3f\t%.3f\t%.3f\t%.3f\n",
+				layer.Layer, layer.Weight, layer.AUC, layer.MeanPlagiarised, layer.MeanOriginal, layer.WeightedSeparation)
+		}
+		layers.Flush()
+
+		obfuscations := make([]string, 0, len(p.RecallByObfuscation))
+		for obfuscation := range p.RecallByObfuscation {
+			obfuscations = append(obfuscations, obfuscation)
+		}
+		sort.Strings(obfuscations)
+		for _, obfuscation := range obfuscations {
+			fmt.Fprintf(w, "  recall[%s] = %.3f\n", obfuscation, p.RecallByObfuscation[obfuscation])
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/RishiKendai/aegis/internal/configs/env"
+	"github.com/RishiKendai/aegis/internal/infra/mongo"
+	"github.com/RishiKendai/aegis/internal/plagiarism"
+	"github.com/RishiKendai/aegis/internal/repository"
+)
+
+// runExport streams a drive's candidate results or pair records as CSV or JSON Lines,
+// or writes one question as a JPlag result archive
+func runExport(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("export", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: aegis export -drive <driveId> [flags] candidates|pairs")
+		fmt.Fprintln(stderr, "       aegis export -drive <driveId> -qid <qId> [flags] jplag")
+		flags.PrintDefaults()
+	}
+
+	driveID := flags.String("drive", "", "drive to export")
+	format := flags.String("format", plagiarism.ExportFormatCSV, "candidates and pairs format: csv or jsonl")
+	qID := flags.Int64("qid", 0, "question of a jplag archive")
+	language := flags.String("language", "", "language of a jplag archive (default: the question's most common)")
+	out := flags.String("out", "", "write the export to this file (default: stdout)")
+	mongoURI := flags.String("mongo-uri", env.GetEnv("MONGODB_URI", ""), "MongoDB URI")
+	mongoDB := flags.String("mongo-db", env.GetEnv("MONGODB_DB_NAME", ""), "MongoDB database")
+
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() != 1 || *driveID == "" {
+		flags.Usage()
+		return 2
+	}
+	kind := flags.Arg(0)
+	switch kind {
+	case "candidates", "pairs":
+		if !plagiarism.IsValidExportFormat(*format) {
+			fmt.Fprintf(stderr, "unknown format %q\n", *format)
+			return 2
+		}
+	case "jplag":
+		if *qID == 0 {
+			fmt.Fprintln(stderr, "-qid is required for a jplag archive")
+			return 2
+		}
+	default:
+		fmt.Fprintf(stderr, "unknown export %q\n", kind)
+		flags.Usage()
+		return 2
+	}
+	if *mongoURI == "" || *mongoDB == "" {
+		fmt.Fprintln(stderr, "MongoDB URI and database are required (-mongo-uri, -mongo-db or MONGODB_URI, MONGODB_DB_NAME)")
+		return 2
+	}
+
+	ctx := context.Background()
+	mongoClient, err := mongo.NewClient(ctx, *mongoURI, *mongoDB)
+	if err != nil {
+		fmt.Fprintf(stderr, "failed to connect to MongoDB: %v\n", err)
+		return 1
+	}
+	defer mongoClient.Close(ctx)
+
+	mongoRepo := repository.NewMongoRepository(mongoClient)
+	resultsRepo := repository.NewResultsRepository(mongoRepo)
+
+	// The archive is built in memory so nothing is written for a question without submissions
+	var archive bytes.Buffer
+	if kind == "jplag" {
+		found, err := plagiarism.WriteJPlagArchive(ctx, repository.NewArtifactsRepository(mongoRepo), resultsRepo, *driveID, *qID, *language, &archive)
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			return 1
+		}
+		if !found {
+			fmt.Fprintf(stderr, "question %d of drive %s has no submissions\n", *qID, *driveID)
+			return 1
+		}
+	}
+
+	w := stdout
+	if *out != "" {
+		file, err := os.Create(*out)
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			return 1
+		}
+		defer file.Close()
+		w = file
+	}
+
+	switch kind {
+	case "candidates":
+		err = plagiarism.ExportCandidates(ctx, resultsRepo, *driveID, *format, w)
+	case "pairs":
+		err = plagiarism.ExportPairs(ctx, resultsRepo, *driveID, *format, w)
+	case "jplag":
+		_, err = archive.WriteTo(w)
+	}
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	return 0
+}
@@ -30,6 +30,22 @@ func main() {
 		log.Warn().Err(err).Msg("Failed to load .env file, continuing with system environment variables")
 	}
 
+	// Subcommands run without Redis or the HTTP API; tune, report and export read MongoDB
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "compare":
+			os.Exit(runCompare(os.Args[2:], os.Stdout, os.Stderr))
+		case "eval":
+			os.Exit(runEval(os.Args[2:], os.Stdout, os.Stderr))
+		case "tune":
+			os.Exit(runTune(os.Args[2:], os.Stdout, os.Stderr))
+		case "report":
+			os.Exit(runReport(os.Args[2:], os.Stdout, os.Stderr))
+		case "export":
+			os.Exit(runExport(os.Args[2:], os.Stdout, os.Stderr))
+		}
+	}
+
 	cfg, err := config.Load()
 	if err != nil {
 		panic(fmt.Sprintf("Failed to load config: %v", err))
@@ -83,13 +99,25 @@ func main() {
 	// Initialize repositories
 	artifactsRepo := repository.NewArtifactsRepository(mongoRepo)
 	resultsRepo := repository.NewResultsRepository(mongoRepo)
+	indexRepo := repository.NewFingerprintIndexRepository(mongoRepo)
+	referencesRepo := repository.NewReferencesRepository(mongoRepo)
+	reviewsRepo := repository.NewReviewsRepository(mongoRepo)
+	if err := indexRepo.EnsureIndexes(ctx); err != nil {
+		log.Warn().Err(err).Msg("Failed to ensure fingerprint index indexes")
+	}
 
 	// Initialize Astra client and preprocessing service
 	// astraClient := preprocess.NewAstraClient(cfg.AstraBaseURL, cfg.AstraAPIKey)
 	// Use test file instead of real API
 	astraClient := preprocess.NewAstraClient(cfg.AstraBaseURL, cfg.AstraAPIKey)
 
-	preprocessSvc := preprocess.NewService(astraClient, artifactsRepo)
+	aiReferences, err := preprocess.LoadAIReferenceCorpus(cfg.AIReferenceDir)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to load AI reference corpus")
+	}
+	log.Info().Int("references", aiReferences.Len()).Msg("Loaded AI reference corpus")
+
+	preprocessSvc := preprocess.NewService(astraClient, artifactsRepo, indexRepo, aiReferences, referencesRepo, cfg.NormalizationPassesFor)
 
 	// Initialize retry handler
 	retryHandler := stream.NewRetryHandler(redisClient.Client, cfg.RedisDeadLetterKey)
@@ -115,7 +143,7 @@ func main() {
 	workerPool := plagiarism.NewWorkerPool(ctx)
 	defer workerPool.Close()
 
-	router := api.SetupRoutes(cfg, artifactsRepo, resultsRepo, workerPool, redisClient)
+	router := api.SetupRoutes(cfg, artifactsRepo, resultsRepo, indexRepo, referencesRepo, reviewsRepo, preprocessSvc, workerPool, redisClient)
 
 	// Start Redis consumer in background
 	consumerCtx, consumerCancel := context.WithCancel(ctx)
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/RishiKendai/aegis/internal/configs/env"
+	"github.com/RishiKendai/aegis/internal/infra/mongo"
+	"github.com/RishiKendai/aegis/internal/plagiarism"
+	"github.com/RishiKendai/aegis/internal/repository"
+)
+
+// runReport renders the investigation report of a drive to a self-contained HTML file
+func runReport(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("report", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: aegis report -drive <driveId> [flags]")
+		fmt.Fprintln(stderr, "Renders a drive's investigation report as a self-contained HTML page, ready to print to PDF.")
+		flags.PrintDefaults()
+	}
+
+	driveID := flags.String("drive", "", "drive to report on")
+	out := flags.String("out", "", "write the report to this file (default: stdout)")
+	topPairs := flags.Int("top-pairs", plagiarism.DefaultReportTopPairs, "number of highest scoring pairs shown side by side")
+	mongoURI := flags.String("mongo-uri", env.GetEnv("MONGODB_URI", ""), "MongoDB URI")
+	mongoDB := flags.String("mongo-db", env.GetEnv("MONGODB_DB_NAME", ""), "MongoDB database")
+
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if *driveID == "" {
+		fmt.Fprintln(stderr, "-drive is required")
+		flags.Usage()
+		return 2
+	}
+	if *mongoURI == "" || *mongoDB == "" {
+		fmt.Fprintln(stderr, "MongoDB URI and database are required (-mongo-uri, -mongo-db or MONGODB_URI, MONGODB_DB_NAME)")
+		return 2
+	}
+
+	ctx := context.Background()
+	mongoClient, err := mongo.NewClient(ctx, *mongoURI, *mongoDB)
+	if err != nil {
+		fmt.Fprintf(stderr, "failed to connect to MongoDB: %v\n", err)
+		return 1
+	}
+	defer mongoClient.Close(ctx)
+
+	mongoRepo := repository.NewMongoRepository(mongoClient)
+	report, err := plagiarism.BuildDriveReport(ctx,
+		repository.NewArtifactsRepository(mongoRepo),
+		repository.NewResultsRepository(mongoRepo),
+		repository.NewReferencesRepository(mongoRepo),
+		*driveID, *topPairs)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	if report == nil {
+		fmt.Fprintf(stderr, "drive %s has no report\n", *driveID)
+		return 1
+	}
+
+	w := stdout
+	if *out != "" {
+		file, err := os.Create(*out)
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			return 1
+		}
+		defer file.Close()
+		w = file
+	}
+
+	if err := plagiarism.RenderDriveReportHTML(w, report); err != nil {
+		fmt.Fprintf(stderr, "failed to render report: %v\n", err)
+		return 1
+	}
+	return 0
+}
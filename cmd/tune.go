@@ -0,0 +1,259 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+
+	"github.com/RishiKendai/aegis/internal/configs/env"
+	"github.com/RishiKendai/aegis/internal/infra/mongo"
+	"github.com/RishiKendai/aegis/internal/models"
+	"github.com/RishiKendai/aegis/internal/repository"
+	"github.com/RishiKendai/aegis/similarity"
+)
+
+// tuneDifficulties are the difficulties a profile is proposed for
+var tuneDifficulties = []string{"easy", "medium", "hard"}
+
+type tuneProposal struct {
+	Difficulty string                   `json:"difficulty"`
+	Skipped    string                   `json:"skipped,omitempty"` // why no profile was proposed
+	Base       similarity.Profile       `json:"base"`
+	Result     *similarity.TuningResult `json:"result,omitempty"`
+}
+
+type tuneReport struct {
+	DriveID   string         `json:"driveId,omitempty"`
+	Reviewed  int            `json:"reviewed"`
+	Proposals []tuneProposal `json:"proposals"`
+}
+
+// runTune fits per-difficulty weights and thresholds to confirmed and dismissed pair
+// verdicts and proposes the resulting scoring profiles. Nothing changes until an admin
+// approves a proposal by pointing SCORING_PROFILES_FILE at the written profiles.
+func runTune(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("tune", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: aegis tune [flags]")
+		fmt.Fprintln(stderr, "Fits scoring profiles to reviewer verdicts on pairs (confirmed vs dismissed).")
+		flags.PrintDefaults()
+	}
+
+	format := flags.String("format", "table", "output format: table or json")
+	driveID := flags.String("drive", "", "only use verdicts of this drive (default: every drive)")
+	out := flags.String("out", "", "write the proposed profiles to this JSON file, for SCORING_PROFILES_FILE")
+	profileFile := flags.String("profile-file", env.GetEnv("SCORING_PROFILES_FILE", ""), "current profiles to tune from (default: built-in)")
+	minPerClass := flags.Int("min-per-class", 10, "fewest confirmed and dismissed pairs a difficulty needs")
+	mongoURI := flags.String("mongo-uri", env.GetEnv("MONGODB_URI", ""), "MongoDB URI")
+	mongoDB := flags.String("mongo-db", env.GetEnv("MONGODB_DB_NAME", ""), "MongoDB database")
+
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if *format != "table" && *format != "json" {
+		fmt.Fprintf(stderr, "unknown format %q\n", *format)
+		return 2
+	}
+	if *mongoURI == "" || *mongoDB == "" {
+		fmt.Fprintln(stderr, "MongoDB URI and database are required (-mongo-uri, -mongo-db or MONGODB_URI, MONGODB_DB_NAME)")
+		return 2
+	}
+
+	baseProfiles := make(map[string]similarity.Profile)
+	if *profileFile != "" {
+		profiles, err := similarity.LoadProfiles(*profileFile)
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			return 2
+		}
+		for _, profile := range profiles {
+			baseProfiles[profile.Name] = profile
+		}
+	}
+
+	ctx := context.Background()
+	mongoClient, err := mongo.NewClient(ctx, *mongoURI, *mongoDB)
+	if err != nil {
+		fmt.Fprintf(stderr, "failed to connect to MongoDB: %v\n", err)
+		return 1
+	}
+	defer mongoClient.Close(ctx)
+
+	mongoRepo := repository.NewMongoRepository(mongoClient)
+	reviews, err := loadTuneReviews(ctx, repository.NewReviewsRepository(mongoRepo), repository.NewResultsRepository(mongoRepo), *driveID)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+
+	report := buildTuneReport(reviews, baseProfiles, *minPerClass)
+	report.DriveID = *driveID
+
+	if *out != "" {
+		if err := writeTunedProfiles(*out, report); err != nil {
+			fmt.Fprintln(stderr, err)
+			return 1
+		}
+	}
+
+	if *format == "json" {
+		encoder := json.NewEncoder(stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(report); err != nil {
+			fmt.Fprintln(stderr, err)
+			return 1
+		}
+		return 0
+	}
+
+	printTuneTable(stdout, report)
+	return 0
+}
+
+// loadTuneReviews loads the confirmed and dismissed pair verdicts with the layer scores
+// they were given on. Verdicts stored without scores take them from the pair record when
+// it still exists.
+func loadTuneReviews(
+	ctx context.Context,
+	reviewsRepo *repository.ReviewsRepository,
+	resultsRepo *repository.ResultsRepository,
+	driveID string,
+) ([]*models.PairReview, error) {
+	reviews, err := reviewsRepo.GetDecidedPairReviews(ctx, driveID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, review := range reviews {
+		if review.Scores != nil {
+			continue
+		}
+		pair, err := resultsRepo.GetPairResult(ctx, review.DriveID, review.QID, review.AttemptA, review.AttemptB)
+		if err != nil {
+			return nil, err
+		}
+		if pair != nil {
+			review.SetScored(pair)
+		}
+	}
+
+	return reviews, nil
+}
+
+// buildTuneReport groups reviewed same-language pairs by difficulty and tunes each group
+func buildTuneReport(reviews []*models.PairReview, baseProfiles map[string]similarity.Profile, minPerClass int) *tuneReport {
+	report := &tuneReport{Proposals: make([]tuneProposal, 0, len(tuneDifficulties))}
+
+	samples := make(map[string][]similarity.LabelledPair)
+	for _, review := range reviews {
+		// Cross-language pairs are scored by another pipeline, not the cascade
+		if review.Scores == nil || review.CrossLanguage {
+			continue
+		}
+		difficulty := review.Difficulty
+		if difficulty == "" {
+			difficulty = "medium"
+		}
+		samples[difficulty] = append(samples[difficulty], similarity.LabelledPair{
+			Scores: similarity.SimilarityScores{
+				Fingerprint: review.Scores.Fingerprint,
+				Token:       review.Scores.Token,
+				AST:         review.Scores.AST,
+				CFG:         review.Scores.CFG,
+			},
+			Confirmed: review.Verdict == models.VerdictConfirmed,
+		})
+		report.Reviewed++
+	}
+
+	for _, difficulty := range tuneDifficulties {
+		base, exists := baseProfiles[difficulty]
+		if !exists {
+			base = similarity.DifficultyProfile(difficulty)
+		}
+
+		proposal := tuneProposal{Difficulty: difficulty, Base: base}
+		result, err := similarity.TuneProfile(base, samples[difficulty], similarity.TuningParams{MinPerClass: minPerClass})
+		if err != nil {
+			proposal.Skipped = err.Error()
+		} else {
+			proposal.Result = result
+		}
+		report.Proposals = append(report.Proposals, proposal)
+	}
+
+	return report
+}
+
+// writeTunedProfiles writes every proposed profile, and the base profile of skipped
+// difficulties, so the file can be approved as a whole
+func writeTunedProfiles(path string, report *tuneReport) error {
+	profiles := make([]similarity.Profile, 0, len(report.Proposals))
+	for _, proposal := range report.Proposals {
+		if proposal.Result != nil {
+			profiles = append(profiles, proposal.Result.Profile)
+		} else {
+			profiles = append(profiles, proposal.Base)
+		}
+	}
+
+	data, err := json.MarshalIndent(profiles, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode profiles: %w", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("failed to write profiles: %w", err)
+	}
+	return nil
+}
+
+// printTuneTable renders the proposals for a terminal
+func printTuneTable(w io.Writer, report *tuneReport) {
+	scope := "all drives"
+	if report.DriveID != "" {
+		scope = "drive " + report.DriveID
+	}
+	fmt.Fprintf(w, "Reviewed pairs: %d (%s)\n", report.Reviewed, scope)
+
+	for _, proposal := range report.Proposals {
+		fmt.Fprintf(w, "\nProfile %s\n", proposal.Difficulty)
+		if proposal.Result == nil {
+			fmt.Fprintf(w, "  skipped: %s\n", proposal.Skipped)
+			continue
+		}
+		result := proposal.Result
+		fmt.Fprintf(w, "  samples: %d (%d confirmed, %d dismissed)\n",
+			result.Samples, result.Confirmed, result.Dismissed)
+
+		table := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(table, "  \tFINGERPRINT\tTOKEN\tAST\tCFG")
+		fmt.Fprintf(table, "  current weights\t%.3f\t%.3f\t%.3f\t%.3f\n",
+			proposal.Base.Weights.Fingerprint, proposal.Base.Weights.Token, proposal.Base.Weights.AST, proposal.Base.Weights.CFG)
+		fmt.Fprintf(table, "  proposed weights\t%.3f\t%.3f\t%.3f\t%.3f\n",
+			result.Profile.Weights.Fingerprint, result.Profile.Weights.Token, result.Profile.Weights.AST, result.Profile.Weights.CFG)
+		fmt.Fprintf(table, "  current thresholds\t%.3f\t%.3f\t%.3f\t-\n",
+			proposal.Base.Thresholds.Fingerprint, proposal.Base.Thresholds.Token, proposal.Base.Thresholds.AST)
+		fmt.Fprintf(table, "  proposed thresholds\t%.3f\t%.3f\t%.3f\t-\n",
+			result.Profile.Thresholds.Fingerprint, result.Profile.Thresholds.Token, result.Profile.Thresholds.AST)
+		table.Flush()
+
+		estimates := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(estimates, "  ESTIMATE\tTHRESHOLD\tPRECISION\tRECALL\tTP\tFP\tTN\tFN")
+		for _, row := range []struct {
+			name     string
+			estimate similarity.PrecisionEstimate
+		}{
+			{"before", result.Before},
+			{"after (cross-validated)", result.After},
+		} {
+			fmt.Fprintf(estimates, "  %s\t%.3f\t%.3f\t%.3f\t%d\t%d\t%d\t%d\n", row.name, row.estimate.Threshold,
+				row.estimate.Precision, row.estimate.Recall, row.estimate.TP, row.estimate.FP, row.estimate.TN, row.estimate.FN)
+		}
+		estimates.Flush()
+	}
+}
@@ -1,18 +1,26 @@
 package api
 
 import (
+	"bytes"
 	"context"
 	"fmt"
+	"io"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
+	"unicode"
 
 	"github.com/RishiKendai/aegis/internal/config"
 	"github.com/RishiKendai/aegis/internal/infra/redis"
 	"github.com/RishiKendai/aegis/internal/metrics"
 	"github.com/RishiKendai/aegis/internal/models"
 	"github.com/RishiKendai/aegis/internal/plagiarism"
+	"github.com/RishiKendai/aegis/internal/preprocess"
 	"github.com/RishiKendai/aegis/internal/repository"
+	"github.com/RishiKendai/aegis/similarity"
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 	"github.com/rs/zerolog/log"
 )
 
@@ -21,6 +29,10 @@ type Handler struct {
 	cfg            *config.Config
 	artifactsRepo  *repository.ArtifactsRepository
 	resultsRepo    *repository.ResultsRepository
+	indexRepo      *repository.FingerprintIndexRepository
+	referencesRepo *repository.ReferencesRepository
+	reviewsRepo    *repository.ReviewsRepository
+	preprocessSvc  *preprocess.Service
 	workerPool     *plagiarism.WorkerPool
 	redisClient    *redis.Client
 	computeSem     chan struct{} // Semaphore for bounded concurrency
@@ -32,6 +44,10 @@ func NewHandler(
 	cfg *config.Config,
 	artifactsRepo *repository.ArtifactsRepository,
 	resultsRepo *repository.ResultsRepository,
+	indexRepo *repository.FingerprintIndexRepository,
+	referencesRepo *repository.ReferencesRepository,
+	reviewsRepo *repository.ReviewsRepository,
+	preprocessSvc *preprocess.Service,
 	workerPool *plagiarism.WorkerPool,
 	redisClient *redis.Client,
 ) *Handler {
@@ -42,6 +58,10 @@ func NewHandler(
 		cfg:            cfg,
 		artifactsRepo:  artifactsRepo,
 		resultsRepo:    resultsRepo,
+		indexRepo:      indexRepo,
+		referencesRepo: referencesRepo,
+		reviewsRepo:    reviewsRepo,
+		preprocessSvc:  preprocessSvc,
 		workerPool:     workerPool,
 		redisClient:    redisClient,
 		computeSem:     sem,
@@ -146,11 +166,11 @@ func (h *Handler) Compute(c *gin.Context) {
 	})
 
 	// Process asynchronously
-	go h.processComputation(req.DriveID)
+	go h.processComputation(req.DriveID, req.CorpusMode)
 }
 
 // processComputation processes computation asynchronously
-func (h *Handler) processComputation(driveID string) {
+func (h *Handler) processComputation(driveID string, corpusMode bool) {
 	defer func() { <-h.computeSem }() // Release semaphore
 
 	// Create context with timeout
@@ -178,9 +198,12 @@ func (h *Handler) processComputation(driveID string) {
 		driveID,
 		h.artifactsRepo,
 		h.resultsRepo,
+		h.indexRepo,
+		h.referencesRepo,
+		h.reviewsRepo,
 		h.workerPool,
 		h.redisClient,
-		h.cfg.BatchSize,
+		h.computeOptions(corpusMode),
 	)
 	metrics.PlagiarismComputationDuration.Observe(time.Since(computationStart).Seconds())
 
@@ -193,6 +216,33 @@ func (h *Handler) processComputation(driveID string) {
 	log.Debug().Str("driveId", driveID).Msg("Computation completed successfully")
 }
 
+// computeOptions builds the computation options from the configuration
+func (h *Handler) computeOptions(corpusMode bool) plagiarism.Options {
+	return plagiarism.Options{
+		BatchSize:                h.cfg.BatchSize,
+		CrossLanguage:            h.cfg.CrossLanguageEnabled,
+		CorpusMode:               corpusMode,
+		CorpusMatchesPerArtifact: h.cfg.CorpusMatchesPerArtifact,
+		LSH: similarity.LSHParams{
+			BucketThreshold: h.cfg.LSHBucketThreshold,
+			Bands:           h.cfg.LSHBands,
+			Rows:            h.cfg.LSHRows,
+			MaxBucketSize:   h.cfg.LSHMaxBucketSize,
+		},
+		Normalization: h.cfg.NormalizationPasses,
+		Calibration: similarity.CalibrationParams{
+			Enabled:         h.cfg.CalibrationEnabled,
+			MinBucketSize:   h.cfg.CalibrationMinBucketSize,
+			OutlierZ:        h.cfg.CalibrationOutlierZ,
+			MinScore:        h.cfg.CalibrationMinScore,
+			AlwaysFlagScore: h.cfg.CalibrationAlwaysFlag,
+		},
+		Aggregation: h.cfg.AggregationStrategy,
+		TestRisk:    h.cfg.TestRiskThresholds(),
+		Profiles:    h.cfg.ScoringProfiles,
+	}
+}
+
 func (h *Handler) createFailedReport(ctx context.Context, driveID, errorMsg string) {
 	err := h.resultsRepo.UpdateTestReportByDriveID(ctx, driveID, &models.TestReport{
 		DriveID:           driveID,
@@ -201,6 +251,7 @@ func (h *Handler) createFailedReport(ctx context.Context, driveID, errorMsg stri
 		FlaggedQuestions:  []string{},
 		FlaggedCandidates: 0,
 		TotalAnalyzed:     0,
+		Clusters:          []models.Cluster{},
 	})
 	if err != nil {
 		log.Error().Err(err).Str("driveId", driveID).Msg("Failed to update failed report")
@@ -215,3 +266,414 @@ func validateComputePayload(req models.ComputeRequest) error {
 
 	return nil
 }
+
+// CreateReference uploads a reference solution for a question. It is preprocessed like a
+// submission and matched against every artifact of the question on the next computation.
+func (h *Handler) CreateReference(c *gin.Context) {
+	var req models.ReferenceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error: "Invalid request body",
+			Code:  "INVALID_REQUEST",
+		})
+		return
+	}
+
+	reference := &models.ReferenceSolution{
+		ReferenceID: uuid.New().String(),
+		QID:         req.QID,
+		Language:    req.Language,
+		Name:        req.Name,
+		SourceURL:   req.SourceURL,
+		SourceCode:  req.SourceCode,
+	}
+
+	if err := h.preprocessSvc.ProcessReference(c.Request.Context(), reference); err != nil {
+		log.Error().Err(err).Int64("qId", req.QID).Str("name", req.Name).Msg("Failed to store reference solution")
+		c.JSON(http.StatusBadGateway, ErrorResponse{
+			Error: "Failed to preprocess reference solution",
+			Code:  "PREPROCESSING_FAILED",
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, reference)
+}
+
+// ListReferences lists reference solutions, optionally for one qId
+func (h *Handler) ListReferences(c *gin.Context) {
+	var qID int64
+	if value := c.Query("qId"); value != "" {
+		parsed, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse{
+				Error: "qId must be a number",
+				Code:  "INVALID_QID",
+			})
+			return
+		}
+		qID = parsed
+	}
+
+	references, err := h.referencesRepo.ListReferences(c.Request.Context(), qID)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to list reference solutions")
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Error: "Failed to list reference solutions",
+			Code:  "INTERNAL_ERROR",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"references": references})
+}
+
+// DeleteReference removes a reference solution
+func (h *Handler) DeleteReference(c *gin.Context) {
+	referenceID := c.Param("referenceId")
+
+	deleted, err := h.referencesRepo.DeleteReference(c.Request.Context(), referenceID)
+	if err != nil {
+		log.Error().Err(err).Str("referenceId", referenceID).Msg("Failed to delete reference solution")
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Error: "Failed to delete reference solution",
+			Code:  "INTERNAL_ERROR",
+		})
+		return
+	}
+	if !deleted {
+		c.JSON(http.StatusNotFound, ErrorResponse{
+			Error: "Reference solution not found",
+			Code:  "NOT_FOUND",
+		})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// ReviewPair records a reviewer's verdict on a pair of a question. Dismissed pairs are
+// suppressed now and on every later computation; both candidates' effective risk is refreshed.
+func (h *Handler) ReviewPair(c *gin.Context) {
+	var req models.PairReviewRequest
+	if err := c.ShouldBindJSON(&req); err != nil || !models.IsValidVerdict(req.Verdict) {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error: "Invalid request body: qId, verdict (confirmed, dismissed, needs_more_info) and reviewer are required",
+			Code:  "INVALID_REQUEST",
+		})
+		return
+	}
+
+	ctx := c.Request.Context()
+	driveID := c.Param("driveId")
+	pairReview := models.NewPairReview(driveID, req.QID, c.Param("a"), c.Param("b"), models.Review{
+		Verdict:    req.Verdict,
+		Reviewer:   req.Reviewer,
+		Comment:    req.Comment,
+		ReviewedAt: time.Now(),
+	})
+
+	// The verdict keeps the pair's layer scores for tuning: pair records are replaced on recomputes
+	pairResult, err := h.resultsRepo.GetPairResult(ctx, driveID, pairReview.QID, pairReview.AttemptA, pairReview.AttemptB)
+	if err != nil {
+		log.Error().Err(err).Str("driveId", driveID).Msg("Failed to load pair record")
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Error: "Failed to load pair record",
+			Code:  "INTERNAL_ERROR",
+		})
+		return
+	}
+	if pairResult == nil {
+		c.JSON(http.StatusNotFound, ErrorResponse{
+			Error: "Pair not found",
+			Code:  "NOT_FOUND",
+		})
+		return
+	}
+	pairReview.SetScored(pairResult)
+
+	if err := h.reviewsRepo.UpsertPairReview(ctx, pairReview); err != nil {
+		log.Error().Err(err).Str("driveId", driveID).Msg("Failed to store pair review")
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Error: "Failed to store pair review",
+			Code:  "INTERNAL_ERROR",
+		})
+		return
+	}
+	if err := h.resultsRepo.UpdatePairReview(ctx, pairReview); err != nil {
+		log.Warn().Err(err).Str("driveId", driveID).Msg("Failed to annotate pair record")
+	}
+
+	// Either side may be another drive's attempt or a reference solution; those have no result here
+	for _, attemptID := range []string{pairReview.AttemptA, pairReview.AttemptB} {
+		if _, err := plagiarism.RefreshEffectiveRisk(ctx, h.resultsRepo, h.reviewsRepo, driveID, attemptID, h.computeOptions(false)); err != nil {
+			log.Warn().Err(err).Str("driveId", driveID).Str("attemptID", attemptID).Msg("Failed to refresh effective risk")
+		}
+	}
+
+	c.JSON(http.StatusOK, pairReview)
+}
+
+// ReviewCandidate records a reviewer's verdict on a candidate and refreshes their effective risk
+func (h *Handler) ReviewCandidate(c *gin.Context) {
+	var req models.ReviewRequest
+	if err := c.ShouldBindJSON(&req); err != nil || !models.IsValidVerdict(req.Verdict) {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error: "Invalid request body: verdict (confirmed, dismissed, needs_more_info) and reviewer are required",
+			Code:  "INVALID_REQUEST",
+		})
+		return
+	}
+
+	ctx := c.Request.Context()
+	candidateReview := &models.CandidateReview{
+		DriveID:   c.Param("driveId"),
+		AttemptID: c.Param("attemptId"),
+		Review: models.Review{
+			Verdict:    req.Verdict,
+			Reviewer:   req.Reviewer,
+			Comment:    req.Comment,
+			ReviewedAt: time.Now(),
+		},
+	}
+
+	if err := h.reviewsRepo.UpsertCandidateReview(ctx, candidateReview); err != nil {
+		log.Error().Err(err).Str("driveId", candidateReview.DriveID).Msg("Failed to store candidate review")
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Error: "Failed to store candidate review",
+			Code:  "INTERNAL_ERROR",
+		})
+		return
+	}
+
+	found, err := plagiarism.RefreshEffectiveRisk(ctx, h.resultsRepo, h.reviewsRepo, candidateReview.DriveID, candidateReview.AttemptID, h.computeOptions(false))
+	if err != nil {
+		log.Warn().Err(err).Str("driveId", candidateReview.DriveID).Str("attemptID", candidateReview.AttemptID).Msg("Failed to refresh effective risk")
+	} else if !found {
+		// The verdict is kept and applied once the candidate has a result
+		log.Debug().Str("driveId", candidateReview.DriveID).Str("attemptID", candidateReview.AttemptID).Msg("Reviewed candidate has no result yet")
+	}
+
+	c.JSON(http.StatusOK, candidateReview)
+}
+
+// ListReviews lists the pair and candidate verdicts of a drive
+func (h *Handler) ListReviews(c *gin.Context) {
+	ctx := c.Request.Context()
+	driveID := c.Param("driveId")
+
+	pairReviews, err := h.reviewsRepo.GetPairReviews(ctx, driveID)
+	if err != nil {
+		log.Error().Err(err).Str("driveId", driveID).Msg("Failed to list pair reviews")
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Error: "Failed to list reviews",
+			Code:  "INTERNAL_ERROR",
+		})
+		return
+	}
+	candidateReviews, err := h.reviewsRepo.GetCandidateReviews(ctx, driveID)
+	if err != nil {
+		log.Error().Err(err).Str("driveId", driveID).Msg("Failed to list candidate reviews")
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Error: "Failed to list reviews",
+			Code:  "INTERNAL_ERROR",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"pairs": pairReviews, "candidates": candidateReviews})
+}
+
+// DiffPair returns two submissions to a question side by side with their matched blocks
+// aligned, as JSON or, with format=html, as a self-contained HTML page
+func (h *Handler) DiffPair(c *gin.Context) {
+	qID, err := strconv.ParseInt(c.Query("qId"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error: "qId is required and must be a number",
+			Code:  "INVALID_QID",
+		})
+		return
+	}
+	format := c.DefaultQuery("format", "json")
+	if format != "json" && format != "html" {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error: "format must be json or html",
+			Code:  "INVALID_FORMAT",
+		})
+		return
+	}
+
+	driveID := c.Param("driveId")
+	diff, err := plagiarism.LoadPairDiff(c.Request.Context(), h.artifactsRepo, h.resultsRepo, h.referencesRepo,
+		driveID, qID, c.Param("a"), c.Param("b"))
+	if err != nil {
+		log.Error().Err(err).Str("driveId", driveID).Msg("Failed to build pair diff")
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Error: "Failed to build pair diff",
+			Code:  "INTERNAL_ERROR",
+		})
+		return
+	}
+	if diff == nil {
+		c.JSON(http.StatusNotFound, ErrorResponse{
+			Error: "Submission not found",
+			Code:  "NOT_FOUND",
+		})
+		return
+	}
+
+	if format == "html" {
+		var page bytes.Buffer
+		if err := plagiarism.RenderPairDiffHTML(&page, diff); err != nil {
+			log.Error().Err(err).Str("driveId", driveID).Msg("Failed to render pair diff")
+			c.JSON(http.StatusInternalServerError, ErrorResponse{
+				Error: "Failed to render pair diff",
+				Code:  "INTERNAL_ERROR",
+			})
+			return
+		}
+		c.Data(http.StatusOK, "text/html; charset=utf-8", page.Bytes())
+		return
+	}
+
+	c.JSON(http.StatusOK, diff)
+}
+
+// maxReportTopPairs bounds the pair diffs of a downloaded investigation report
+const maxReportTopPairs = 50
+
+// ExportReport downloads a drive's investigation report as a self-contained HTML page,
+// ready to print to PDF
+func (h *Handler) ExportReport(c *gin.Context) {
+	topPairs := plagiarism.DefaultReportTopPairs
+	if value := c.Query("topPairs"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 0 || parsed > maxReportTopPairs {
+			c.JSON(http.StatusBadRequest, ErrorResponse{
+				Error: fmt.Sprintf("topPairs must be a number from 0 to %d", maxReportTopPairs),
+				Code:  "INVALID_REQUEST",
+			})
+			return
+		}
+		topPairs = parsed
+	}
+
+	driveID := c.Param("driveId")
+	report, err := plagiarism.BuildDriveReport(c.Request.Context(), h.artifactsRepo, h.resultsRepo, h.referencesRepo, driveID, topPairs)
+	if err != nil {
+		log.Error().Err(err).Str("driveId", driveID).Msg("Failed to build investigation report")
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Error: "Failed to build report",
+			Code:  "INTERNAL_ERROR",
+		})
+		return
+	}
+	if report == nil {
+		c.JSON(http.StatusNotFound, ErrorResponse{
+			Error: "Report not found",
+			Code:  "NOT_FOUND",
+		})
+		return
+	}
+
+	var page bytes.Buffer
+	if err := plagiarism.RenderDriveReportHTML(&page, report); err != nil {
+		log.Error().Err(err).Str("driveId", driveID).Msg("Failed to render investigation report")
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Error: "Failed to render report",
+			Code:  "INTERNAL_ERROR",
+		})
+		return
+	}
+
+	c.Header("Content-Disposition", attachment("aegis-report-"+driveID+".html"))
+	c.Data(http.StatusOK, "text/html; charset=utf-8", page.Bytes())
+}
+
+// ExportCandidates streams a drive's candidate results as CSV or JSON Lines
+func (h *Handler) ExportCandidates(c *gin.Context) {
+	h.streamExport(c, "candidates", func(ctx context.Context, driveID, format string, w io.Writer) error {
+		return plagiarism.ExportCandidates(ctx, h.resultsRepo, driveID, format, w)
+	})
+}
+
+// ExportPairs streams a drive's pair records as CSV or JSON Lines
+func (h *Handler) ExportPairs(c *gin.Context) {
+	h.streamExport(c, "pairs", func(ctx context.Context, driveID, format string, w io.Writer) error {
+		return plagiarism.ExportPairs(ctx, h.resultsRepo, driveID, format, w)
+	})
+}
+
+// streamExport writes an export straight to the response. Once streaming has started
+// the status can no longer change, so later errors are only logged.
+func (h *Handler) streamExport(c *gin.Context, name string, export func(context.Context, string, string, io.Writer) error) {
+	format := c.DefaultQuery("format", plagiarism.ExportFormatCSV)
+	if !plagiarism.IsValidExportFormat(format) {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error: "format must be csv or jsonl",
+			Code:  "INVALID_FORMAT",
+		})
+		return
+	}
+
+	contentType := "text/csv; charset=utf-8"
+	if format == plagiarism.ExportFormatJSONL {
+		contentType = "application/x-ndjson"
+	}
+
+	driveID := c.Param("driveId")
+	c.Header("Content-Type", contentType)
+	c.Header("Content-Disposition", attachment(fmt.Sprintf("aegis-%s-%s.%s", name, driveID, format)))
+	c.Status(http.StatusOK)
+
+	if err := export(c.Request.Context(), driveID, format, c.Writer); err != nil {
+		log.Error().Err(err).Str("driveId", driveID).Str("export", name).Msg("Failed to stream export")
+	}
+}
+
+// ExportJPlag downloads the results of one question as a JPlag result archive
+func (h *Handler) ExportJPlag(c *gin.Context) {
+	qID, err := strconv.ParseInt(c.Query("qId"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error: "qId is required and must be a number",
+			Code:  "INVALID_QID",
+		})
+		return
+	}
+
+	driveID := c.Param("driveId")
+	var archive bytes.Buffer
+	found, err := plagiarism.WriteJPlagArchive(c.Request.Context(), h.artifactsRepo, h.resultsRepo, driveID, qID, c.Query("language"), &archive)
+	if err != nil {
+		log.Error().Err(err).Str("driveId", driveID).Int64("qId", qID).Msg("Failed to build JPlag archive")
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Error: "Failed to build JPlag archive",
+			Code:  "INTERNAL_ERROR",
+		})
+		return
+	}
+	if !found {
+		c.JSON(http.StatusNotFound, ErrorResponse{
+			Error: "No submissions found for this question",
+			Code:  "NOT_FOUND",
+		})
+		return
+	}
+
+	c.Header("Content-Disposition", attachment(fmt.Sprintf("aegis-jplag-%s-%d.zip", driveID, qID)))
+	c.Data(http.StatusOK, "application/zip", archive.Bytes())
+}
+
+// attachment builds a Content-Disposition header for a download, keeping the file name
+// to characters that need no quoting
+func attachment(filename string) string {
+	safe := strings.Map(func(r rune) rune {
+		if r == '-' || r == '_' || r == '.' || unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return r
+		}
+		return '_'
+	}, filename)
+	return fmt.Sprintf(`attachment; filename="%s"`, safe)
+}
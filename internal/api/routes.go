@@ -4,6 +4,7 @@ import (
 	"github.com/RishiKendai/aegis/internal/config"
 	"github.com/RishiKendai/aegis/internal/infra/redis"
 	"github.com/RishiKendai/aegis/internal/plagiarism"
+	"github.com/RishiKendai/aegis/internal/preprocess"
 	"github.com/RishiKendai/aegis/internal/repository"
 
 	"github.com/gin-gonic/gin"
@@ -13,13 +14,17 @@ func SetupRoutes(
 	cfg *config.Config,
 	artifactsRepo *repository.ArtifactsRepository,
 	resultsRepo *repository.ResultsRepository,
+	indexRepo *repository.FingerprintIndexRepository,
+	referencesRepo *repository.ReferencesRepository,
+	reviewsRepo *repository.ReviewsRepository,
+	preprocessSvc *preprocess.Service,
 	workerPool *plagiarism.WorkerPool,
 	redisClient *redis.Client,
 ) *gin.Engine {
 	router := gin.Default()
 
 	// Create handler
-	handler := NewHandler(cfg, artifactsRepo, resultsRepo, workerPool, redisClient)
+	handler := NewHandler(cfg, artifactsRepo, resultsRepo, indexRepo, referencesRepo, reviewsRepo, preprocessSvc, workerPool, redisClient)
 
 	// Create rate limiter
 	rateLimiter := NewRateLimiter(cfg.RateLimitRPS, int(cfg.RateLimitRPS*2))
@@ -36,6 +41,25 @@ func SetupRoutes(
 	api.Use(RateLimitMiddleware(rateLimiter))
 	{
 		api.POST("/compute", handler.Compute)
+
+		// Reference solutions, matched as known sources
+		api.POST("/references", handler.CreateReference)
+		api.GET("/references", handler.ListReferences)
+		api.DELETE("/references/:referenceId", handler.DeleteReference)
+
+		// Reviewer verdicts on flagged pairs and candidates
+		api.PUT("/drives/:driveId/pairs/:a/:b/review", handler.ReviewPair)
+		api.PUT("/drives/:driveId/candidates/:attemptId/review", handler.ReviewCandidate)
+		api.GET("/drives/:driveId/reviews", handler.ListReviews)
+
+		// Side-by-side view of a pair
+		api.GET("/drives/:driveId/pairs/:a/:b/diff", handler.DiffPair)
+
+		// Exports
+		api.GET("/drives/:driveId/export/report", handler.ExportReport)
+		api.GET("/drives/:driveId/export/candidates", handler.ExportCandidates)
+		api.GET("/drives/:driveId/export/pairs", handler.ExportPairs)
+		api.GET("/drives/:driveId/export/jplag", handler.ExportJPlag)
 	}
 
 	return router
@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"github.com/RishiKendai/aegis/internal/configs/env"
+	"github.com/RishiKendai/aegis/similarity"
 )
 
 // Config holds all configuration for the application
@@ -37,6 +38,23 @@ type Config struct {
 	ComputationTimeout time.Duration
 	BatchSize          int
 
+	// Detection
+	CrossLanguageEnabled     bool
+	CorpusMatchesPerArtifact int
+	LSHBucketThreshold       int
+	LSHBands                 int
+	LSHRows                  int
+	LSHMaxBucketSize         int
+	NormalizationPasses      []similarity.NormalizationPass
+	AIReferenceDir           string
+	CalibrationEnabled       bool
+	CalibrationMinBucketSize int
+	CalibrationOutlierZ      float64
+	CalibrationMinScore      float64
+	CalibrationAlwaysFlag    float64
+	AggregationStrategy      similarity.AggregationStrategy
+	ScoringProfiles          map[string]similarity.Profile // by difficulty; replaces the built-in profiles
+
 	// Test Risk Thresholds
 	TestRiskSafe     float64
 	TestRiskModerate float64
@@ -85,11 +103,47 @@ func Load() (*Config, error) {
 	cfg.ComputationTimeout = time.Duration(timeoutMinutes) * time.Minute
 	cfg.BatchSize = env.GetEnvInt("BATCH_SIZE", 100)
 
+	// Detection
+	cfg.CrossLanguageEnabled = env.GetEnvBool("CROSS_LANGUAGE_ENABLED", false)
+	cfg.CorpusMatchesPerArtifact = env.GetEnvInt("CORPUS_MATCHES_PER_ARTIFACT", 10)
+	cfg.LSHBucketThreshold = env.GetEnvInt("LSH_BUCKET_THRESHOLD", 1000)
+	cfg.LSHBands = env.GetEnvInt("LSH_BANDS", 0)
+	cfg.LSHRows = env.GetEnvInt("LSH_ROWS", 0)
+	cfg.LSHMaxBucketSize = env.GetEnvInt("LSH_MAX_BUCKET_SIZE", similarity.DefaultLSHMaxBucketSize)
+	passes, err := similarity.ParseNormalizationPasses(env.GetEnv("NORMALIZATION_PASSES", "all"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid NORMALIZATION_PASSES: %w", err)
+	}
+	cfg.NormalizationPasses = passes
+	cfg.AIReferenceDir = env.GetEnv("AI_REFERENCE_DIR", "")
+	cfg.CalibrationEnabled = env.GetEnvBool("CALIBRATION_ENABLED", true)
+	cfg.CalibrationMinBucketSize = env.GetEnvInt("CALIBRATION_MIN_BUCKET_SIZE", 20)
+	cfg.CalibrationOutlierZ = env.GetEnvFloat("CALIBRATION_OUTLIER_Z", 3.5)
+	cfg.CalibrationMinScore = env.GetEnvFloat("CALIBRATION_MIN_SCORE", 0.30)
+	cfg.CalibrationAlwaysFlag = env.GetEnvFloat("CALIBRATION_ALWAYS_FLAG", 0.85)
+	if value := env.GetEnv("AGGREGATION_STRATEGY", ""); value != "" {
+		strategy, err := similarity.ParseAggregationStrategy(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid AGGREGATION_STRATEGY: %w", err)
+		}
+		cfg.AggregationStrategy = strategy
+	}
+	if path := env.GetEnv("SCORING_PROFILES_FILE", ""); path != "" {
+		profiles, err := similarity.LoadProfiles(path)
+		if err != nil {
+			return nil, fmt.Errorf("invalid SCORING_PROFILES_FILE: %w", err)
+		}
+		cfg.ScoringProfiles = make(map[string]similarity.Profile, len(profiles))
+		for _, profile := range profiles {
+			cfg.ScoringProfiles[profile.Name] = profile
+		}
+	}
+
 	// Test Risk Thresholds
 	cfg.TestRiskSafe = env.GetEnvFloat("TEST_RISK_SAFE", 0.0)
-	cfg.TestRiskModerate = env.GetEnvFloat("TEST_RISK_MODERATE", 0.3)
-	cfg.TestRiskHigh = env.GetEnvFloat("TEST_RISK_HIGH", 0.7)
-	cfg.TestRiskCritical = env.GetEnvFloat("TEST_RISK_CRITICAL", 0.9)
+	cfg.TestRiskModerate = env.GetEnvFloat("TEST_RISK_MODERATE", 0.40)
+	cfg.TestRiskHigh = env.GetEnvFloat("TEST_RISK_HIGH", 0.60)
+	cfg.TestRiskCritical = env.GetEnvFloat("TEST_RISK_CRITICAL", 0.80)
 
 	// Logging
 	cfg.LogLevel = env.GetEnv("LOG_LEVEL", "info")
@@ -128,5 +182,27 @@ func (c *Config) Validate() error {
 	if c.StreamRetentionDuration <= 0 {
 		return fmt.Errorf("STREAM_RETENTION_HOURS must be greater than 0")
 	}
+	if err := c.TestRiskThresholds().Validate(); err != nil {
+		return fmt.Errorf("invalid TEST_RISK_*: %w", err)
+	}
 	return nil
 }
+
+// TestRiskThresholds returns the configured cutoffs of the test risk levels
+func (c *Config) TestRiskThresholds() similarity.TestRiskThresholds {
+	return similarity.TestRiskThresholds{
+		Safe:     c.TestRiskSafe,
+		Moderate: c.TestRiskModerate,
+		High:     c.TestRiskHigh,
+		Critical: c.TestRiskCritical,
+	}
+}
+
+// NormalizationPassesFor returns the normalization passes of a difficulty: the loaded
+// scoring profile's when it sets them, NORMALIZATION_PASSES otherwise
+func (c *Config) NormalizationPassesFor(difficulty string) []similarity.NormalizationPass {
+	if profile, exists := c.ScoringProfiles[difficulty]; exists && profile.Normalization != nil {
+		return profile.Normalization
+	}
+	return c.NormalizationPasses
+}
@@ -42,3 +42,12 @@ func GetEnvFloat(key string, defaultValue float64) float64 {
 	}
 	return defaultValue
 }
+
+func GetEnvBool(key string, defaultValue bool) bool {
+	if value := os.Getenv(key); value != "" {
+		if boolValue, err := strconv.ParseBool(value); err == nil {
+			return boolValue
+		}
+	}
+	return defaultValue
+}
@@ -0,0 +1,48 @@
+package models
+
+// PairDiff is a side-by-side view of two submissions to a question, with their
+// matched blocks aligned
+type PairDiff struct {
+	DriveID     string       `json:"driveId"`
+	QID         string       `json:"qId"`
+	A           DiffSide     `json:"a"`
+	B           DiffSide     `json:"b"`
+	FinalScore  *float64     `json:"final_score,omitempty"` // from the pair record, nil when the pair was not scored
+	Scores      *LayerScores `json:"scores,omitempty"`
+	Groups      int          `json:"groups"`      // number of colour groups
+	Approximate bool         `json:"approximate"` // the scored tokens of either side differ from the re-tokenization the blocks come from
+	Blocks      []DiffBlock  `json:"blocks"`
+	Rows        []DiffRow    `json:"rows"`
+}
+
+// DiffSide is one submission of a diff
+type DiffSide struct {
+	AttemptID    string `json:"attemptID"`
+	Email        string `json:"email"` // reference name for reference solutions
+	DriveID      string `json:"driveId"`
+	Language     string `json:"language"`
+	SourceCode   string `json:"sourceCode"`
+	Lines        int    `json:"lines"`
+	MatchedLines int    `json:"matched_lines"`
+}
+
+// DiffBlock is a region of A matching a region of B. Both regions share a colour group.
+type DiffBlock struct {
+	Group   int    `json:"group"`
+	Source  string `json:"source"`  // token (Greedy String Tiling tile) or fingerprint (run of shared k-grams)
+	TokensA [2]int `json:"tokensA"` // first and last token
+	TokensB [2]int `json:"tokensB"`
+	LinesA  [2]int `json:"linesA"` // first and last 1-based source line
+	LinesB  [2]int `json:"linesB"`
+	Length  int    `json:"length"`  // tokens
+	Aligned bool   `json:"aligned"` // placed side by side in Rows; other blocks were moved relative to them
+}
+
+// DiffRow is one line of the side-by-side view. A zero line is padding; a zero group
+// is an unmatched line.
+type DiffRow struct {
+	LineA  int `json:"lineA"`
+	LineB  int `json:"lineB"`
+	GroupA int `json:"groupA,omitempty"`
+	GroupB int `json:"groupB,omitempty"`
+}
@@ -1,5 +1,7 @@
 package models
 
+import "time"
+
 // PreprocessingResponse represents the response from Astra preprocessing API
 type PreprocessingResponse struct {
 	EmailID       string            `json:"email"`
@@ -18,62 +20,24 @@ type PreprocessingData struct {
 	Fingerprints     *Fingerprints `json:"fingerprints"`
 }
 
-// ASTNode represents an AST node
-type ASTNode struct {
-	Type       string                   `json:"type"`
-	Name       string                   `json:"name,omitempty"`
-	Modifiers  []string                 `json:"modifiers,omitempty"`
-	ReturnType string                   `json:"returnType,omitempty"`
-	Parameters []*Parameter             `json:"parameters,omitempty"`
-	Body       map[string]interface{}   `json:"body,omitempty"`
-	Children   []*ASTNode               `json:"children,omitempty"`
-	Expression map[string]interface{}   `json:"expression,omitempty"`
-	Operator   string                   `json:"operator,omitempty"`
-	Left       map[string]interface{}   `json:"left,omitempty"`
-	Right      map[string]interface{}   `json:"right,omitempty"`
-	Statements []map[string]interface{} `json:"statements,omitempty"`
-}
-
-// Parameter represents a function parameter
-type Parameter struct {
-	Type      string `json:"type"`
-	ParamType string `json:"paramType"`
-	Name      string `json:"name"`
-}
-
-// CFG represents a Control Flow Graph
-type CFG struct {
-	Nodes []*CFGNode `json:"nodes"`
-	Edges []*CFGEdge `json:"edges"`
-}
-
-// CFGNode represents a CFG node
-type CFGNode struct {
-	ID         string `json:"id"`
-	Type       string `json:"type"`
-	Label      string `json:"label"`
-	LineNumber int    `json:"lineNumber,omitempty"`
-}
-
-// CFGEdge represents a CFG edge
-type CFGEdge struct {
-	From string `json:"from"`
-	To   string `json:"to"`
-	Type string `json:"type"`
-}
-
-// Fingerprints represents fingerprint data
-type Fingerprints struct {
-	Method     string      `json:"method"`
-	KGramSize  int         `json:"kGramSize"`
-	WindowSize int         `json:"windowSize"`
-	Hashes     []HashEntry `json:"hashes"`
-}
-
-// HashEntry represents a single hash entry
-type HashEntry struct {
-	Hash     string `json:"hash"`
-	Position int    `json:"position"`
+// FingerprintPosting represents one (artifact, hash) entry of the persistent fingerprint index
+type FingerprintPosting struct {
+	Hash      string    `bson:"hash" json:"hash"`
+	QID       int64     `bson:"qId" json:"qId"`
+	Language  string    `bson:"language" json:"language"`
+	DriveID   string    `bson:"driveId" json:"driveId"`
+	AttemptID string    `bson:"attemptID" json:"attemptID"`
+	Email     string    `bson:"email" json:"email"`
+	HashCount int       `bson:"hash_count" json:"hash_count"` // unique hashes of the artifact
+	CreatedAt time.Time `bson:"createdAt" json:"createdAt"`
+}
+
+// CorpusOverlap is an artifact of another drive sharing fingerprint hashes with a local artifact
+type CorpusOverlap struct {
+	DriveID   string  `bson:"driveId" json:"driveId"`
+	AttemptID string  `bson:"attemptID" json:"attemptID"`
+	Shared    int     `bson:"shared" json:"shared"`   // unique hashes in common
+	Overlap   float64 `bson:"overlap" json:"overlap"` // shared / the smaller unique hash count
 }
 
 // PreprocessingError represents an error response from Astra API
@@ -0,0 +1,60 @@
+package models
+
+import "time"
+
+// ReferenceDriveID marks virtual artifacts built from reference solutions
+const ReferenceDriveID = "__reference__"
+
+// ReferenceSolution is a known solution to a question (an editorial or a well-known
+// public answer). It is preprocessed like a submission and matched against every
+// artifact of the question as a virtual candidate.
+type ReferenceSolution struct {
+	ReferenceID      string        `bson:"referenceId" json:"referenceId"`
+	QID              int64         `bson:"qId" json:"qId"`
+	Language         string        `bson:"language" json:"language"`
+	Name             string        `bson:"name" json:"name"` // e.g. "Editorial", "GeeksforGeeks"
+	SourceURL        string        `bson:"sourceUrl,omitempty" json:"sourceUrl,omitempty"`
+	SourceCode       string        `bson:"sourceCode" json:"sourceCode"`
+	Tokens           []string      `bson:"tokens" json:"-"`
+	NormalizedTokens []string      `bson:"normalizedTokens" json:"-"`
+	AST              *ASTNode      `bson:"ast" json:"-"`
+	CFG              *CFG          `bson:"cfg" json:"-"`
+	Fingerprints     *Fingerprints `bson:"fingerprints" json:"-"`
+	CreatedAt        time.Time     `bson:"createdAt" json:"createdAt"`
+}
+
+// Artifact returns the reference as a virtual artifact; its Email carries the reference name
+func (r *ReferenceSolution) Artifact() *Artifact {
+	return &Artifact{
+		Email:            r.Name,
+		AttemptID:        r.ReferenceID,
+		DriveID:          ReferenceDriveID,
+		QID:              r.QID,
+		Language:         r.Language,
+		SourceCode:       r.SourceCode,
+		Tokens:           r.Tokens,
+		NormalizedTokens: r.NormalizedTokens,
+		AST:              r.AST,
+		CFG:              r.CFG,
+		Fingerprints:     r.Fingerprints,
+		SubmittedAt:      r.CreatedAt,
+		CreatedAt:        r.CreatedAt,
+	}
+}
+
+// ReferenceRequest is the payload to upload a reference solution
+type ReferenceRequest struct {
+	QID        int64  `json:"qId" binding:"required"`
+	Language   string `json:"language" binding:"required"`
+	Name       string `json:"name" binding:"required"`
+	SourceURL  string `json:"sourceUrl"`
+	SourceCode string `json:"sourceCode" binding:"required"`
+}
+
+// KnownSourceMatch is a candidate's match against a reference solution
+type KnownSourceMatch struct {
+	QID         string  `bson:"qId" json:"qId"`
+	ReferenceID string  `bson:"referenceId" json:"referenceId"`
+	Name        string  `bson:"name" json:"name"`
+	Score       float64 `bson:"score" json:"score"`
+}
@@ -17,53 +17,105 @@ const (
 	StepFailed        Step = "failed"
 )
 
-// Artifact represents a plagiarism artifact stored in MongoDB
-type Artifact struct {
-	Email            string        `bson:"email" json:"email"`
-	AttemptID        string        `bson:"attemptID" json:"attemptID"`
-	TestID           string        `bson:"testId" json:"testId"`
-	DriveID          string        `bson:"driveId" json:"driveId"`
-	Difficulty       string        `bson:"difficulty" json:"difficulty"`
-	SourceCode       string        `bson:"sourceCode" json:"sourceCode"`
-	QID              int64         `bson:"qId" json:"qId"`
-	Language         string        `bson:"language" json:"language"`
-	LangCode         string        `bson:"langCode" json:"langCode"`
-	Tokens           []string      `bson:"tokens" json:"tokens"`
-	NormalizedTokens []string      `bson:"normalizedTokens" json:"normalizedTokens"`
-	AST              *ASTNode      `bson:"ast" json:"ast"`
-	CFG              *CFG          `bson:"cfg" json:"cfg"`
-	Fingerprints     *Fingerprints `bson:"fingerprints" json:"fingerprints"`
-	CreatedAt        time.Time     `bson:"createdAt" json:"createdAt"`
-}
-
 // CandidateResult represents a candidate's plagiarism result
 type CandidateResult struct {
-	Email            string              `bson:"email" json:"email"`
-	AttemptID        string              `bson:"attemptID" json:"attemptID"`
-	DriveID          string              `bson:"driveId" json:"driveId"`
-	Risk             string              `bson:"risk" json:"risk"` // safe, suspicious, highly_suspicious, near_copy
-	FlaggedQuestions []string            `bson:"flagged_qns" json:"flagged_qns"`
-	PlagiarismPeers  map[string][]string `bson:"plagiarism_peers" json:"plagiarism_peers"` // qId -> []attemptId
-	CodeSimilarity   int                 `bson:"code_similarity" json:"code_similarity"`
-	AlgoSimilarity   int                 `bson:"algo_similarity" json:"algo_similarity"`
-	PlagiarismStatus string              `bson:"plagiarism_status" json:"plagiarism_status"` // pending, completed, failed
-	CreatedAt        time.Time           `bson:"createdAt" json:"createdAt"`
+	Email              string              `bson:"email" json:"email"`
+	AttemptID          string              `bson:"attemptID" json:"attemptID"`
+	DriveID            string              `bson:"driveId" json:"driveId"`
+	Risk               string              `bson:"risk" json:"risk"`                     // safe, suspicious, highly_suspicious, near_copy
+	EffectiveRisk      string              `bson:"effective_risk" json:"effective_risk"` // Risk after reviewer verdicts
+	Review             *Review             `bson:"review" json:"review"`                 // verdict on the candidate, nil when unreviewed
+	FlaggedQuestions   []string            `bson:"flagged_qns" json:"flagged_qns"`
+	PlagiarismPeers    map[string][]string `bson:"plagiarism_peers" json:"plagiarism_peers"` // qId -> []attemptId
+	CodeSimilarity     int                 `bson:"code_similarity" json:"code_similarity"`
+	AlgoSimilarity     int                 `bson:"algo_similarity" json:"algo_similarity"`
+	LikelySources      map[string][]string `bson:"likely_sources" json:"likely_sources"`             // qId -> []attemptId this candidate likely copied from
+	LikelyCopiers      map[string][]string `bson:"likely_copiers" json:"likely_copiers"`             // qId -> []attemptId likely copied from this candidate
+	CorpusMatches      []CorpusMatch       `bson:"corpus_matches" json:"corpus_matches"`             // matches against other drives
+	KnownSourceMatches []KnownSourceMatch  `bson:"known_source_matches" json:"known_source_matches"` // matches against reference solutions
+	PlagiarismStatus   string              `bson:"plagiarism_status" json:"plagiarism_status"`       // pending, completed, failed
+	AILikelihood       float64             `bson:"ai_likelihood" json:"ai_likelihood"`               // highest AI signal score across questions
+	AILevel            string              `bson:"ai_level" json:"ai_level"`                         // low, medium, high; separate from Risk
+	AISignals          map[string]AISignal `bson:"ai_signals" json:"ai_signals"`                     // qId -> signal
+	RiskExplanation    *RiskExplanation    `bson:"risk_explanation" json:"risk_explanation"`         // how Risk was reached; nil when clean
+	CreatedAt          time.Time           `bson:"createdAt" json:"createdAt"`
 }
 
 // TestReport represents an overall test plagiarism report
 type TestReport struct {
-	DriveID           string    `bson:"driveId" json:"driveId"`
-	Risk              string    `bson:"risk" json:"risk"`     // safe, moderate, high, critical
-	Status            string    `bson:"status" json:"status"` // pending, completed, failed
-	CreatedAt         time.Time `bson:"createdAt" json:"createdAt"`
-	FlaggedQuestions  []string  `bson:"flagged_qns" json:"flagged_qns"`
-	FlaggedCandidates int       `bson:"flagged_candidates" json:"flagged_candidates"`
-	TotalAnalyzed     int       `bson:"total_analyzed" json:"total_analyzed"`
+	DriveID           string              `bson:"driveId" json:"driveId"`
+	Risk              string              `bson:"risk" json:"risk"` // safe, moderate, high, critical
+	RiskScore         float64             `bson:"risk_score" json:"risk_score"`
+	RiskComponents    *TestRiskComponents `bson:"risk_components" json:"risk_components"`
+	Status            string              `bson:"status" json:"status"` // pending, completed, failed
+	CreatedAt         time.Time           `bson:"createdAt" json:"createdAt"`
+	FlaggedQuestions  []string            `bson:"flagged_qns" json:"flagged_qns"`
+	FlaggedCandidates int                 `bson:"flagged_candidates" json:"flagged_candidates"`
+	TotalAnalyzed     int                 `bson:"total_analyzed" json:"total_analyzed"`
+	Clusters          []Cluster           `bson:"clusters" json:"clusters"`
+	QuestionStats     []QuestionStats     `bson:"question_stats" json:"question_stats"` // one entry per qId and language
+}
+
+// QuestionStats summarises one question and language bucket of a drive, so question
+// authors can see which questions leak and should be retired
+type QuestionStats struct {
+	QID               string   `bson:"qId" json:"qId"`
+	Language          string   `bson:"language" json:"language"`
+	Candidates        int      `bson:"candidates" json:"candidates"`
+	WorthyPairs       int      `bson:"worthy_pairs" json:"worthy_pairs"`
+	SignificantPairs  int      `bson:"significant_pairs" json:"significant_pairs"`
+	MaxScore          *float64 `bson:"max_score,omitempty" json:"max_score,omitempty"`       // over the bucket's scored worthy peer pairs; unset when none was scored
+	MedianScore       *float64 `bson:"median_score,omitempty" json:"median_score,omitempty"` // over the same pairs as MaxScore
+	Clusters          int      `bson:"clusters" json:"clusters"`                             // collusion rings involving this bucket
+	FlaggedCandidates int      `bson:"flagged_candidates" json:"flagged_candidates"`
+	FlaggedPercent    float64  `bson:"flagged_percent" json:"flagged_percent"` // 0..100
+}
+
+// PairResult represents a persisted similarity record for a pair of artifacts
+type PairResult struct {
+	DriveID          string           `bson:"driveId" json:"driveId"`
+	QID              string           `bson:"qId" json:"qId"`
+	Language         string           `bson:"language" json:"language"`
+	LanguageB        string           `bson:"languageB,omitempty" json:"languageB,omitempty"` // set for cross-language pairs
+	Difficulty       string           `bson:"difficulty,omitempty" json:"difficulty,omitempty"`
+	CrossLanguage    bool             `bson:"cross_language" json:"cross_language"`
+	CrossDrive       bool             `bson:"cross_drive" json:"cross_drive"`
+	DriveB           string           `bson:"driveB,omitempty" json:"driveB,omitempty"`             // set for cross-drive pairs
+	KnownSource      string           `bson:"known_source,omitempty" json:"known_source,omitempty"` // reference name when B is a reference solution
+	AttemptA         string           `bson:"attemptA" json:"attemptA"`
+	EmailA           string           `bson:"emailA" json:"emailA"`
+	AttemptB         string           `bson:"attemptB" json:"attemptB"`
+	EmailB           string           `bson:"emailB" json:"emailB"`
+	FinalScore       float64          `bson:"final_score" json:"final_score"`
+	Scores           LayerScores      `bson:"scores" json:"scores"`
+	Containment      LayerContainment `bson:"containment" json:"containment"`
+	LikelySource     string           `bson:"likely_source" json:"likely_source"` // attemptId, empty when undetermined
+	LikelyCopier     string           `bson:"likely_copier" json:"likely_copier"`
+	DirectionBasis   string           `bson:"direction_basis" json:"direction_basis"` // timestamp, containment, known_source
+	FunctionMatches  []FunctionMatch  `bson:"function_matches" json:"function_matches"`
+	EstimatedJaccard float64          `bson:"estimated_jaccard,omitempty" json:"estimated_jaccard,omitempty"` // set when the pair came from MinHash/LSH
+	Normalization    []string         `bson:"normalization,omitempty" json:"normalization,omitempty"`         // normalization passes that changed either artifact
+	Calibration      *Calibration     `bson:"calibration,omitempty" json:"calibration,omitempty"`             // nil when the bucket was too small to calibrate
+	Review           *Review          `bson:"review,omitempty" json:"review,omitempty"`                       // verdict on the pair, nil when unreviewed
+	Suppressed       bool             `bson:"suppressed" json:"suppressed"`                                   // dismissed by a reviewer; kept out of candidate scores
+	RunID            string           `bson:"runId" json:"runId"`                                             // computation that wrote the record
+	CreatedAt        time.Time        `bson:"createdAt" json:"createdAt"`
+}
+
+// CorpusMatch represents a match against an artifact from another drive
+type CorpusMatch struct {
+	QID       string  `bson:"qId" json:"qId"`
+	DriveID   string  `bson:"driveId" json:"driveId"`
+	AttemptID string  `bson:"attemptID" json:"attemptID"`
+	Email     string  `bson:"email" json:"email"`
+	Score     float64 `bson:"score" json:"score"`
 }
 
 // ComputeRequest represents a request to compute plagiarism
 type ComputeRequest struct {
 	DriveID string `json:"driveId" binding:"required"`
+	// CorpusMode opts in to matching against other drives sharing a question
+	CorpusMode bool `json:"corpusMode"`
 }
 
 // ComputeResponse represents the response from compute endpoint
@@ -0,0 +1,94 @@
+package models
+
+import "time"
+
+// Reviewer verdicts on flagged pairs and candidates
+const (
+	VerdictConfirmed     = "confirmed"
+	VerdictDismissed     = "dismissed"
+	VerdictNeedsMoreInfo = "needs_more_info"
+)
+
+// IsValidVerdict reports whether a verdict is one of the known verdicts
+func IsValidVerdict(verdict string) bool {
+	switch verdict {
+	case VerdictConfirmed, VerdictDismissed, VerdictNeedsMoreInfo:
+		return true
+	default:
+		return false
+	}
+}
+
+// Review is a reviewer's verdict on a pair or a candidate
+type Review struct {
+	Verdict    string    `bson:"verdict" json:"verdict"` // confirmed, dismissed, needs_more_info
+	Reviewer   string    `bson:"reviewer" json:"reviewer"`
+	Comment    string    `bson:"comment,omitempty" json:"comment,omitempty"`
+	ReviewedAt time.Time `bson:"reviewedAt" json:"reviewedAt"`
+}
+
+// PairReview is the verdict on a pair of one question. It is stored apart from the
+// pair records, which are replaced on every computation, so it survives recomputes.
+// AttemptA is always the lower of the two attemptIds.
+type PairReview struct {
+	DriveID  string `bson:"driveId" json:"driveId"`
+	QID      string `bson:"qId" json:"qId"`
+	AttemptA string `bson:"attemptA" json:"attemptA"`
+	AttemptB string `bson:"attemptB" json:"attemptB"`
+	Review   `bson:",inline"`
+	// The pair as it was scored when reviewed, kept for tuning once the pair record is
+	// gone; Scores is nil when the pair had no record
+	Difficulty    string       `bson:"difficulty,omitempty" json:"difficulty,omitempty"`
+	CrossLanguage bool         `bson:"cross_language,omitempty" json:"cross_language,omitempty"`
+	Scores        *LayerScores `bson:"scores,omitempty" json:"scores,omitempty"`
+}
+
+// SetScored records how the pair was scored when it was reviewed
+func (r *PairReview) SetScored(pair *PairResult) {
+	scores := pair.Scores
+	r.Difficulty = pair.Difficulty
+	r.CrossLanguage = pair.CrossLanguage
+	r.Scores = &scores
+}
+
+// NewPairReview builds a pair review with its attempts in canonical order
+func NewPairReview(driveID, qID, attemptA, attemptB string, review Review) *PairReview {
+	if attemptB < attemptA {
+		attemptA, attemptB = attemptB, attemptA
+	}
+	return &PairReview{
+		DriveID:  driveID,
+		QID:      qID,
+		AttemptA: attemptA,
+		AttemptB: attemptB,
+		Review:   review,
+	}
+}
+
+// PairReviewKey identifies a pair of a question regardless of its orientation
+func PairReviewKey(qID, attemptA, attemptB string) string {
+	if attemptB < attemptA {
+		attemptA, attemptB = attemptB, attemptA
+	}
+	return qID + "|" + attemptA + "|" + attemptB
+}
+
+// CandidateReview is the verdict on a candidate of a drive
+type CandidateReview struct {
+	DriveID   string `bson:"driveId" json:"driveId"`
+	AttemptID string `bson:"attemptID" json:"attemptID"`
+	Review    `bson:",inline"`
+}
+
+// ReviewRequest is the payload to record a verdict
+type ReviewRequest struct {
+	Verdict  string `json:"verdict" binding:"required"`
+	Reviewer string `json:"reviewer" binding:"required"`
+	Comment  string `json:"comment"`
+}
+
+// PairReviewRequest is the payload to record a verdict on a pair of a question
+type PairReviewRequest struct {
+	QID string `json:"qId" binding:"required"`
+	ReviewRequest
+}
@@ -0,0 +1,28 @@
+package models
+
+import "github.com/RishiKendai/aegis/similarity/model"
+
+// The types the similarity engine reads and produces are defined in the public
+// similarity/model package, so the engine's API does not expose internal packages.
+// They are aliased here for the rest of the service.
+type (
+	Artifact     = model.Artifact
+	AISignal     = model.AISignal
+	ASTNode      = model.ASTNode
+	Parameter    = model.Parameter
+	CFG          = model.CFG
+	CFGNode      = model.CFGNode
+	CFGEdge      = model.CFGEdge
+	Fingerprints = model.Fingerprints
+	HashEntry    = model.HashEntry
+
+	LayerScores        = model.LayerScores
+	Containment        = model.Containment
+	LayerContainment   = model.LayerContainment
+	FunctionMatch      = model.FunctionMatch
+	Calibration        = model.Calibration
+	Cluster            = model.Cluster
+	RiskExplanation    = model.RiskExplanation
+	ExplainedPair      = model.ExplainedPair
+	TestRiskComponents = model.TestRiskComponents
+)
@@ -0,0 +1,108 @@
+package plagiarism
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/RishiKendai/aegis/internal/models"
+	"github.com/RishiKendai/aegis/internal/repository"
+	"github.com/RishiKendai/aegis/similarity"
+	"github.com/rs/zerolog/log"
+)
+
+// DefaultCorpusMatchesPerArtifact bounds how many historical artifacts are compared per artifact
+const DefaultCorpusMatchesPerArtifact = 10
+
+// GetCorpusPairs finds worthy pairs between a bucket's artifacts and artifacts of other
+// drives answering the same question in the same language. The fingerprint index counts
+// shared hashes per historical artifact and keeps the maxPerArtifact strongest worthy
+// matches of each local artifact, so cost stays bounded however large the corpus grows.
+// Only those matches are loaded, in one query per drive. Local hashes are normalized
+// with passes, as the index postings are; another drive's postings follow the passes
+// its artifacts were last indexed with.
+func GetCorpusPairs(
+	ctx context.Context,
+	driveID string,
+	bucketArtifacts []*models.Artifact,
+	difficulty string,
+	maxPerArtifact int,
+	passes []similarity.NormalizationPass,
+	artifactsRepo *repository.ArtifactsRepository,
+	indexRepo *repository.FingerprintIndexRepository,
+) ([]similarity.Pair, error) {
+	if len(bucketArtifacts) == 0 {
+		return nil, nil
+	}
+	if maxPerArtifact <= 0 {
+		maxPerArtifact = DefaultCorpusMatchesPerArtifact
+	}
+
+	qID := bucketArtifacts[0].QID
+	language := bucketArtifacts[0].Language
+	threshold := similarity.WorthyThreshold(difficulty)
+
+	type foreignKey struct{ driveID, attemptID string }
+	matches := make(map[*models.Artifact][]foreignKey)
+	attemptsByDrive := make(map[string][]string)
+	requested := make(map[foreignKey]bool)
+	for _, artifact := range bucketArtifacts {
+		fingerprints := similarity.FingerprintSet(artifact, passes...)
+		if len(fingerprints) == 0 {
+			continue
+		}
+		hashes := make([]string, 0, len(fingerprints))
+		for hash := range fingerprints {
+			hashes = append(hashes, hash)
+		}
+
+		overlaps, err := indexRepo.FindCorpusOverlaps(ctx, qID, language, hashes, driveID, threshold, maxPerArtifact)
+		if err != nil {
+			return nil, fmt.Errorf("failed to query fingerprint index: %w", err)
+		}
+		for _, overlap := range overlaps {
+			key := foreignKey{overlap.DriveID, overlap.AttemptID}
+			matches[artifact] = append(matches[artifact], key)
+			if !requested[key] {
+				requested[key] = true
+				attemptsByDrive[key.driveID] = append(attemptsByDrive[key.driveID], key.attemptID)
+			}
+		}
+	}
+	if len(matches) == 0 {
+		return nil, nil
+	}
+
+	foreignArtifacts := make(map[foreignKey]*models.Artifact, len(requested))
+	for foreignDriveID, attemptIDs := range attemptsByDrive {
+		artifacts, err := artifactsRepo.GetArtifactsByAttemptIDs(ctx, foreignDriveID, qID, attemptIDs)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load corpus artifacts: %w", err)
+		}
+		for _, artifact := range artifacts {
+			if artifact.Language == language {
+				foreignArtifacts[foreignKey{artifact.DriveID, artifact.AttemptID}] = artifact
+			}
+		}
+	}
+
+	pairs := make([]similarity.Pair, 0)
+	for _, artifact := range bucketArtifacts {
+		for _, key := range matches[artifact] {
+			foreign := foreignArtifacts[key]
+			if foreign == nil {
+				log.Warn().
+					Str("driveId", key.driveID).
+					Str("attemptID", key.attemptID).
+					Msg("Fingerprint index points to a missing artifact")
+				continue
+			}
+
+			pairs = append(pairs, similarity.Pair{
+				ArtifactA: artifact,
+				ArtifactB: foreign,
+			})
+		}
+	}
+
+	return pairs, nil
+}
@@ -0,0 +1,352 @@
+package plagiarism
+
+import (
+	"context"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/RishiKendai/aegis/internal/models"
+	"github.com/RishiKendai/aegis/internal/preprocess"
+	"github.com/RishiKendai/aegis/internal/repository"
+	"github.com/RishiKendai/aegis/similarity"
+)
+
+// Sources of a diff block
+const (
+	DiffSourceToken       = "token"
+	DiffSourceFingerprint = "fingerprint"
+)
+
+// LoadPairDiff loads the submissions of two attempts to a question and builds their diff.
+// The pair record, when there is one, supplies the scores and where B lives (another
+// drive or a reference solution). It returns nil when either submission is not found.
+func LoadPairDiff(
+	ctx context.Context,
+	artifactsRepo *repository.ArtifactsRepository,
+	resultsRepo *repository.ResultsRepository,
+	referencesRepo *repository.ReferencesRepository,
+	driveID string,
+	qID int64,
+	attemptA string,
+	attemptB string,
+) (*models.PairDiff, error) {
+	qIDStr := strconv.FormatInt(qID, 10)
+	pairResult, err := resultsRepo.GetPairResult(ctx, driveID, qIDStr, attemptA, attemptB)
+	if err != nil {
+		return nil, err
+	}
+
+	artifactA, err := loadDiffArtifact(ctx, artifactsRepo, referencesRepo, driveID, qID, attemptA, pairResult)
+	if err != nil || artifactA == nil {
+		return nil, err
+	}
+	artifactB, err := loadDiffArtifact(ctx, artifactsRepo, referencesRepo, driveID, qID, attemptB, pairResult)
+	if err != nil || artifactB == nil {
+		return nil, err
+	}
+
+	diff := BuildPairDiff(artifactA, artifactB)
+	diff.DriveID = driveID
+	diff.QID = qIDStr
+	if pairResult != nil {
+		finalScore, scores := pairResult.FinalScore, pairResult.Scores
+		diff.FinalScore = &finalScore
+		diff.Scores = &scores
+	}
+
+	return diff, nil
+}
+
+// loadDiffArtifact loads one side of a pair from wherever its pair record places it
+func loadDiffArtifact(
+	ctx context.Context,
+	artifactsRepo *repository.ArtifactsRepository,
+	referencesRepo *repository.ReferencesRepository,
+	driveID string,
+	qID int64,
+	attemptID string,
+	pairResult *models.PairResult,
+) (*models.Artifact, error) {
+	language := ""
+	if pairResult != nil {
+		language = pairResult.Language
+		if attemptID == pairResult.AttemptB {
+			if pairResult.KnownSource != "" {
+				reference, err := referencesRepo.GetReference(ctx, attemptID)
+				if err != nil || reference == nil {
+					return nil, err
+				}
+				return reference.Artifact(), nil
+			}
+			if pairResult.CrossDrive {
+				driveID = pairResult.DriveB
+			}
+			if pairResult.LanguageB != "" {
+				language = pairResult.LanguageB
+			}
+		}
+	}
+
+	artifacts, err := artifactsRepo.GetArtifactsByAttemptIDs(ctx, driveID, qID, []string{attemptID})
+	if err != nil {
+		return nil, err
+	}
+	for _, artifact := range artifacts {
+		if language == "" || artifact.Language == language {
+			return artifact, nil
+		}
+	}
+	return nil, nil
+}
+
+// BuildPairDiff matches two submissions and aligns their matched blocks. Stored
+// preprocessing output carries no source positions, so both sources are re-tokenized
+// with the built-in lexical preprocessor. Blocks are its Greedy String Tiling tiles
+// plus the runs of shared fingerprint k-grams that no tile covers, such as a region
+// copied twice. They match the scored tiles and fingerprints only when the stored
+// tokens are the local preprocessor's; otherwise the diff is marked approximate.
+func BuildPairDiff(artifactA, artifactB *models.Artifact) *models.PairDiff {
+	localA := preprocess.PreprocessLocally(artifactA.SourceCode, artifactA.Language)
+	localB := preprocess.PreprocessLocally(artifactB.SourceCode, artifactB.Language)
+
+	blocks := diffBlocks(localA, localB)
+	diff := &models.PairDiff{
+		A:      diffSide(artifactA),
+		B:      diffSide(artifactB),
+		Groups: len(blocks),
+		Blocks: blocks,
+		Approximate: !slices.Equal(artifactA.NormalizedTokens, localA.Data.NormalizedTokens) ||
+			!slices.Equal(artifactB.NormalizedTokens, localB.Data.NormalizedTokens),
+	}
+	diff.Rows = alignRows(diff.Blocks, diff.A.Lines, diff.B.Lines)
+
+	for _, row := range diff.Rows {
+		if row.GroupA != 0 {
+			diff.A.MatchedLines++
+		}
+		if row.GroupB != 0 {
+			diff.B.MatchedLines++
+		}
+	}
+
+	return diff
+}
+
+func diffSide(artifact *models.Artifact) models.DiffSide {
+	return models.DiffSide{
+		AttemptID:  artifact.AttemptID,
+		Email:      artifact.Email,
+		DriveID:    artifact.DriveID,
+		Language:   artifact.Language,
+		SourceCode: artifact.SourceCode,
+		Lines:      lineCount(artifact.SourceCode),
+	}
+}
+
+// diffBlocks returns the matched blocks ordered by their position in A, each in its
+// own colour group
+func diffBlocks(localA, localB *preprocess.LocalResult) []models.DiffBlock {
+	coveredA := make([]bool, len(localA.Data.NormalizedTokens))
+	coveredB := make([]bool, len(localB.Data.NormalizedTokens))
+	blocks := make([]models.DiffBlock, 0)
+
+	add := func(source string, tile similarity.Tile) {
+		for k := 0; k < tile.Length; k++ {
+			coveredA[tile.StartA+k] = true
+			coveredB[tile.StartB+k] = true
+		}
+		endA, endB := tile.StartA+tile.Length-1, tile.StartB+tile.Length-1
+		blocks = append(blocks, models.DiffBlock{
+			Source:  source,
+			TokensA: [2]int{tile.StartA, endA},
+			TokensB: [2]int{tile.StartB, endB},
+			LinesA:  [2]int{localA.TokenLines[tile.StartA], localA.TokenLines[endA]},
+			LinesB:  [2]int{localB.TokenLines[tile.StartB], localB.TokenLines[endB]},
+			Length:  tile.Length,
+		})
+	}
+
+	for _, tile := range similarity.MatchedTiles(localA.Data.NormalizedTokens, localB.Data.NormalizedTokens) {
+		add(DiffSourceToken, tile)
+	}
+
+	// A k-gram run may repeat a region the other side already matched once, so only the
+	// side it starts from has to be uncovered
+	for _, run := range sharedKGramRuns(localA.Data.Fingerprints, localB.Data.Fingerprints) {
+		if !anyCovered(coveredA, run.StartA, run.Length) {
+			add(DiffSourceFingerprint, run)
+		}
+	}
+	for _, run := range sharedKGramRuns(localB.Data.Fingerprints, localA.Data.Fingerprints) {
+		if !anyCovered(coveredB, run.StartA, run.Length) {
+			add(DiffSourceFingerprint, similarity.Tile{StartA: run.StartB, StartB: run.StartA, Length: run.Length})
+		}
+	}
+
+	sort.SliceStable(blocks, func(i, j int) bool {
+		if blocks[i].TokensA[0] != blocks[j].TokensA[0] {
+			return blocks[i].TokensA[0] < blocks[j].TokensA[0]
+		}
+		return blocks[i].TokensB[0] < blocks[j].TokensB[0]
+	})
+	for i := range blocks {
+		blocks[i].Group = i + 1
+	}
+
+	return blocks
+}
+
+// sharedKGramRuns maps the fingerprints of one side to the first position of the same
+// hash on the other side and merges consecutive k-grams at a constant offset into runs.
+// StartA is on the from side.
+func sharedKGramRuns(from, to *models.Fingerprints) []similarity.Tile {
+	runs := make([]similarity.Tile, 0)
+	if from == nil || to == nil || from.KGramSize <= 0 {
+		return runs
+	}
+
+	positions := make(map[string]int, len(to.Hashes))
+	for _, entry := range to.Hashes {
+		if _, exists := positions[entry.Hash]; !exists {
+			positions[entry.Hash] = entry.Position
+		}
+	}
+
+	for _, entry := range from.Hashes {
+		position, shared := positions[entry.Hash]
+		if !shared {
+			continue
+		}
+		if len(runs) > 0 {
+			last := &runs[len(runs)-1]
+			if entry.Position-last.StartA == position-last.StartB && entry.Position <= last.StartA+last.Length {
+				last.Length = entry.Position + from.KGramSize - last.StartA
+				continue
+			}
+		}
+		runs = append(runs, similarity.Tile{StartA: entry.Position, StartB: position, Length: from.KGramSize})
+	}
+
+	return runs
+}
+
+func anyCovered(covered []bool, start, length int) bool {
+	for k := start; k < start+length; k++ {
+		if covered[k] {
+			return true
+		}
+	}
+	return false
+}
+
+// alignRows lays both sources out side by side. The heaviest chain of blocks in the
+// same order in both sources is aligned; the lines before, between and after them are
+// paired in order, the shorter side padded. Other blocks are only coloured.
+func alignRows(blocks []models.DiffBlock, linesA, linesB int) []models.DiffRow {
+	chain := alignedChain(blocks)
+	for _, i := range chain {
+		blocks[i].Aligned = true
+	}
+	groupsA, groupsB := lineGroups(blocks, linesA, linesB)
+
+	rows := make([]models.DiffRow, 0, max(linesA, linesB))
+	lineA, lineB := 1, 1
+	pairUp := func(endA, endB int) {
+		for lineA <= endA || lineB <= endB {
+			var row models.DiffRow
+			if lineA <= endA {
+				row.LineA, row.GroupA = lineA, groupsA[lineA]
+				lineA++
+			}
+			if lineB <= endB {
+				row.LineB, row.GroupB = lineB, groupsB[lineB]
+				lineB++
+			}
+			rows = append(rows, row)
+		}
+	}
+
+	for _, i := range chain {
+		pairUp(blocks[i].LinesA[0]-1, blocks[i].LinesB[0]-1)
+		pairUp(blocks[i].LinesA[1], blocks[i].LinesB[1])
+	}
+	pairUp(linesA, linesB)
+
+	return rows
+}
+
+// alignedChain returns the indices of the blocks, longest in total, that follow each
+// other in both sources. Blocks are ordered by their position in A.
+func alignedChain(blocks []models.DiffBlock) []int {
+	if len(blocks) == 0 {
+		return nil
+	}
+
+	weight := make([]int, len(blocks))
+	previous := make([]int, len(blocks))
+	best := 0
+	for i, block := range blocks {
+		weight[i], previous[i] = block.Length, -1
+		for j := 0; j < i; j++ {
+			if follows(blocks[j], block) && weight[j]+block.Length > weight[i] {
+				weight[i], previous[i] = weight[j]+block.Length, j
+			}
+		}
+		if weight[i] > weight[best] {
+			best = i
+		}
+	}
+
+	chain := make([]int, 0)
+	for i := best; i >= 0; i = previous[i] {
+		chain = append(chain, i)
+	}
+	for left, right := 0, len(chain)-1; left < right; left, right = left+1, right-1 {
+		chain[left], chain[right] = chain[right], chain[left]
+	}
+	return chain
+}
+
+// follows reports whether next comes after block in both sources. Tiles split by a
+// single differing token share a line, so lines may touch but tokens must not overlap.
+func follows(block, next models.DiffBlock) bool {
+	return block.TokensA[1] < next.TokensA[0] && block.TokensB[1] < next.TokensB[0] &&
+		block.LinesA[1] <= next.LinesA[0] && block.LinesB[1] <= next.LinesB[0]
+}
+
+// lineGroups colours every line with the group of a block covering it, aligned
+// blocks first; index 0 is unused
+func lineGroups(blocks []models.DiffBlock, linesA, linesB int) ([]int, []int) {
+	groupsA := make([]int, linesA+1)
+	groupsB := make([]int, linesB+1)
+
+	for _, aligned := range []bool{true, false} {
+		for _, block := range blocks {
+			if block.Aligned != aligned {
+				continue
+			}
+			for line := block.LinesA[0]; line <= block.LinesA[1] && line <= linesA; line++ {
+				if groupsA[line] == 0 {
+					groupsA[line] = block.Group
+				}
+			}
+			for line := block.LinesB[0]; line <= block.LinesB[1] && line <= linesB; line++ {
+				if groupsB[line] == 0 {
+					groupsB[line] = block.Group
+				}
+			}
+		}
+	}
+
+	return groupsA, groupsB
+}
+
+// lineCount counts source lines as the tokenizer numbers them, ignoring a final newline
+func lineCount(source string) int {
+	if source == "" {
+		return 0
+	}
+	return strings.Count(strings.TrimSuffix(source, "\n"), "\n") + 1
+}
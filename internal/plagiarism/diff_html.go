@@ -0,0 +1,70 @@
+package plagiarism
+
+import (
+	"io"
+	"strings"
+
+	"github.com/RishiKendai/aegis/internal/models"
+)
+
+// diffHTMLRow is a DiffRow with its source text
+type diffHTMLRow struct {
+	models.DiffRow
+	TextA string
+	TextB string
+	MoveA bool // the line belongs to a block that was not aligned
+	MoveB bool
+}
+
+// diffView is the data of the diffBody template. Anchor prefixes line IDs so several
+// diffs can share a page.
+type diffView struct {
+	Diff   *models.PairDiff
+	Rows   []diffHTMLRow
+	Anchor string
+}
+
+// RenderPairDiffHTML writes a diff as a self-contained HTML page: the matched blocks
+// share a colour on both sides, and blocks that could not be aligned are marked as moved
+func RenderPairDiffHTML(w io.Writer, diff *models.PairDiff) error {
+	return htmlTemplates.ExecuteTemplate(w, "diff", newDiffView(diff, ""))
+}
+
+// newDiffView pairs the rows of a diff with their source lines
+func newDiffView(diff *models.PairDiff, anchor string) *diffView {
+	linesA := sourceLines(diff.A.SourceCode)
+	linesB := sourceLines(diff.B.SourceCode)
+
+	aligned := make(map[int]bool, len(diff.Blocks))
+	for _, block := range diff.Blocks {
+		aligned[block.Group] = block.Aligned
+	}
+
+	rows := make([]diffHTMLRow, 0, len(diff.Rows))
+	for _, row := range diff.Rows {
+		htmlRow := diffHTMLRow{DiffRow: row}
+		if row.LineA > 0 && row.LineA <= len(linesA) {
+			htmlRow.TextA = linesA[row.LineA-1]
+			htmlRow.MoveA = row.GroupA != 0 && !aligned[row.GroupA]
+		}
+		if row.LineB > 0 && row.LineB <= len(linesB) {
+			htmlRow.TextB = linesB[row.LineB-1]
+			htmlRow.MoveB = row.GroupB != 0 && !aligned[row.GroupB]
+		}
+		rows = append(rows, htmlRow)
+	}
+
+	return &diffView{Diff: diff, Rows: rows, Anchor: anchor}
+}
+
+// sourceLines splits source into the lines lineCount counts
+func sourceLines(source string) []string {
+	if source == "" {
+		return nil
+	}
+	lines := strings.Split(strings.TrimSuffix(source, "\n"), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimSuffix(line, "\r")
+	}
+	return lines
+}
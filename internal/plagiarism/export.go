@@ -0,0 +1,150 @@
+package plagiarism
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/RishiKendai/aegis/internal/models"
+	"github.com/RishiKendai/aegis/internal/repository"
+)
+
+// Export formats of candidate results and pair records
+const (
+	ExportFormatCSV   = "csv"
+	ExportFormatJSONL = "jsonl"
+)
+
+// IsValidExportFormat reports whether a format is csv or jsonl
+func IsValidExportFormat(format string) bool {
+	return format == ExportFormatCSV || format == ExportFormatJSONL
+}
+
+// candidateColumns are the CSV columns of a candidate result; JSON Lines carry the full record
+var candidateColumns = []string{
+	"attemptID", "email", "risk", "effective_risk", "score", "strategy", "distinct_peers",
+	"flagged_qns", "code_similarity", "algo_similarity", "ai_likelihood", "ai_level",
+	"verdict", "reviewer", "plagiarism_status",
+}
+
+// pairColumns are the CSV columns of a pair record
+var pairColumns = []string{
+	"qId", "language", "languageB", "difficulty", "attemptA", "emailA", "attemptB", "emailB",
+	"final_score", "fingerprint", "token", "ast", "cfg", "cross_language", "cross_drive",
+	"driveB", "known_source", "likely_source", "direction_basis", "verdict", "suppressed",
+}
+
+// ExportCandidates streams the candidate results of a drive to w as CSV or JSON Lines
+func ExportCandidates(ctx context.Context, resultsRepo *repository.ResultsRepository, driveID, format string, w io.Writer) error {
+	return exportRecords(format, w, candidateColumns, func(write func(interface{}, []string) error) error {
+		return resultsRepo.StreamCandidateResults(ctx, driveID, func(result *models.CandidateResult) error {
+			return write(result, candidateRow(result))
+		})
+	})
+}
+
+// ExportPairs streams the pair records of a drive to w as CSV or JSON Lines, highest
+// score first. Suppressed pairs are included and marked.
+func ExportPairs(ctx context.Context, resultsRepo *repository.ResultsRepository, driveID, format string, w io.Writer) error {
+	return exportRecords(format, w, pairColumns, func(write func(interface{}, []string) error) error {
+		return resultsRepo.StreamPairResults(ctx, driveID, func(pair *models.PairResult) error {
+			return write(pair, pairRow(pair))
+		})
+	})
+}
+
+// exportRecords writes the records stream produces, as CSV rows under columns or as one
+// JSON object per line
+func exportRecords(format string, w io.Writer, columns []string, stream func(write func(interface{}, []string) error) error) error {
+	switch format {
+	case ExportFormatJSONL:
+		encoder := json.NewEncoder(w)
+		return stream(func(record interface{}, _ []string) error {
+			return encoder.Encode(record)
+		})
+	case ExportFormatCSV:
+		writer := csv.NewWriter(w)
+		if err := writer.Write(columns); err != nil {
+			return err
+		}
+		if err := stream(func(_ interface{}, row []string) error {
+			return writer.Write(row)
+		}); err != nil {
+			return err
+		}
+		writer.Flush()
+		return writer.Error()
+	default:
+		return fmt.Errorf("unknown export format %q", format)
+	}
+}
+
+func candidateRow(result *models.CandidateResult) []string {
+	score, strategy, peers := 0.0, "", 0
+	if result.RiskExplanation != nil {
+		score = result.RiskExplanation.Score
+		strategy = result.RiskExplanation.Strategy
+		peers = result.RiskExplanation.DistinctPeers
+	}
+	verdict, reviewer := "", ""
+	if result.Review != nil {
+		verdict, reviewer = result.Review.Verdict, result.Review.Reviewer
+	}
+
+	return []string{
+		result.AttemptID,
+		result.Email,
+		result.Risk,
+		result.EffectiveRisk,
+		formatScore(score),
+		strategy,
+		strconv.Itoa(peers),
+		strings.Join(result.FlaggedQuestions, ";"),
+		strconv.Itoa(result.CodeSimilarity),
+		strconv.Itoa(result.AlgoSimilarity),
+		formatScore(result.AILikelihood),
+		result.AILevel,
+		verdict,
+		reviewer,
+		result.PlagiarismStatus,
+	}
+}
+
+func pairRow(pair *models.PairResult) []string {
+	verdict := ""
+	if pair.Review != nil {
+		verdict = pair.Review.Verdict
+	}
+
+	return []string{
+		pair.QID,
+		pair.Language,
+		pair.LanguageB,
+		pair.Difficulty,
+		pair.AttemptA,
+		pair.EmailA,
+		pair.AttemptB,
+		pair.EmailB,
+		formatScore(pair.FinalScore),
+		formatScore(pair.Scores.Fingerprint),
+		formatScore(pair.Scores.Token),
+		formatScore(pair.Scores.AST),
+		formatScore(pair.Scores.CFG),
+		strconv.FormatBool(pair.CrossLanguage),
+		strconv.FormatBool(pair.CrossDrive),
+		pair.DriveB,
+		pair.KnownSource,
+		pair.LikelySource,
+		pair.DirectionBasis,
+		verdict,
+		strconv.FormatBool(pair.Suppressed),
+	}
+}
+
+func formatScore(score float64) string {
+	return strconv.FormatFloat(score, 'f', 4, 64)
+}
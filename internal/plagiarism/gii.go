@@ -1,164 +1,125 @@
 package plagiarism
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/RishiKendai/aegis/internal/models"
+	"github.com/RishiKendai/aegis/internal/repository"
+	"github.com/RishiKendai/aegis/similarity"
 	"github.com/rs/zerolog/log"
 )
 
-// GII (Global Inverted Index) maps hash → [submission_ids]
-type GII map[string][]string
+// LoadBucketGII reads a bucket's GII from the persistent fingerprint index.
+// Artifacts ingested before the index existed are indexed on the fly, so the
+// index converges without a separate migration.
+func LoadBucketGII(
+	ctx context.Context,
+	driveID string,
+	bucketArtifacts []*models.Artifact,
+	indexRepo *repository.FingerprintIndexRepository,
+) (similarity.GII, map[string]int, error) {
+	if len(bucketArtifacts) == 0 {
+		return similarity.GII{}, map[string]int{}, nil
+	}
 
-func BuildGII(artifacts []*models.Artifact) GII {
-	gii := make(GII)
+	qID := bucketArtifacts[0].QID
+	language := bucketArtifacts[0].Language
 
-	// First pass: Build hash → [attemptIds] mapping
-	for _, artifact := range artifacts {
-		if artifact.Fingerprints == nil {
+	postings, hashCounts, err := indexRepo.GetBucketPostings(ctx, driveID, qID, language)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Backfill artifacts missing from the index, then re-read the bucket
+	backfilled := 0
+	for _, artifact := range bucketArtifacts {
+		fingerprints := artifact.IndexedFingerprints()
+		if _, indexed := hashCounts[artifact.AttemptID]; indexed || fingerprints == nil || len(fingerprints.Hashes) == 0 {
 			continue
 		}
-
-		attemptID := artifact.AttemptID
-		seenHashes := make(map[string]bool)
-		for _, hashEntry := range artifact.Fingerprints.Hashes {
-			hash := hashEntry.Hash
-			if !seenHashes[hash] {
-				seenHashes[hash] = true
-				gii[hash] = append(gii[hash], attemptID)
-				log.Trace().
-					Str("hash", hash).
-					Str("Email ", artifact.Email).
-					Str("attemptID", fmt.Sprintf("%+v", gii[hash])).
-					Msg("gii hash")
-			}
+		if err := indexRepo.IndexArtifact(ctx, artifact); err != nil {
+			return nil, nil, fmt.Errorf("failed to backfill fingerprint index: %w", err)
 		}
+		backfilled++
 	}
 
-	// Second pass: Filter out hashes with only 1 candidate (optimization)
-	filteredGII := make(GII)
-	for hash, attemptIDs := range gii {
-		if len(attemptIDs) >= 2 {
-			// Only include hashes with 2+ candidates
-			filteredGII[hash] = attemptIDs
+	if backfilled > 0 {
+		log.Info().
+			Str("driveId", driveID).
+			Int64("qId", qID).
+			Str("language", language).
+			Int("backfilled", backfilled).
+			Msg("Backfilled fingerprint index")
+
+		postings, hashCounts, err = indexRepo.GetBucketPostings(ctx, driveID, qID, language)
+		if err != nil {
+			return nil, nil, err
 		}
 	}
 
-	return filteredGII
+	return similarity.GII(postings), hashCounts, nil
 }
 
-// GetWorthyPairs finds worthy pairs based on difficulty threshold
-func GetWorthyPairs(gii GII, artifacts []*models.Artifact, difficulty string) []Pair {
-	// Build artifact map for quick lookup
-	artifactMap := make(map[string]*models.Artifact)
+// EnsureNormalizedFingerprints brings the normalized fingerprints of a question's
+// artifacts in line with the passes, so candidate generation can stay on the persistent
+// index. Artifacts ingested before the passes changed (or before normalized fingerprints
+// existed) have their normalized tokens loaded, are re-fingerprinted, stored and
+// re-indexed; the heavy tokens are dropped again afterwards.
+func EnsureNormalizedFingerprints(
+	ctx context.Context,
+	driveID string,
+	qID int64,
+	artifacts []*models.Artifact,
+	passes []similarity.NormalizationPass,
+	artifactsRepo *repository.ArtifactsRepository,
+	indexRepo *repository.FingerprintIndexRepository,
+) error {
+	stale := make(map[artifactKey]*models.Artifact)
+	attemptIDs := make([]string, 0)
 	for _, artifact := range artifacts {
-		artifactMap[artifact.AttemptID] = artifact
-	}
-
-	// Get threshold based on difficulty
-	threshold := getWorthyThreshold(difficulty)
-
-	sharedPairCount := make(map[string]int)
-	pairArtifacts := make(map[string]Pair)
-
-	for _, attemptIDs := range gii {
-		if len(attemptIDs) < 2 {
+		if similarity.HasFingerprintsFor(artifact, passes) {
 			continue
 		}
-
-		// Get artifacts for this hash
-		hashArtifacts := make([]*models.Artifact, 0)
-		for _, attemptID := range attemptIDs {
-			if artifact, ok := artifactMap[attemptID]; ok {
-				hashArtifacts = append(hashArtifacts, artifact)
-			}
-		}
-
-		// Calculate shared hashes for each pair
-		for i := 0; i < len(hashArtifacts); i++ {
-			for j := i + 1; j < len(hashArtifacts); j++ {
-				artifactA := hashArtifacts[i]
-				artifactB := hashArtifacts[j]
-
-				pairKey := getPairKey(artifactA.AttemptID, artifactB.AttemptID)
-				sharedPairCount[pairKey]++
-				if _, exists := pairArtifacts[pairKey]; !exists {
-					pairArtifacts[pairKey] = Pair{
-						ArtifactA: artifactA,
-						ArtifactB: artifactB,
-					}
-				}
-				log.Trace().
-					Str("pairKey", pairKey).
-					Int("sharedPairCount", sharedPairCount[pairKey]).
-					Msg("shared pair count")
-			}
-		}
-	}
-
-	// Convert map to slice
-	worthyPairs := make([]Pair, 0)
-	for pairKey, sharedCount := range sharedPairCount {
-		pair := pairArtifacts[pairKey]
-		overlap := calculateOverlap(pair.ArtifactA, pair.ArtifactB, sharedCount)
-
-		if overlap >= threshold {
-			worthyPairs = append(worthyPairs, pair)
+		key := artifactKey{artifact.AttemptID, artifact.Language}
+		if _, exists := stale[key]; !exists {
+			stale[key] = artifact
+			attemptIDs = append(attemptIDs, artifact.AttemptID)
 		}
 	}
-	return worthyPairs
-}
-
-func calculateOverlap(artifactA, artifactB *models.Artifact, sharedCount int) float64 {
-	if artifactA.Fingerprints == nil || artifactB.Fingerprints == nil {
-		return 0.0
-	}
-
-	hashesA := make(map[string]bool)
-	for _, hashEntry := range artifactA.Fingerprints.Hashes {
-		hashesA[hashEntry.Hash] = true
-	}
-
-	hashesB := make(map[string]bool)
-	for _, hashEntry := range artifactB.Fingerprints.Hashes {
-		hashesB[hashEntry.Hash] = true
+	if len(stale) == 0 {
+		return nil
 	}
 
-	totalA := len(hashesA)
-	totalB := len(hashesB)
-
-	if totalA == 0 || totalB == 0 {
-		return 0.0
+	tokens := make(map[artifactKey][]string, len(stale))
+	if len(passes) > 0 {
+		loaded, err := artifactsRepo.GetArtifactFieldsByAttemptIDs(ctx, driveID, qID, attemptIDs, repository.ArtifactFieldsNormalizedTokens)
+		if err != nil {
+			return fmt.Errorf("failed to load normalized tokens: %w", err)
+		}
+		for _, artifact := range loaded {
+			tokens[artifactKey{artifact.AttemptID, artifact.Language}] = artifact.NormalizedTokens
+		}
 	}
 
-	minTotal := min(totalA, totalB)
-	return float64(sharedCount) / float64(minTotal)
-}
+	for key, artifact := range stale {
+		artifact.NormalizedTokens = tokens[key]
+		similarity.NormalizeFingerprints(artifact, passes)
+		artifact.NormalizedTokens = nil
 
-// getWorthyThreshold returns threshold based on difficulty
-func getWorthyThreshold(difficulty string) float64 {
-	switch difficulty {
-	case "easy":
-		return 0.15 // 15%
-	case "medium":
-		return 0.10 // 10%
-	case "hard":
-		return 0.05 // 5%
-	default:
-		return 0.10 // Default to medium
+		if err := artifactsRepo.SetNormalizedFingerprints(ctx, artifact); err != nil {
+			return err
+		}
+		if err := indexRepo.IndexArtifact(ctx, artifact); err != nil {
+			return fmt.Errorf("failed to re-index artifact: %w", err)
+		}
 	}
-}
 
-// Pair represents a pair of artifacts to compare
-type Pair struct {
-	ArtifactA *models.Artifact
-	ArtifactB *models.Artifact
-}
+	log.Info().
+		Str("driveId", driveID).
+		Int64("qId", qID).
+		Int("artifacts", len(stale)).
+		Msg("Rebuilt normalized fingerprints")
 
-// getPairKey creates a sorted key for a pair to avoid duplicates
-func getPairKey(id1, id2 string) string {
-	if id1 < id2 {
-		return id1 + ":" + id2
-	}
-	return id2 + ":" + id1
+	return nil
 }
@@ -0,0 +1,341 @@
+package plagiarism
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/RishiKendai/aegis/internal/models"
+	"github.com/RishiKendai/aegis/internal/preprocess"
+	"github.com/RishiKendai/aegis/internal/repository"
+)
+
+const (
+	// jplagMatchSensitivity is the minimum tile length of the token layer, JPlag's
+	// minimum token match
+	jplagMatchSensitivity = 5
+	// jplagDistributionSize is the number of similarity buckets of a JPlag 4 metric
+	jplagDistributionSize = 10
+)
+
+// jplagVersion is the JPlag result format the archive follows
+var jplagVersion = jplagVersionInfo{Major: 4, Minor: 0, Patch: 0}
+
+type jplagVersionInfo struct {
+	Major int `json:"major"`
+	Minor int `json:"minor"`
+	Patch int `json:"patch"`
+}
+
+// jplagOverview is overview.json of a JPlag result archive
+type jplagOverview struct {
+	JPlagVersion                      jplagVersionInfo             `json:"jplag_version"`
+	SubmissionFolderPath              []string                     `json:"submission_folder_path"`
+	BaseCodeFolderPath                string                       `json:"base_code_folder_path"`
+	Language                          string                       `json:"language"`
+	FileExtensions                    []string                     `json:"file_extensions"`
+	SubmissionIDToDisplayName         map[string]string            `json:"submission_id_to_display_name"`
+	SubmissionIDsToComparisonFileName map[string]map[string]string `json:"submission_ids_to_comparison_file_name"`
+	FailedSubmissionNames             []string                     `json:"failed_submission_names"`
+	ExcludedFiles                     []string                     `json:"excluded_files"`
+	MatchSensitivity                  int                          `json:"match_sensitivity"`
+	DateOfExecution                   string                       `json:"date_of_execution"`
+	ExecutionTime                     int64                        `json:"execution_time"`
+	Metrics                           []jplagMetric                `json:"metrics"`
+	Clusters                          []jplagCluster               `json:"clusters"`
+	TotalComparisons                  int                          `json:"total_comparisons"`
+}
+
+type jplagMetric struct {
+	Name           string            `json:"name"`
+	Distribution   []int             `json:"distribution"` // highest bucket first
+	TopComparisons []jplagComparison `json:"topComparisons"`
+	Description    string            `json:"description"`
+}
+
+type jplagComparison struct {
+	FirstSubmission  string  `json:"first_submission"`
+	SecondSubmission string  `json:"second_submission"`
+	Similarity       float64 `json:"similarity"`
+}
+
+type jplagCluster struct {
+	AverageSimilarity float64  `json:"average_similarity"`
+	Strength          float64  `json:"strength"`
+	Members           []string `json:"members"`
+}
+
+// jplagComparisonFile is the <id1>-<id2>.json file of a compared pair
+type jplagComparisonFile struct {
+	ID1          string             `json:"id1"`
+	ID2          string             `json:"id2"`
+	Similarities map[string]float64 `json:"similarities"`
+	Matches      []jplagMatch       `json:"matches"`
+}
+
+type jplagMatch struct {
+	File1  string `json:"file1"`
+	File2  string `json:"file2"`
+	Start1 int    `json:"start1"`
+	End1   int    `json:"end1"`
+	Start2 int    `json:"start2"`
+	End2   int    `json:"end2"`
+	Tokens int    `json:"tokens"`
+}
+
+// WriteJPlagArchive writes the results of one question of a drive as a JPlag 4 result
+// archive, which the JPlag report viewer opens. JPlag compares one language at a time:
+// the question's most common language is used unless language is given. Only pairs of
+// two submissions of the drive are included, with the final score as AVG similarity
+// and matches from the pair diff. It reports false, writing nothing, when the question
+// has no submissions.
+func WriteJPlagArchive(
+	ctx context.Context,
+	artifactsRepo *repository.ArtifactsRepository,
+	resultsRepo *repository.ResultsRepository,
+	driveID string,
+	qID int64,
+	language string,
+	w io.Writer,
+) (bool, error) {
+	artifacts, err := artifactsRepo.GetArtifactsByDriveIDAndQID(ctx, driveID, qID, repository.ArtifactFieldsAll)
+	if err != nil {
+		return false, err
+	}
+	if language == "" {
+		language = mostCommonLanguage(artifacts)
+	}
+
+	submissions := make(map[string]*models.Artifact)
+	for _, artifact := range artifacts {
+		if artifact.Language == language {
+			submissions[artifact.AttemptID] = artifact
+		}
+	}
+	if len(submissions) == 0 {
+		return false, nil
+	}
+
+	qIDStr := strconv.FormatInt(qID, 10)
+	pairs, err := resultsRepo.GetPairResultsByQID(ctx, driveID, qIDStr)
+	if err != nil {
+		return false, err
+	}
+	testReport, err := resultsRepo.GetLatestReportByDriveID(ctx, driveID)
+	if err != nil {
+		return false, err
+	}
+
+	extension := preprocess.FileExtension(language)
+	overview := &jplagOverview{
+		JPlagVersion:                      jplagVersion,
+		SubmissionFolderPath:              []string{driveID + "/" + qIDStr},
+		Language:                          language,
+		FileExtensions:                    []string{extension},
+		SubmissionIDToDisplayName:         make(map[string]string, len(submissions)),
+		SubmissionIDsToComparisonFileName: make(map[string]map[string]string, len(submissions)),
+		FailedSubmissionNames:             []string{},
+		ExcludedFiles:                     []string{},
+		MatchSensitivity:                  jplagMatchSensitivity,
+		DateOfExecution:                   time.Now().Format("02/01/06"),
+		Clusters:                          []jplagCluster{},
+	}
+	if testReport != nil && !testReport.CreatedAt.IsZero() {
+		overview.DateOfExecution = testReport.CreatedAt.Format("02/01/06")
+	}
+
+	archive := zip.NewWriter(w)
+
+	attemptIDs := make([]string, 0, len(submissions))
+	for attemptID := range submissions {
+		attemptIDs = append(attemptIDs, attemptID)
+	}
+	sort.Strings(attemptIDs)
+
+	submissionIDs := make(map[string]string, len(submissions)) // attemptID -> submission ID
+	usedIDs := make(map[string]bool, len(submissions))
+	for _, attemptID := range attemptIDs {
+		artifact := submissions[attemptID]
+		id := jplagSubmissionID(attemptID, usedIDs)
+		submissionIDs[attemptID] = id
+		overview.SubmissionIDToDisplayName[id] = artifact.Email
+		overview.SubmissionIDsToComparisonFileName[id] = make(map[string]string)
+		if err := writeZipFile(archive, path.Join("submissions", id, "solution"+extension), []byte(artifact.SourceCode)); err != nil {
+			return false, err
+		}
+	}
+
+	average := make([]jplagComparison, 0, len(pairs))
+	maximum := make([]jplagComparison, 0, len(pairs))
+	for _, pair := range pairs {
+		artifactA, artifactB := submissions[pair.AttemptA], submissions[pair.AttemptB]
+		if pair.Language != language || pair.CrossLanguage || pair.CrossDrive || pair.KnownSource != "" ||
+			artifactA == nil || artifactB == nil {
+			continue
+		}
+
+		idA, idB := submissionIDs[pair.AttemptA], submissionIDs[pair.AttemptB]
+		comparison := jplagComparisonFile{
+			ID1: idA,
+			ID2: idB,
+			Similarities: map[string]float64{
+				"AVG": pair.FinalScore,
+				"MAX": math.Max(pair.FinalScore, math.Max(pair.Containment.Token.AInB, pair.Containment.Token.BInA)),
+			},
+			Matches: make([]jplagMatch, 0),
+		}
+		fileA := path.Join(idA, "solution"+extension)
+		fileB := path.Join(idB, "solution"+extension)
+		for _, block := range BuildPairDiff(artifactA, artifactB).Blocks {
+			comparison.Matches = append(comparison.Matches, jplagMatch{
+				File1:  fileA,
+				File2:  fileB,
+				Start1: block.LinesA[0],
+				End1:   block.LinesA[1],
+				Start2: block.LinesB[0],
+				End2:   block.LinesB[1],
+				Tokens: block.Length,
+			})
+		}
+
+		fileName := idA + "-" + idB + ".json"
+		if err := writeZipJSON(archive, fileName, comparison); err != nil {
+			return false, err
+		}
+		overview.SubmissionIDsToComparisonFileName[idA][idB] = fileName
+		overview.SubmissionIDsToComparisonFileName[idB][idA] = fileName
+
+		average = append(average, jplagComparison{FirstSubmission: idA, SecondSubmission: idB, Similarity: comparison.Similarities["AVG"]})
+		maximum = append(maximum, jplagComparison{FirstSubmission: idA, SecondSubmission: idB, Similarity: comparison.Similarities["MAX"]})
+	}
+
+	overview.TotalComparisons = len(average)
+	overview.Metrics = []jplagMetric{
+		jplagMetricOf("AVG", "AEGIS final score of the pair", average),
+		jplagMetricOf("MAX", "Highest of the final score and the token containment of either submission", maximum),
+	}
+	if testReport != nil {
+		overview.Clusters = jplagClusters(testReport.Clusters, qIDStr, submissionIDs)
+	}
+
+	if err := writeZipJSON(archive, "overview.json", overview); err != nil {
+		return false, err
+	}
+	if err := archive.Close(); err != nil {
+		return false, fmt.Errorf("failed to write JPlag archive: %w", err)
+	}
+	return true, nil
+}
+
+// jplagMetricOf builds a metric from the comparisons, sorted by similarity
+func jplagMetricOf(name, description string, comparisons []jplagComparison) jplagMetric {
+	sort.SliceStable(comparisons, func(i, j int) bool {
+		return comparisons[i].Similarity > comparisons[j].Similarity
+	})
+
+	distribution := make([]int, jplagDistributionSize)
+	for _, comparison := range comparisons {
+		bucket := min(int(comparison.Similarity*jplagDistributionSize), jplagDistributionSize-1)
+		distribution[jplagDistributionSize-1-max(bucket, 0)]++
+	}
+
+	return jplagMetric{
+		Name:           name,
+		Distribution:   distribution,
+		TopComparisons: comparisons,
+		Description:    description,
+	}
+}
+
+// jplagClusters keeps the clusters involving the question, with members that are submissions
+func jplagClusters(clusters []models.Cluster, qID string, submissionIDs map[string]string) []jplagCluster {
+	result := make([]jplagCluster, 0)
+	for _, cluster := range clusters {
+		involved := false
+		for _, question := range cluster.Questions {
+			involved = involved || question == qID
+		}
+		if !involved {
+			continue
+		}
+
+		members := make([]string, 0, len(cluster.Members))
+		for _, attemptID := range cluster.Members {
+			if id, exists := submissionIDs[attemptID]; exists {
+				members = append(members, id)
+			}
+		}
+		if len(members) > 1 {
+			result = append(result, jplagCluster{
+				AverageSimilarity: cluster.AverageScore,
+				Strength:          cluster.Density,
+				Members:           members,
+			})
+		}
+	}
+	return result
+}
+
+// mostCommonLanguage returns the language most artifacts use, the first in name order on ties
+func mostCommonLanguage(artifacts []*models.Artifact) string {
+	counts := make(map[string]int)
+	for _, artifact := range artifacts {
+		counts[artifact.Language]++
+	}
+
+	best := ""
+	for language, count := range counts {
+		if count > counts[best] || count == counts[best] && language < best {
+			best = language
+		}
+	}
+	return best
+}
+
+// jplagSubmissionID turns an attemptID into a submission ID safe for folder and file
+// names. '-' separates the two IDs of a comparison file name, so it is replaced too.
+// Attempts that sanitise to an ID already in used get a _2, _3, ... suffix; the new
+// ID is added to used.
+func jplagSubmissionID(attemptID string, used map[string]bool) string {
+	base := strings.Map(func(r rune) rune {
+		if r == '_' || r == '.' || unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return r
+		}
+		return '_'
+	}, attemptID)
+
+	id := base
+	for suffix := 2; used[id]; suffix++ {
+		id = fmt.Sprintf("%s_%d", base, suffix)
+	}
+	used[id] = true
+	return id
+}
+
+func writeZipJSON(archive *zip.Writer, name string, value interface{}) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s: %w", name, err)
+	}
+	return writeZipFile(archive, name, data)
+}
+
+func writeZipFile(archive *zip.Writer, name string, data []byte) error {
+	file, err := archive.Create(name)
+	if err != nil {
+		return fmt.Errorf("failed to add %s to archive: %w", name, err)
+	}
+	if _, err := file.Write(data); err != nil {
+		return fmt.Errorf("failed to write %s: %w", name, err)
+	}
+	return nil
+}
@@ -0,0 +1,153 @@
+package plagiarism
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/RishiKendai/aegis/internal/models"
+	"github.com/RishiKendai/aegis/internal/repository"
+	"github.com/RishiKendai/aegis/similarity"
+)
+
+// artifactLoader fills in the heavy fields (source, tokens, AST, CFG) of one question's
+// artifacts on demand. Filtering only needs fingerprints, so heavy fields are fetched
+// for artifacts that actually take part in a worthy pair.
+type artifactLoader struct {
+	artifactsRepo *repository.ArtifactsRepository
+	driveID       string
+	qID           int64
+	loaded        map[artifactKey]bool // heavy fields present
+}
+
+// artifactKey identifies an artifact within a question: several languages of the same
+// attempt share an attemptID
+type artifactKey struct {
+	attemptID string
+	language  string
+}
+
+func newArtifactLoader(artifactsRepo *repository.ArtifactsRepository, driveID string, qID int64) *artifactLoader {
+	return &artifactLoader{
+		artifactsRepo: artifactsRepo,
+		driveID:       driveID,
+		qID:           qID,
+		loaded:        make(map[artifactKey]bool),
+	}
+}
+
+// loadPairs loads the heavy fields of every artifact referenced by the pairs
+func (l *artifactLoader) loadPairs(ctx context.Context, pairs []similarity.Pair) error {
+	artifacts := make([]*models.Artifact, 0, len(pairs)*2)
+	for _, pair := range pairs {
+		artifacts = append(artifacts, pair.ArtifactA, pair.ArtifactB)
+	}
+	return l.load(ctx, artifacts)
+}
+
+// load fetches heavy fields for artifacts of this drive that do not have them yet.
+// Artifacts are updated in place so pairs keep pointing at the same structs.
+// Artifacts of other drives (corpus mode) are already loaded in full.
+func (l *artifactLoader) load(ctx context.Context, artifacts []*models.Artifact) error {
+	pending := make(map[artifactKey][]*models.Artifact)
+	requested := make(map[string]bool)
+	attemptIDs := make([]string, 0)
+	for _, artifact := range artifacts {
+		key := artifactKey{artifact.AttemptID, artifact.Language}
+		if artifact.DriveID != l.driveID || l.loaded[key] {
+			continue
+		}
+		pending[key] = append(pending[key], artifact)
+		if !requested[artifact.AttemptID] {
+			requested[artifact.AttemptID] = true
+			attemptIDs = append(attemptIDs, artifact.AttemptID)
+		}
+	}
+	if len(pending) == 0 {
+		return nil
+	}
+
+	full, err := l.artifactsRepo.GetArtifactsByAttemptIDs(ctx, l.driveID, l.qID, attemptIDs)
+	if err != nil {
+		return fmt.Errorf("failed to load artifact contents: %w", err)
+	}
+
+	for _, source := range full {
+		key := artifactKey{source.AttemptID, source.Language}
+		artifacts, wanted := pending[key]
+		if !wanted {
+			continue
+		}
+		for _, artifact := range artifacts {
+			artifact.SourceCode = source.SourceCode
+			artifact.Tokens = source.Tokens
+			artifact.NormalizedTokens = source.NormalizedTokens
+			artifact.AST = source.AST
+			artifact.CFG = source.CFG
+			artifact.Fingerprints = source.Fingerprints
+		}
+		l.loaded[key] = true
+	}
+
+	return nil
+}
+
+// loadAST fetches only the AST of artifacts of this drive that have none yet, for the
+// cross-language candidate pass. The other heavy fields are loaded later, for the
+// artifacts of candidate pairs only.
+func (l *artifactLoader) loadAST(ctx context.Context, artifacts []*models.Artifact) error {
+	pending := make(map[artifactKey][]*models.Artifact)
+	requested := make(map[string]bool)
+	attemptIDs := make([]string, 0)
+	for _, artifact := range artifacts {
+		if artifact.DriveID != l.driveID || artifact.AST != nil {
+			continue
+		}
+		key := artifactKey{artifact.AttemptID, artifact.Language}
+		pending[key] = append(pending[key], artifact)
+		if !requested[artifact.AttemptID] {
+			requested[artifact.AttemptID] = true
+			attemptIDs = append(attemptIDs, artifact.AttemptID)
+		}
+	}
+	if len(pending) == 0 {
+		return nil
+	}
+
+	sources, err := l.artifactsRepo.GetArtifactFieldsByAttemptIDs(ctx, l.driveID, l.qID, attemptIDs, repository.ArtifactFieldsAST)
+	if err != nil {
+		return fmt.Errorf("failed to load artifact ASTs: %w", err)
+	}
+
+	for _, source := range sources {
+		for _, artifact := range pending[artifactKey{source.AttemptID, source.Language}] {
+			artifact.AST = source.AST
+		}
+	}
+
+	return nil
+}
+
+// release drops heavy fields once a question is done; results only need identities
+func (l *artifactLoader) release(artifacts []*models.Artifact) {
+	for _, artifact := range artifacts {
+		dropContents(artifact)
+	}
+	l.loaded = make(map[artifactKey]bool)
+}
+
+// releaseCorpus drops the contents of the other drives' artifacts of scored corpus pairs.
+// They are loaded per bucket and would otherwise stay alive with the pair results.
+func releaseCorpus(pairs []similarity.Pair) {
+	for _, pair := range pairs {
+		dropContents(pair.ArtifactB)
+	}
+}
+
+func dropContents(artifact *models.Artifact) {
+	artifact.SourceCode = ""
+	artifact.Tokens = nil
+	artifact.NormalizedTokens = nil
+	artifact.AST = nil
+	artifact.CFG = nil
+	artifact.Fingerprints = nil
+}
@@ -0,0 +1,53 @@
+package plagiarism
+
+import "github.com/RishiKendai/aegis/similarity"
+
+// Options holds tunables for a single plagiarism computation
+type Options struct {
+	BatchSize int
+
+	// CrossLanguage enables the cross-language pass within each qId
+	CrossLanguage bool
+
+	// CorpusMode compares the drive against other drives sharing a question
+	CorpusMode bool
+
+	// CorpusMatchesPerArtifact caps historical artifacts compared per artifact
+	CorpusMatchesPerArtifact int
+
+	// LSH switches large buckets to MinHash/LSH candidate generation
+	LSH similarity.LSHParams
+
+	// Normalization lists the canonicalisation passes run on every artifact before matching,
+	// unless the loaded scoring profile of the difficulty sets its own
+	Normalization []similarity.NormalizationPass
+
+	// Calibration flags pairs against their bucket's score distribution instead of a fixed threshold
+	Calibration similarity.CalibrationParams
+
+	// Aggregation overrides the scoring profile's candidate aggregation strategy when set
+	Aggregation similarity.AggregationStrategy
+
+	// TestRisk holds the cutoffs of the test risk levels; zero uses the defaults
+	TestRisk similarity.TestRiskThresholds
+
+	// Profiles replace the built-in scoring profile of a difficulty, keyed by difficulty
+	Profiles map[string]similarity.Profile
+}
+
+// profile returns the scoring profile of a difficulty
+func (o Options) profile(difficulty string) similarity.Profile {
+	if profile, exists := o.Profiles[difficulty]; exists {
+		return profile
+	}
+	return similarity.DifficultyProfile(difficulty)
+}
+
+// passes returns the normalization passes of a difficulty: the loaded profile's when it
+// sets them, the configured passes otherwise
+func (o Options) passes(difficulty string) []similarity.NormalizationPass {
+	if profile, exists := o.Profiles[difficulty]; exists && profile.Normalization != nil {
+		return profile.Normalization
+	}
+	return o.Normalization
+}
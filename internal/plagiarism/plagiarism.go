@@ -9,16 +9,24 @@ import (
 	"github.com/RishiKendai/aegis/internal/infra/redis"
 	"github.com/RishiKendai/aegis/internal/metrics"
 	"github.com/RishiKendai/aegis/internal/models"
+	"github.com/RishiKendai/aegis/internal/preprocess"
 	"github.com/RishiKendai/aegis/internal/repository"
+	"github.com/RishiKendai/aegis/similarity"
 	"github.com/rs/zerolog/log"
 )
 
 // ComputationJob represents a job for the worker pool
 type ComputationJob struct {
-	Pair       Pair
+	Pair       similarity.Pair
 	Difficulty string
+	Profile    similarity.Profile
 	QID        string
-	ResultChan chan<- PairSimilarity
+	Language   string
+	// CrossLanguage runs the language-neutral pipeline instead of the cascade
+	CrossLanguage bool
+	// Features shares per-artifact features between the jobs of a question
+	Features   *similarity.FeatureCache
+	ResultChan chan<- similarity.PairSimilarity
 	DoneChan   chan<- struct{}
 }
 
@@ -32,14 +40,11 @@ func (j *ComputationJob) Execute(ctx context.Context) error {
 		}
 	}()
 
-	result := CascadePipeline(j.Pair.ArtifactA, j.Pair.ArtifactB, j.Difficulty)
-
-	pairSimilarity := PairSimilarity{
-		ArtifactA:  j.Pair.ArtifactA,
-		ArtifactB:  j.Pair.ArtifactB,
-		FinalScore: result.FinalScore,
-		QID:        j.QID,
-		Difficulty: j.Difficulty,
+	var pairSimilarity similarity.PairSimilarity
+	if j.CrossLanguage {
+		pairSimilarity = j.crossLanguageSimilarity()
+	} else {
+		pairSimilarity = j.cascadeSimilarity()
 	}
 
 	select {
@@ -50,22 +55,63 @@ func (j *ComputationJob) Execute(ctx context.Context) error {
 	}
 }
 
+// cascadeSimilarity runs the same-language cascade for the job's pair
+func (j *ComputationJob) cascadeSimilarity() similarity.PairSimilarity {
+	result := similarity.CascadePipeline(j.Features.Get(j.Pair.ArtifactA), j.Features.Get(j.Pair.ArtifactB), j.Profile)
+
+	return similarity.PairSimilarity{
+		ArtifactA:        j.Pair.ArtifactA,
+		ArtifactB:        j.Pair.ArtifactB,
+		FinalScore:       result.FinalScore,
+		Scores:           result.Scores,
+		Weights:          &result.Weights,
+		Containment:      result.Containment,
+		Direction:        similarity.InferCopyDirection(j.Pair.ArtifactA, j.Pair.ArtifactB, result.Containment),
+		FunctionMatches:  result.FunctionMatches,
+		CrossDrive:       j.Pair.ArtifactA.DriveID != j.Pair.ArtifactB.DriveID,
+		EstimatedJaccard: j.Pair.EstimatedJaccard,
+		Normalization:    result.Normalization,
+		QID:              j.QID,
+		Language:         j.Language,
+		Difficulty:       j.Difficulty,
+	}
+}
+
+// crossLanguageSimilarity runs the language-neutral pipeline for the job's pair
+func (j *ComputationJob) crossLanguageSimilarity() similarity.PairSimilarity {
+	result := similarity.CrossLanguagePipeline(j.Features.Get(j.Pair.ArtifactA), j.Features.Get(j.Pair.ArtifactB), j.Difficulty)
+
+	return similarity.PairSimilarity{
+		ArtifactA:     j.Pair.ArtifactA,
+		ArtifactB:     j.Pair.ArtifactB,
+		FinalScore:    result.FinalScore,
+		Direction:     similarity.InferCopyDirection(j.Pair.ArtifactA, j.Pair.ArtifactB, models.LayerContainment{}),
+		CrossLanguage: true,
+		QID:           j.QID,
+		Language:      j.Pair.ArtifactA.Language,
+		Difficulty:    j.Difficulty,
+	}
+}
+
 func ComputePlagiarism(
 	ctx context.Context,
 	driveID string,
 	artifactsRepo *repository.ArtifactsRepository,
 	resultsRepo *repository.ResultsRepository,
+	indexRepo *repository.FingerprintIndexRepository,
+	referencesRepo *repository.ReferencesRepository,
+	reviewsRepo *repository.ReviewsRepository,
 	workerPool *WorkerPool,
 	redisClient *redis.Client,
-	batchSize int,
+	opts Options,
 ) error {
 	// Update status: Started
 	if err := UpdateStatus(ctx, redisClient, driveID, models.StepStarted); err != nil {
 		log.Warn().Err(err).Str("driveId", driveID).Msg("Failed to update started status")
 	}
 
-	// Load all artifacts for driveId
-	artifacts, err := artifactsRepo.GetArtifactsByDriveID(ctx, driveID)
+	// Load identities of all artifacts for driveId; contents are loaded per question
+	artifacts, err := artifactsRepo.GetArtifactsByDriveID(ctx, driveID, repository.ArtifactFieldsSummary)
 	if err != nil {
 		log.Error().Err(err).Str("driveId", driveID).Msg("Failed to load artifacts")
 		return fmt.Errorf("failed to load artifacts: %w", err)
@@ -76,6 +122,14 @@ func ComputePlagiarism(
 		return fmt.Errorf("no artifacts found for driveId: %s", driveID)
 	}
 
+	// Reviewer verdicts outlive pair records: dismissed pairs stay suppressed
+	// Computing without them would re-flag dismissed pairs and overwrite the verdicts
+	reviews, err := loadReviews(ctx, reviewsRepo, driveID)
+	if err != nil {
+		log.Error().Err(err).Str("driveId", driveID).Msg("Failed to load reviews")
+		return err
+	}
+
 	// Update status: Preprocessing
 	if err := UpdateStatus(ctx, redisClient, driveID, models.StepPreprocessing); err != nil {
 		log.Warn().Err(err).Str("driveId", driveID).Msg("Failed to update preprocessing status")
@@ -86,11 +140,12 @@ func ComputePlagiarism(
 	for _, artifact := range artifacts {
 		uniqueCandidates[artifact.Email] = true
 	}
-	if len(uniqueCandidates) == 1 {
-		return handleSingleCandidate(ctx, artifacts[0], resultsRepo, redisClient, driveID)
+	// A lone candidate can still match other drives in corpus mode, or reference solutions
+	if len(uniqueCandidates) == 1 && !opts.CorpusMode && !hasReferenceSolutions(ctx, referencesRepo, artifacts) {
+		return handleSingleCandidate(ctx, artifacts, reviews, resultsRepo, redisClient, driveID)
 	}
 
-	// Group by qId, then by language
+	// Group by qId, then by language (summaries only)
 	buckets := groupByQuestionAndLanguage(artifacts)
 
 	// Update status: Filtering
@@ -99,98 +154,299 @@ func ComputePlagiarism(
 	}
 
 	// Process each bucket
-	allPairSimilarities := make([]PairSimilarity, 0)
-	candidatePairsMap := make(map[string][]PairSimilarity) // attemptID -> []PairSimilarity
+	allPairSimilarities := make([]similarity.PairSimilarity, 0)
+	candidatePairsMap := make(map[string][]similarity.PairSimilarity) // attemptID -> []PairSimilarity
+	suppressedPairs := make([]similarity.PairSimilarity, 0)           // flagged but dismissed by a reviewer
+	questionStats := newQuestionStats()
 	deepAnalysisStatusUpdated := false
 
-	for qID, langBuckets := range buckets {
-		for language, bucketArtifacts := range langBuckets {
-			if len(bucketArtifacts) < 2 {
-				// Edge Case: No pairs possible in this bucket
-				continue
+	// collectSignificant keeps flagged pairs and tracks them per candidate. Calibrated pairs
+	// are flagged against their bucket baseline, the others at or above threshold.
+	// Pairs a reviewer dismissed are kept aside and count toward no candidate.
+	collectSignificant := func(pairSimilarities []similarity.PairSimilarity, threshold float64) {
+		for _, ps := range pairSimilarities {
+			flagged := ps.FinalScore >= threshold
+			if ps.Calibration != nil {
+				flagged = ps.Calibration.Outlier
 			}
+			if flagged && reviews.dismissed(ps) {
+				suppressedPairs = append(suppressedPairs, ps)
+			} else if flagged {
+				allPairSimilarities = append(allPairSimilarities, ps)
+				candidatePairsMap[ps.ArtifactA.AttemptID] = append(candidatePairsMap[ps.ArtifactA.AttemptID], ps)
+				candidatePairsMap[ps.ArtifactB.AttemptID] = append(candidatePairsMap[ps.ArtifactB.AttemptID], ps)
+			}
+		}
+	}
 
-			// Build GII (optimization: skip hashes with only 1 candidate)
-			gii := BuildGII(bucketArtifacts)
-
-			// Edge Case: No worthy pairs
-			if len(gii) == 0 {
-				continue
+	markDeepAnalysis := func() {
+		if !deepAnalysisStatusUpdated {
+			if err := UpdateStatus(ctx, redisClient, driveID, models.StepDeepAnalysis); err != nil {
+				log.Warn().Err(err).Str("driveId", driveID).Msg("Failed to update deep analysis status")
 			}
+			deepAnalysisStatusUpdated = true
+		}
+	}
+
+	for qID, summaryBuckets := range buckets {
+		questionID := firstQID(summaryBuckets)
+
+		// Load fingerprints of this question only; heavy fields are fetched for worthy pairs
+		questionArtifacts, err := artifactsRepo.GetArtifactsByDriveIDAndQID(ctx, driveID, questionID, repository.ArtifactFieldsFingerprints)
+		if err != nil {
+			log.Error().Err(err).Str("driveId", driveID).Str("qId", qID).Msg("Failed to load question artifacts")
+			return fmt.Errorf("failed to load artifacts for qId %s: %w", qID, err)
+		}
 
+		// Candidate generation matches fingerprints normalized at ingest with the same passes
+		passes := opts.passes(firstDifficulty(summaryBuckets))
+		if err := EnsureNormalizedFingerprints(ctx, driveID, questionID, questionArtifacts, passes, artifactsRepo, indexRepo); err != nil {
+			log.Warn().Err(err).Str("driveId", driveID).Str("qId", qID).Msg("Failed to rebuild normalized fingerprints")
+		}
+		langBuckets := groupByQuestionAndLanguage(questionArtifacts)[qID]
+		loader := newArtifactLoader(artifactsRepo, driveID, questionID)
+		features := similarity.NewFeatureCache(passes...)
+
+		// Reference solutions take part as virtual candidates of the question
+		referenceArtifacts, err := loadReferenceArtifacts(ctx, referencesRepo, questionID)
+		if err != nil {
+			log.Warn().Err(err).Str("qId", qID).Msg("Reference matching skipped")
+			referenceArtifacts = nil
+		}
+
+		for language, bucketArtifacts := range langBuckets {
 			difficulty := bucketArtifacts[0].Difficulty
 
-			// Find worthy pairs
-			worthyPairs := GetWorthyPairs(gii, bucketArtifacts, difficulty)
+			// Opt-in corpus pass against other drives answering the same question
+			var corpusPairs []similarity.Pair
+			if opts.CorpusMode {
+				corpusPairs, err = GetCorpusPairs(
+					ctx,
+					driveID,
+					bucketArtifacts,
+					difficulty,
+					opts.CorpusMatchesPerArtifact,
+					passes,
+					artifactsRepo,
+					indexRepo,
+				)
+				if err != nil {
+					log.Warn().Err(err).Str("qId", qID).Str("language", language).Msg("Corpus matching failed")
+					corpusPairs = nil
+				}
+			}
+
+			// Edge Case: No pairs possible in this bucket
+			var worthyPairs []similarity.Pair
+			if len(bucketArtifacts) >= 2 {
+				worthyPairs = findWorthyPairs(ctx, driveID, qID, language, bucketArtifacts, difficulty, passes, indexRepo, opts)
+			}
 
-			if len(worthyPairs) == 0 {
-				log.Info().
-					Str("qId", qID).
-					Str("language", language).
-					Msg("No worthy pairs found after threshold check")
+			referencePairs := GetReferencePairs(bucketArtifacts, referenceArtifacts[language], difficulty, passes...)
+
+			if len(corpusPairs) == 0 && len(worthyPairs) == 0 && len(referencePairs) == 0 {
+				questionStats.addBucket(qID, language, bucketArtifacts, 0, nil, nil)
 				continue
 			}
 
-			if !deepAnalysisStatusUpdated {
-				if err := UpdateStatus(ctx, redisClient, driveID, models.StepDeepAnalysis); err != nil {
-					log.Warn().Err(err).Str("driveId", driveID).Msg("Failed to update deep analysis status")
-				}
-				deepAnalysisStatusUpdated = true
+			markDeepAnalysis()
+
+			// Fetch source, tokens, AST and CFG for artifacts in worthy pairs only
+			pairsToLoad := append(append(corpusPairs, worthyPairs...), referencePairs...)
+			if err := loader.loadPairs(ctx, pairsToLoad); err != nil {
+				log.Error().Err(err).Str("qId", qID).Str("language", language).Msg("Failed to load artifact contents")
+				return err
 			}
 
+			// Peer pairs are scored first: their scores are the bucket's baseline
 			pairSimilarities := processPairsInBatches(
 				ctx,
 				worthyPairs,
 				difficulty,
+				opts.profile(difficulty),
 				qID,
+				language,
+				false,
+				features,
 				workerPool,
-				batchSize,
+				opts.BatchSize,
+			)
+			corpusSimilarities := processPairsInBatches(
+				ctx,
+				corpusPairs,
+				difficulty,
+				opts.profile(difficulty),
+				qID,
+				language,
+				false,
+				features,
+				workerPool,
+				opts.BatchSize,
+			)
+			referenceSimilarities := processPairsInBatches(
+				ctx,
+				referencePairs,
+				difficulty,
+				opts.profile(difficulty),
+				qID,
+				language,
+				false,
+				features,
+				workerPool,
+				opts.BatchSize,
 			)
 
-			// Filter pairs with FinalScore >= SignificantSimilarityThreshold (significant pairs)
-			significantPairs := make([]PairSimilarity, 0)
-			for _, ps := range pairSimilarities {
-				if ps.FinalScore >= SignificantSimilarityThreshold {
-					significantPairs = append(significantPairs, ps)
-					// Track pairs for each candidate
-					candidatePairsMap[ps.ArtifactA.AttemptID] = append(candidatePairsMap[ps.ArtifactA.AttemptID], ps)
-					candidatePairsMap[ps.ArtifactB.AttemptID] = append(candidatePairsMap[ps.ArtifactB.AttemptID], ps)
-				}
+			// Small buckets stay uncalibrated and use SignificantSimilarityThreshold
+			baseline := similarity.NewBucketBaseline(pairSimilarities)
+			similarity.CalibratePairs(pairSimilarities, baseline, opts.Calibration)
+			similarity.CalibratePairs(corpusSimilarities, baseline, opts.Calibration)
+			similarity.CalibratePairs(referenceSimilarities, baseline, opts.Calibration)
+			questionStats.addBucket(qID, language, bucketArtifacts, len(worthyPairs), pairSimilarities, baseline)
+
+			collectSignificant(pairSimilarities, similarity.SignificantSimilarityThreshold)
+			collectSignificant(corpusSimilarities, similarity.SignificantSimilarityThreshold)
+			collectSignificant(referenceSimilarities, similarity.SignificantSimilarityThreshold)
+			releaseCorpus(corpusPairs)
+		}
+
+		// Optional cross-language pass within the qId
+		if opts.CrossLanguage && len(langBuckets) > 1 {
+			difficulty := firstDifficulty(langBuckets)
+
+			// Candidate generation needs every AST of the question, scoring the full
+			// artifacts of candidate pairs only
+			if err := loader.loadAST(ctx, questionArtifacts); err != nil {
+				log.Error().Err(err).Str("qId", qID).Msg("Failed to load artifact ASTs")
+				return err
 			}
 
-			allPairSimilarities = append(allPairSimilarities, significantPairs...)
+			crossPairs := similarity.GetCrossLanguagePairs(langBuckets, difficulty, features, opts.LSH)
+			if len(crossPairs) > 0 {
+				markDeepAnalysis()
+
+				if err := loader.loadPairs(ctx, crossPairs); err != nil {
+					log.Error().Err(err).Str("qId", qID).Msg("Failed to load artifact contents")
+					return err
+				}
+
+				pairSimilarities := processPairsInBatches(
+					ctx,
+					crossPairs,
+					difficulty,
+					opts.profile(difficulty),
+					qID,
+					"",
+					true,
+					features,
+					workerPool,
+					opts.BatchSize,
+				)
+
+				collectSignificant(pairSimilarities, similarity.CrossLanguageThreshold(difficulty))
+			}
 		}
+
+		// Pairs keep pointers to these artifacts; drop contents before the next question
+		loader.release(questionArtifacts)
+	}
+
+	// Persist pair records (also clears records left over from a previous computation)
+	if err := savePairResults(ctx, driveID, allPairSimilarities, suppressedPairs, reviews, resultsRepo); err != nil {
+		log.Error().Err(err).Str("driveId", driveID).Msg("Failed to save pair results")
+		return err
 	}
 
 	// Edge Case: Short-circuit stops (no pairs with FinalScore >= SignificantSimilarityThreshold)
 	if len(allPairSimilarities) == 0 {
-		return handleNoSignificantPairs(ctx, artifacts, resultsRepo, redisClient, driveID)
+		return handleNoSignificantPairs(ctx, artifacts, questionStats, reviews, resultsRepo, redisClient, driveID)
 	}
 
 	// Aggregate results
-	return aggregateResults(ctx, artifacts, allPairSimilarities, candidatePairsMap, resultsRepo, redisClient, driveID)
+	return aggregateResults(ctx, artifacts, allPairSimilarities, candidatePairsMap, questionStats, reviews, resultsRepo, redisClient, driveID, opts)
+}
+
+// findWorthyPairs returns the pairs of a bucket worth deep analysis: MinHash/LSH for
+// very large buckets, the exact GII otherwise. Fingerprints are the normalized ones built
+// with the same passes the fingerprint layer uses.
+func findWorthyPairs(
+	ctx context.Context,
+	driveID string,
+	qID string,
+	language string,
+	bucketArtifacts []*models.Artifact,
+	difficulty string,
+	passes []similarity.NormalizationPass,
+	indexRepo *repository.FingerprintIndexRepository,
+	opts Options,
+) []similarity.Pair {
+	var worthyPairs []similarity.Pair
+	if opts.LSH.UseLSH(len(bucketArtifacts)) {
+		log.Info().
+			Str("qId", qID).
+			Str("language", language).
+			Int("bucketSize", len(bucketArtifacts)).
+			Msg("Using MinHash/LSH candidate generation")
+		worthyPairs = similarity.GetLSHPairs(bucketArtifacts, difficulty, opts.LSH, passes...)
+	} else {
+		// Load GII from the persistent index (only hashes with 2+ candidates),
+		// falling back to an in-memory build if the index is unavailable
+		gii, hashCounts, err := LoadBucketGII(ctx, driveID, bucketArtifacts, indexRepo)
+		if err != nil {
+			log.Warn().Err(err).Str("qId", qID).Str("language", language).Msg("Fingerprint index unavailable, building GII in memory")
+			gii = similarity.BuildGII(bucketArtifacts, passes...)
+			hashCounts = nil
+		}
+
+		// Edge Case: No worthy pairs
+		if len(gii) == 0 {
+			return nil
+		}
+
+		if hashCounts != nil {
+			worthyPairs = similarity.GetWorthyPairsWithCounts(gii, bucketArtifacts, hashCounts, difficulty)
+		} else {
+			worthyPairs = similarity.GetWorthyPairs(gii, bucketArtifacts, difficulty, passes...)
+		}
+	}
+
+	if len(worthyPairs) == 0 {
+		log.Info().
+			Str("qId", qID).
+			Str("language", language).
+			Msg("No worthy pairs found after threshold check")
+	}
+
+	return worthyPairs
 }
 
 // processPairsInBatches processes pairs in batches
 func processPairsInBatches(
 	ctx context.Context,
-	pairs []Pair,
+	pairs []similarity.Pair,
 	difficulty string,
+	profile similarity.Profile,
 	qID string,
+	language string,
+	crossLanguage bool,
+	features *similarity.FeatureCache,
 	workerPool *WorkerPool,
 	batchSize int,
-) []PairSimilarity {
-	resultChan := make(chan PairSimilarity, len(pairs))
+) []similarity.PairSimilarity {
+	resultChan := make(chan similarity.PairSimilarity, len(pairs))
 	doneChan := make(chan struct{}, len(pairs))
 
 	// Submit all jobs
 	for _, pair := range pairs {
 		job := &ComputationJob{
-			Pair:       pair,
-			Difficulty: difficulty,
-			QID:        qID,
-			ResultChan: resultChan,
-			DoneChan:   doneChan,
+			Pair:          pair,
+			Difficulty:    difficulty,
+			Profile:       profile,
+			QID:           qID,
+			Language:      language,
+			CrossLanguage: crossLanguage,
+			Features:      features,
+			ResultChan:    resultChan,
+			DoneChan:      doneChan,
 		}
 
 		if err := workerPool.Submit(job); err != nil {
@@ -200,19 +456,19 @@ func processPairsInBatches(
 
 	// Collect results as jobs complete
 	expectedResults := len(pairs)
-	resultsMap := make(map[string]PairSimilarity) // Use pair key to track results
+	resultsMap := make(map[string]similarity.PairSimilarity) // Use pair key to track results
 
 	for len(resultsMap) < expectedResults {
 		select {
 		case <-ctx.Done():
-			finalResults := make([]PairSimilarity, 0, len(resultsMap))
+			finalResults := make([]similarity.PairSimilarity, 0, len(resultsMap))
 			for _, result := range resultsMap {
 				finalResults = append(finalResults, result)
 			}
 			return finalResults
 		case result := <-resultChan:
 			// Use pair key to avoid duplicates
-			pairKey := getPairKey(result.ArtifactA.AttemptID, result.ArtifactB.AttemptID)
+			pairKey := similarity.PairKey(result.ArtifactA.AttemptID, result.ArtifactB.AttemptID)
 			resultsMap[pairKey] = result
 		case <-doneChan:
 			// Job completed, continue waiting for results
@@ -220,7 +476,7 @@ func processPairsInBatches(
 	}
 
 	// Convert map to slice
-	finalResults := make([]PairSimilarity, 0, len(resultsMap))
+	finalResults := make([]similarity.PairSimilarity, 0, len(resultsMap))
 	for _, result := range resultsMap {
 		finalResults = append(finalResults, result)
 	}
@@ -228,6 +484,93 @@ func processPairsInBatches(
 	return finalResults
 }
 
+// savePairResults converts significant pairs into pair records and stores them
+func savePairResults(
+	ctx context.Context,
+	driveID string,
+	pairSimilarities []similarity.PairSimilarity,
+	suppressedPairs []similarity.PairSimilarity,
+	reviews *reviewSet,
+	resultsRepo *repository.ResultsRepository,
+) error {
+	pairResults := make([]*models.PairResult, 0, len(pairSimilarities)+len(suppressedPairs))
+	for i, ps := range append(append([]similarity.PairSimilarity{}, pairSimilarities...), suppressedPairs...) {
+		pairResult := &models.PairResult{
+			DriveID:       driveID,
+			QID:           ps.QID,
+			Language:      ps.Language,
+			Difficulty:    ps.Difficulty,
+			CrossLanguage: ps.CrossLanguage,
+			CrossDrive:    ps.CrossDrive,
+			AttemptA:      ps.ArtifactA.AttemptID,
+			EmailA:        ps.ArtifactA.Email,
+			AttemptB:      ps.ArtifactB.AttemptID,
+			EmailB:        ps.ArtifactB.Email,
+			FinalScore:    ps.FinalScore,
+			Scores: models.LayerScores{
+				Fingerprint: ps.Scores.Fingerprint,
+				Token:       ps.Scores.Token,
+				AST:         ps.Scores.AST,
+				CFG:         ps.Scores.CFG,
+			},
+			Containment:      ps.Containment,
+			FunctionMatches:  ps.FunctionMatches,
+			EstimatedJaccard: ps.EstimatedJaccard,
+			Calibration:      ps.Calibration,
+			Review:           reviews.pairReview(ps),
+			Suppressed:       i >= len(pairSimilarities),
+		}
+		for _, pass := range ps.Normalization {
+			pairResult.Normalization = append(pairResult.Normalization, string(pass))
+		}
+		if ps.CrossLanguage {
+			pairResult.LanguageB = ps.ArtifactB.Language
+		}
+		if isReferencePair(ps) {
+			pairResult.KnownSource = ps.ArtifactB.Email
+		} else if ps.CrossDrive {
+			pairResult.DriveB = ps.ArtifactB.DriveID
+		}
+		if isReferencePair(ps) {
+			// A reference solution is the source by definition
+			pairResult.LikelySource = ps.ArtifactB.AttemptID
+			pairResult.LikelyCopier = ps.ArtifactA.AttemptID
+			pairResult.DirectionBasis = "known_source"
+		} else if ps.Direction != nil {
+			pairResult.LikelySource = ps.Direction.Source.AttemptID
+			pairResult.LikelyCopier = ps.Direction.Copier.AttemptID
+			pairResult.DirectionBasis = ps.Direction.Basis
+		}
+		pairResults = append(pairResults, pairResult)
+	}
+
+	if err := resultsRepo.ReplacePairResults(ctx, driveID, pairResults); err != nil {
+		return fmt.Errorf("failed to save pair results: %w", err)
+	}
+
+	return nil
+}
+
+// firstDifficulty returns the difficulty of the first artifact in a qId's buckets
+func firstDifficulty(langBuckets map[string][]*models.Artifact) string {
+	for _, bucketArtifacts := range langBuckets {
+		if len(bucketArtifacts) > 0 {
+			return bucketArtifacts[0].Difficulty
+		}
+	}
+	return ""
+}
+
+// firstQID returns the numeric qId of a question's language buckets
+func firstQID(langBuckets map[string][]*models.Artifact) int64 {
+	for _, bucketArtifacts := range langBuckets {
+		if len(bucketArtifacts) > 0 {
+			return bucketArtifacts[0].QID
+		}
+	}
+	return 0
+}
+
 // groupByQuestionAndLanguage groups artifacts by qId and language
 func groupByQuestionAndLanguage(artifacts []*models.Artifact) map[string]map[string][]*models.Artifact {
 	buckets := make(map[string]map[string][]*models.Artifact)
@@ -244,25 +587,101 @@ func groupByQuestionAndLanguage(artifacts []*models.Artifact) map[string]map[str
 	return buckets
 }
 
+// hasReferenceSolutions reports whether any question of the drive has reference solutions
+func hasReferenceSolutions(ctx context.Context, referencesRepo *repository.ReferencesRepository, artifacts []*models.Artifact) bool {
+	seen := make(map[int64]bool)
+	qIDs := make([]int64, 0)
+	for _, artifact := range artifacts {
+		if !seen[artifact.QID] {
+			seen[artifact.QID] = true
+			qIDs = append(qIDs, artifact.QID)
+		}
+	}
+
+	count, err := referencesRepo.CountReferencesByQIDs(ctx, qIDs)
+	if err != nil {
+		log.Warn().Err(err).Msg("Failed to count reference solutions")
+		return false
+	}
+	return count > 0
+}
+
+// groupByAttempt groups a drive's artifacts (one per question and language) by attempt
+func groupByAttempt(artifacts []*models.Artifact) map[string][]*models.Artifact {
+	byAttempt := make(map[string][]*models.Artifact)
+	for _, artifact := range artifacts {
+		byAttempt[artifact.AttemptID] = append(byAttempt[artifact.AttemptID], artifact)
+	}
+	return byAttempt
+}
+
+// candidateStrategy returns the aggregation strategy for a candidate: the override
+// when set, otherwise that of the profile their strongest pair was scored with
+func candidateStrategy(pairs []similarity.PairSimilarity, opts Options) similarity.AggregationStrategy {
+	if opts.Aggregation != "" || len(pairs) == 0 {
+		return opts.Aggregation
+	}
+
+	strongest := pairs[0]
+	for _, pair := range pairs[1:] {
+		if pair.FinalScore > strongest.FinalScore {
+			strongest = pair
+		}
+	}
+	return opts.profile(strongest.Difficulty).Aggregation
+}
+
+// applyAISignals copies the ingest-time AI signals of a candidate's artifacts onto its
+// result. The AI likelihood is reported next to the plagiarism risk, never folded into it.
+func applyAISignals(result *models.CandidateResult, artifacts []*models.Artifact) {
+	result.AISignals = make(map[string]models.AISignal)
+	result.AILevel = preprocess.AILevelLow
+
+	for _, artifact := range artifacts {
+		if artifact.AttemptID != result.AttemptID || artifact.AISignal == nil {
+			continue
+		}
+		// Keep the strongest signal when a question was answered in several languages
+		qID := strconv.FormatInt(artifact.QID, 10)
+		if existing, exists := result.AISignals[qID]; exists && existing.Score >= artifact.AISignal.Score {
+			continue
+		}
+		result.AISignals[qID] = *artifact.AISignal
+
+		if artifact.AISignal.Score > result.AILikelihood {
+			result.AILikelihood = artifact.AISignal.Score
+			result.AILevel = artifact.AISignal.Level
+		}
+	}
+}
+
 // handleSingleCandidate handles the case when there's only one candidate
 func handleSingleCandidate(
 	ctx context.Context,
-	artifact *models.Artifact,
+	artifacts []*models.Artifact,
+	reviews *reviewSet,
 	resultsRepo *repository.ResultsRepository,
 	redisClient *redis.Client,
 	driveID string,
 ) error {
+	artifact := artifacts[0]
 	candidateResult := &models.CandidateResult{
-		Email:            artifact.Email,
-		AttemptID:        artifact.AttemptID,
-		DriveID:          driveID,
-		Risk:             RiskClean,
-		FlaggedQuestions: []string{},
-		PlagiarismPeers:  make(map[string][]string),
-		CodeSimilarity:   0,
-		AlgoSimilarity:   0,
-		PlagiarismStatus: "completed",
+		Email:              artifact.Email,
+		AttemptID:          artifact.AttemptID,
+		DriveID:            driveID,
+		Risk:               similarity.RiskClean,
+		FlaggedQuestions:   []string{},
+		PlagiarismPeers:    make(map[string][]string),
+		LikelySources:      make(map[string][]string),
+		LikelyCopiers:      make(map[string][]string),
+		CorpusMatches:      []models.CorpusMatch{},
+		KnownSourceMatches: []models.KnownSourceMatch{},
+		CodeSimilarity:     0,
+		AlgoSimilarity:     0,
+		PlagiarismStatus:   "completed",
 	}
+	applyAISignals(candidateResult, artifacts)
+	reviews.applyCandidateReview(candidateResult)
 
 	if err := resultsRepo.UpdateCandidateResult(ctx, candidateResult); err != nil {
 		// Check if it's a "not found" error
@@ -285,11 +704,13 @@ func handleSingleCandidate(
 
 	testReport := &models.TestReport{
 		DriveID:           driveID,
-		Risk:              TestRiskSafe,
+		Risk:              similarity.TestRiskSafe,
 		Status:            "completed",
 		FlaggedQuestions:  []string{},
 		FlaggedCandidates: 0,
 		TotalAnalyzed:     1,
+		Clusters:          []models.Cluster{},
+		QuestionStats:     []models.QuestionStats{},
 	}
 
 	if err := resultsRepo.UpdateTestReportByDriveID(ctx, driveID, testReport); err != nil {
@@ -315,6 +736,8 @@ func handleSingleCandidate(
 func handleNoSignificantPairs(
 	ctx context.Context,
 	artifacts []*models.Artifact,
+	questionStats *questionStats,
+	reviews *reviewSet,
 	resultsRepo *repository.ResultsRepository,
 	redisClient *redis.Client,
 	driveID string,
@@ -326,19 +749,26 @@ func handleNoSignificantPairs(
 			uniqueCandidates[artifact.AttemptID] = artifact
 		}
 	}
+	artifactsByAttempt := groupByAttempt(artifacts)
 
 	for _, artifact := range uniqueCandidates {
 		candidateResult := &models.CandidateResult{
-			Email:            artifact.Email,
-			AttemptID:        artifact.AttemptID,
-			DriveID:          driveID,
-			Risk:             RiskClean,
-			FlaggedQuestions: []string{},
-			PlagiarismPeers:  make(map[string][]string),
-			CodeSimilarity:   0,
-			AlgoSimilarity:   0,
-			PlagiarismStatus: "completed",
+			Email:              artifact.Email,
+			AttemptID:          artifact.AttemptID,
+			DriveID:            driveID,
+			Risk:               similarity.RiskClean,
+			FlaggedQuestions:   []string{},
+			PlagiarismPeers:    make(map[string][]string),
+			LikelySources:      make(map[string][]string),
+			LikelyCopiers:      make(map[string][]string),
+			CorpusMatches:      []models.CorpusMatch{},
+			KnownSourceMatches: []models.KnownSourceMatch{},
+			CodeSimilarity:     0,
+			AlgoSimilarity:     0,
+			PlagiarismStatus:   "completed",
 		}
+		applyAISignals(candidateResult, artifactsByAttempt[artifact.AttemptID])
+		reviews.applyCandidateReview(candidateResult)
 
 		if err := resultsRepo.UpdateCandidateResult(ctx, candidateResult); err != nil {
 			// Check if it's a "not found" error
@@ -363,11 +793,13 @@ func handleNoSignificantPairs(
 	totalAnalyzed := len(uniqueCandidates)
 	testReport := &models.TestReport{
 		DriveID:           driveID,
-		Risk:              TestRiskSafe,
+		Risk:              similarity.TestRiskSafe,
 		Status:            "completed",
 		FlaggedQuestions:  []string{},
 		FlaggedCandidates: 0,
 		TotalAnalyzed:     totalAnalyzed,
+		Clusters:          []models.Cluster{},
+		QuestionStats:     questionStats.finalize(nil, nil),
 	}
 
 	if err := resultsRepo.UpdateTestReportByDriveID(ctx, driveID, testReport); err != nil {
@@ -393,11 +825,14 @@ func handleNoSignificantPairs(
 func aggregateResults(
 	ctx context.Context,
 	artifacts []*models.Artifact,
-	allPairSimilarities []PairSimilarity,
-	candidatePairsMap map[string][]PairSimilarity,
+	allPairSimilarities []similarity.PairSimilarity,
+	candidatePairsMap map[string][]similarity.PairSimilarity,
+	questionStats *questionStats,
+	reviews *reviewSet,
 	resultsRepo *repository.ResultsRepository,
 	redisClient *redis.Client,
 	driveID string,
+	opts Options,
 ) error {
 	// Get unique candidates
 	uniqueCandidates := make(map[string]*models.Artifact)
@@ -408,6 +843,7 @@ func aggregateResults(
 			uniqueCandidates[artifact.AttemptID] = artifact
 		}
 	}
+	artifactsByAttempt := groupByAttempt(artifacts)
 
 	// Calculate candidate scores
 	candidateResults := make([]*models.CandidateResult, 0)
@@ -422,50 +858,90 @@ func aggregateResults(
 		if len(pairs) == 0 {
 			// No significant pairs for this candidate
 			candidateResult := &models.CandidateResult{
-				Email:            artifact.Email,
-				AttemptID:        artifact.AttemptID,
-				DriveID:          driveID,
-				Risk:             RiskClean,
-				FlaggedQuestions: []string{},
-				PlagiarismPeers:  make(map[string][]string),
-				CodeSimilarity:   0,
-				AlgoSimilarity:   0,
-				PlagiarismStatus: "completed",
+				Email:              artifact.Email,
+				AttemptID:          artifact.AttemptID,
+				DriveID:            driveID,
+				Risk:               similarity.RiskClean,
+				FlaggedQuestions:   []string{},
+				PlagiarismPeers:    make(map[string][]string),
+				LikelySources:      make(map[string][]string),
+				LikelyCopiers:      make(map[string][]string),
+				CorpusMatches:      []models.CorpusMatch{},
+				KnownSourceMatches: []models.KnownSourceMatch{},
+				CodeSimilarity:     0,
+				AlgoSimilarity:     0,
+				PlagiarismStatus:   "completed",
 			}
+			applyAISignals(candidateResult, artifactsByAttempt[artifact.AttemptID])
+			reviews.applyCandidateReview(candidateResult)
 			candidateResults = append(candidateResults, candidateResult)
 			continue
 		}
 
 		// Calculate candidate score
-		score := CandidateScore(pairs)
-		risk := GetRiskLevel(score)
+		strategy := candidateStrategy(pairs, opts)
+		score := similarity.AggregateCandidate(pairs, attemptID, strategy).Score
+		risk := similarity.GetRiskLevel(score)
 
 		// Build flagged questions and plagiarism peers
 		flaggedQNSet := make(map[string]bool)
 		plagiarismPeers := make(map[string][]string)
+		likelySources := make(map[string][]string)
+		likelyCopiers := make(map[string][]string)
+		corpusMatches := make([]models.CorpusMatch, 0)
+		knownSourceMatches := make([]models.KnownSourceMatch, 0)
 		codeSimilarity := 0
 		algoSimilarity := 0
 
 		for _, pair := range pairs {
 			flaggedQNSet[pair.QID] = true
 
-			if _, exists := plagiarismPeers[pair.QID]; !exists {
-				plagiarismPeers[pair.QID] = make([]string, 0)
-			}
-
-			// Add peer
-			if pair.ArtifactA.AttemptID == attemptID {
-				plagiarismPeers[pair.QID] = append(plagiarismPeers[pair.QID], pair.ArtifactB.AttemptID)
+			if isReferencePair(pair) {
+				// "Matches known source X", never a peer
+				knownSourceMatches = append(knownSourceMatches, models.KnownSourceMatch{
+					QID:         pair.QID,
+					ReferenceID: pair.ArtifactB.AttemptID,
+					Name:        pair.ArtifactB.Email,
+					Score:       pair.FinalScore,
+				})
+			} else if pair.CrossDrive {
+				// Matches against other drives are reported separately from peers
+				corpusMatches = append(corpusMatches, models.CorpusMatch{
+					QID:       pair.QID,
+					DriveID:   pair.ArtifactB.DriveID,
+					AttemptID: pair.ArtifactB.AttemptID,
+					Email:     pair.ArtifactB.Email,
+					Score:     pair.FinalScore,
+				})
 			} else {
-				plagiarismPeers[pair.QID] = append(plagiarismPeers[pair.QID], pair.ArtifactA.AttemptID)
+				if _, exists := plagiarismPeers[pair.QID]; !exists {
+					plagiarismPeers[pair.QID] = make([]string, 0)
+				}
+
+				// Add peer
+				if pair.ArtifactA.AttemptID == attemptID {
+					plagiarismPeers[pair.QID] = append(plagiarismPeers[pair.QID], pair.ArtifactB.AttemptID)
+				} else {
+					plagiarismPeers[pair.QID] = append(plagiarismPeers[pair.QID], pair.ArtifactA.AttemptID)
+				}
+
+				// Add suggested direction of copying
+				if pair.Direction != nil {
+					if pair.Direction.Copier.AttemptID == attemptID {
+						likelySources[pair.QID] = append(likelySources[pair.QID], pair.Direction.Source.AttemptID)
+					} else {
+						likelyCopiers[pair.QID] = append(likelyCopiers[pair.QID], pair.Direction.Copier.AttemptID)
+					}
+				}
 			}
 
-			// Count similarities
-			if pair.FinalScore >= SignificantSimilarityThreshold {
+			// Count similarities with the flag collectSignificant applied, so calibrated
+			// outliers below the fixed threshold count; algorithmic ones are a subset
+			if pair.Significant() {
 				codeSimilarity++
-			}
-			if pair.FinalScore >= AlgorithmicSimilarityThreshold {
-				algoSimilarity++
+				if pair.FinalScore >= similarity.AlgorithmicSimilarityThreshold {
+					algoSimilarity++
+				}
 			}
 		}
 
@@ -475,24 +951,31 @@ func aggregateResults(
 			flaggedQNs[qID] = true
 		}
 
-		if risk != RiskClean {
+		if risk != similarity.RiskClean {
 			flaggedCandidates++
 		}
 
 		candidateResult := &models.CandidateResult{
-			Email:            artifact.Email,
-			AttemptID:        artifact.AttemptID,
-			DriveID:          driveID,
-			Risk:             risk,
-			FlaggedQuestions: flaggedQNList,
-			PlagiarismPeers:  plagiarismPeers,
-			CodeSimilarity:   codeSimilarity,
-			AlgoSimilarity:   algoSimilarity,
-			PlagiarismStatus: "completed",
+			Email:              artifact.Email,
+			AttemptID:          artifact.AttemptID,
+			DriveID:            driveID,
+			Risk:               risk,
+			FlaggedQuestions:   flaggedQNList,
+			PlagiarismPeers:    plagiarismPeers,
+			LikelySources:      likelySources,
+			LikelyCopiers:      likelyCopiers,
+			CorpusMatches:      corpusMatches,
+			KnownSourceMatches: knownSourceMatches,
+			CodeSimilarity:     codeSimilarity,
+			AlgoSimilarity:     algoSimilarity,
+			PlagiarismStatus:   "completed",
+			RiskExplanation:    similarity.ExplainCandidateScore(pairs, attemptID, strategy),
 		}
+		applyAISignals(candidateResult, artifactsByAttempt[artifact.AttemptID])
+		reviews.applyCandidateReview(candidateResult)
 
 		// Track high plagiarisms (count individual candidates with RiskHighlySuspicious or RiskNearCopy)
-		if risk == RiskHighlySuspicious || risk == RiskNearCopy {
+		if risk == similarity.RiskHighlySuspicious || risk == similarity.RiskNearCopy {
 			metrics.HighPlagiarismsDetected.WithLabelValues(driveID).Inc()
 		}
 
@@ -527,7 +1010,7 @@ func aggregateResults(
 	avgDifficulty := 0.0
 	difficultyCount := 0
 	for _, artifact := range artifacts {
-		avgDifficulty += DifficultyToFloat(artifact.Difficulty)
+		avgDifficulty += similarity.DifficultyToFloat(artifact.Difficulty)
 		difficultyCount++
 	}
 	if difficultyCount > 0 {
@@ -549,15 +1032,28 @@ func aggregateResults(
 		flaggedQNList = append(flaggedQNList, qID)
 	}
 
-	_, riskLevel := TestRisk(totalQuestions, avgDifficulty, avgSimilarity, len(flaggedQNList))
+	testRisk := similarity.TestRisk(totalQuestions, avgDifficulty, avgSimilarity, len(flaggedQNList), opts.TestRisk)
+
+	// Detect collusion rings across all significant pairs of the drive
+	drivePairs := make([]similarity.PairSimilarity, 0, len(allPairSimilarities))
+	for _, ps := range allPairSimilarities {
+		if !ps.CrossDrive {
+			drivePairs = append(drivePairs, ps)
+		}
+	}
+	clusters := similarity.DetectClusters(drivePairs)
 
 	testReport := &models.TestReport{
 		DriveID:           driveID,
-		Risk:              riskLevel,
+		Risk:              testRisk.Level,
+		RiskScore:         testRisk.Score,
+		RiskComponents:    testRisk,
 		Status:            "completed",
 		FlaggedQuestions:  flaggedQNList,
 		FlaggedCandidates: flaggedCandidates,
 		TotalAnalyzed:     len(candidateResults),
+		Clusters:          clusters,
+		QuestionStats:     questionStats.finalize(allPairSimilarities, clusters),
 	}
 
 	if err := resultsRepo.UpdateTestReportByDriveID(ctx, driveID, testReport); err != nil {
@@ -576,7 +1072,9 @@ func aggregateResults(
 		Str("driveId", driveID).
 		Int("candidates", len(candidateResults)).
 		Int("flagged", flaggedCandidates).
-		Str("testRisk", riskLevel).
+		Int("clusters", len(clusters)).
+		Str("testRisk", testRisk.Level).
+		Float64("testRiskScore", testRisk.Score).
 		Msg("Computation completed successfully")
 
 	return nil
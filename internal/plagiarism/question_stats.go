@@ -0,0 +1,124 @@
+package plagiarism
+
+import (
+	"sort"
+
+	"github.com/RishiKendai/aegis/internal/models"
+	"github.com/RishiKendai/aegis/similarity"
+)
+
+// questionStats collects per question and language analytics while buckets are
+// processed; flagged candidates and clusters are only known once the drive is done
+type questionStats struct {
+	entries []*models.QuestionStats
+	members []map[string]bool // attemptIds of each entry's bucket
+}
+
+func newQuestionStats() *questionStats {
+	return &questionStats{}
+}
+
+// addBucket records a bucket; pairSimilarities are its scored worthy peer pairs
+func (q *questionStats) addBucket(
+	qID, language string,
+	bucketArtifacts []*models.Artifact,
+	worthyPairs int,
+	pairSimilarities []similarity.PairSimilarity,
+	baseline *similarity.BucketBaseline,
+) {
+	members := make(map[string]bool)
+	for _, artifact := range bucketArtifacts {
+		members[artifact.AttemptID] = true
+	}
+
+	entry := &models.QuestionStats{
+		QID:         qID,
+		Language:    language,
+		Candidates:  len(members),
+		WorthyPairs: worthyPairs,
+	}
+	for _, ps := range pairSimilarities {
+		if ps.Significant() {
+			entry.SignificantPairs++
+		}
+	}
+	// Corpus and reference pairs are not part of the baseline; buckets with no scored
+	// peer pair leave the scores unset rather than reporting 0
+	if baseline != nil && baseline.Size > 0 {
+		maxScore, medianScore := baseline.Max, baseline.Median
+		entry.MaxScore = &maxScore
+		entry.MedianScore = &medianScore
+	}
+
+	q.entries = append(q.entries, entry)
+	q.members = append(q.members, members)
+}
+
+// finalize fills in flagged candidates and clusters from the drive's significant pairs
+// and returns the entries ordered by qId and language
+func (q *questionStats) finalize(significantPairs []similarity.PairSimilarity, clusters []models.Cluster) []models.QuestionStats {
+	// qId -> attemptIds in at least one significant pair of the question
+	flagged := make(map[string]map[string]bool)
+	flag := func(qID, attemptID string) {
+		if _, exists := flagged[qID]; !exists {
+			flagged[qID] = make(map[string]bool)
+		}
+		flagged[qID][attemptID] = true
+	}
+	for _, ps := range significantPairs {
+		flag(ps.QID, ps.ArtifactA.AttemptID)
+		if !ps.CrossDrive && !isReferencePair(ps) {
+			flag(ps.QID, ps.ArtifactB.AttemptID)
+		}
+	}
+
+	stats := make([]models.QuestionStats, 0, len(q.entries))
+	for i, entry := range q.entries {
+		members := q.members[i]
+		for attemptID := range flagged[entry.QID] {
+			if members[attemptID] {
+				entry.FlaggedCandidates++
+			}
+		}
+		if entry.Candidates > 0 {
+			entry.FlaggedPercent = 100.0 * float64(entry.FlaggedCandidates) / float64(entry.Candidates)
+		}
+
+		for _, cluster := range clusters {
+			if clusterTouches(cluster, entry.QID, members) {
+				entry.Clusters++
+			}
+		}
+
+		stats = append(stats, *entry)
+	}
+
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].QID != stats[j].QID {
+			return stats[i].QID < stats[j].QID
+		}
+		return stats[i].Language < stats[j].Language
+	})
+	return stats
+}
+
+// clusterTouches reports whether a cluster spans a question and has a member in the bucket
+func clusterTouches(cluster models.Cluster, qID string, members map[string]bool) bool {
+	spansQuestion := false
+	for _, question := range cluster.Questions {
+		if question == qID {
+			spansQuestion = true
+			break
+		}
+	}
+	if !spansQuestion {
+		return false
+	}
+
+	for _, member := range cluster.Members {
+		if members[member] {
+			return true
+		}
+	}
+	return false
+}
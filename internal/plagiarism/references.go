@@ -0,0 +1,74 @@
+package plagiarism
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/RishiKendai/aegis/internal/models"
+	"github.com/RishiKendai/aegis/internal/repository"
+	"github.com/RishiKendai/aegis/similarity"
+)
+
+// loadReferenceArtifacts loads a question's reference solutions as virtual artifacts,
+// grouped by language
+func loadReferenceArtifacts(
+	ctx context.Context,
+	referencesRepo *repository.ReferencesRepository,
+	qID int64,
+) (map[string][]*models.Artifact, error) {
+	references, err := referencesRepo.GetReferencesByQID(ctx, qID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load reference solutions: %w", err)
+	}
+
+	byLanguage := make(map[string][]*models.Artifact)
+	for _, reference := range references {
+		byLanguage[reference.Language] = append(byLanguage[reference.Language], reference.Artifact())
+	}
+	return byLanguage, nil
+}
+
+// GetReferencePairs pairs every artifact of a bucket with the reference solutions it
+// shares enough fingerprints with, using the same overlap filter as peer pairs.
+// The candidate is always ArtifactA and the reference ArtifactB.
+func GetReferencePairs(bucketArtifacts, references []*models.Artifact, difficulty string, passes ...similarity.NormalizationPass) []similarity.Pair {
+	if len(bucketArtifacts) == 0 || len(references) == 0 {
+		return nil
+	}
+
+	threshold := similarity.WorthyThreshold(difficulty)
+	referenceHashes := make([]map[string]bool, len(references))
+	for i, reference := range references {
+		referenceHashes[i] = similarity.FingerprintSet(reference, passes...)
+	}
+
+	pairs := make([]similarity.Pair, 0)
+	for _, artifact := range bucketArtifacts {
+		hashes := similarity.FingerprintSet(artifact, passes...)
+		if len(hashes) == 0 {
+			continue
+		}
+
+		for i, reference := range references {
+			if len(referenceHashes[i]) == 0 {
+				continue
+			}
+			shared := 0
+			for hash := range hashes {
+				if referenceHashes[i][hash] {
+					shared++
+				}
+			}
+			if float64(shared)/float64(min(len(hashes), len(referenceHashes[i]))) >= threshold {
+				pairs = append(pairs, similarity.Pair{ArtifactA: artifact, ArtifactB: reference})
+			}
+		}
+	}
+
+	return pairs
+}
+
+// isReferencePair reports whether a pair matches a candidate against a reference solution
+func isReferencePair(pair similarity.PairSimilarity) bool {
+	return pair.ArtifactB.DriveID == models.ReferenceDriveID
+}
@@ -0,0 +1,113 @@
+package plagiarism
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/RishiKendai/aegis/internal/models"
+	"github.com/RishiKendai/aegis/internal/repository"
+)
+
+// DefaultReportTopPairs is the number of pair diffs an investigation report includes
+const DefaultReportTopPairs = 10
+
+// DriveReport is everything the investigation report of a drive shows
+type DriveReport struct {
+	DriveID     string
+	GeneratedAt time.Time
+	Report      *models.TestReport
+	Candidates  []*models.CandidateResult // flagged before or after reviewer verdicts
+	Diffs       []*models.PairDiff        // the highest scoring unsuppressed pairs
+}
+
+// BuildDriveReport gathers the test report, flagged candidates and the diffs of the
+// topPairs highest scoring pairs of a drive. It returns nil when the drive has no report.
+func BuildDriveReport(
+	ctx context.Context,
+	artifactsRepo *repository.ArtifactsRepository,
+	resultsRepo *repository.ResultsRepository,
+	referencesRepo *repository.ReferencesRepository,
+	driveID string,
+	topPairs int,
+) (*DriveReport, error) {
+	testReport, err := resultsRepo.GetLatestReportByDriveID(ctx, driveID)
+	if err != nil || testReport == nil {
+		return nil, err
+	}
+
+	candidates, err := resultsRepo.GetFlaggedCandidateResults(ctx, driveID)
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return riskRank[candidates[i].EffectiveRisk] > riskRank[candidates[j].EffectiveRisk]
+	})
+
+	report := &DriveReport{
+		DriveID:     driveID,
+		GeneratedAt: time.Now(),
+		Report:      testReport,
+		Candidates:  candidates,
+		Diffs:       make([]*models.PairDiff, 0, topPairs),
+	}
+	if topPairs <= 0 {
+		return report, nil
+	}
+
+	pairs, err := resultsRepo.GetTopPairResults(ctx, driveID, int64(topPairs))
+	if err != nil {
+		return nil, err
+	}
+	for _, pair := range pairs {
+		qID, err := strconv.ParseInt(pair.QID, 10, 64)
+		if err != nil {
+			continue
+		}
+		diff, err := LoadPairDiff(ctx, artifactsRepo, resultsRepo, referencesRepo, driveID, qID, pair.AttemptA, pair.AttemptB)
+		if err != nil {
+			return nil, fmt.Errorf("failed to build diff of %s and %s: %w", pair.AttemptA, pair.AttemptB, err)
+		}
+		// Submissions may have been removed since the computation
+		if diff != nil {
+			report.Diffs = append(report.Diffs, diff)
+		}
+	}
+
+	return report, nil
+}
+
+// reportCandidate is a row of the flagged candidates table
+type reportCandidate struct {
+	*models.CandidateResult
+	Score float64
+	Peers int
+}
+
+// RenderDriveReportHTML writes a drive report as a single self-contained HTML page with a
+// print stylesheet, so it can be attached as is or printed to PDF
+func RenderDriveReportHTML(w io.Writer, report *DriveReport) error {
+	candidates := make([]reportCandidate, 0, len(report.Candidates))
+	for _, candidate := range report.Candidates {
+		row := reportCandidate{CandidateResult: candidate}
+		if candidate.RiskExplanation != nil {
+			row.Score = candidate.RiskExplanation.Score
+			row.Peers = candidate.RiskExplanation.DistinctPeers
+		}
+		candidates = append(candidates, row)
+	}
+
+	diffs := make([]*diffView, 0, len(report.Diffs))
+	for i, diff := range report.Diffs {
+		diffs = append(diffs, newDiffView(diff, fmt.Sprintf("p%d-", i+1)))
+	}
+
+	return htmlTemplates.ExecuteTemplate(w, "report", struct {
+		*DriveReport
+		Candidates []reportCandidate
+		Diffs      []*diffView
+	}{report, candidates, diffs})
+}
@@ -0,0 +1,162 @@
+package plagiarism
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/RishiKendai/aegis/internal/models"
+	"github.com/RishiKendai/aegis/internal/repository"
+	"github.com/RishiKendai/aegis/similarity"
+)
+
+// reviewSet holds the reviewer verdicts of a drive, loaded once per computation
+type reviewSet struct {
+	pairs      map[string]*models.Review // PairReviewKey -> verdict
+	candidates map[string]*models.Review // attemptId -> verdict
+}
+
+// loadReviews loads the verdicts of a drive
+func loadReviews(ctx context.Context, reviewsRepo *repository.ReviewsRepository, driveID string) (*reviewSet, error) {
+	reviews := &reviewSet{
+		pairs:      make(map[string]*models.Review),
+		candidates: make(map[string]*models.Review),
+	}
+
+	pairReviews, err := reviewsRepo.GetPairReviews(ctx, driveID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load pair reviews: %w", err)
+	}
+	for _, review := range pairReviews {
+		reviews.pairs[models.PairReviewKey(review.QID, review.AttemptA, review.AttemptB)] = &review.Review
+	}
+
+	candidateReviews, err := reviewsRepo.GetCandidateReviews(ctx, driveID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load candidate reviews: %w", err)
+	}
+	for _, review := range candidateReviews {
+		reviews.candidates[review.AttemptID] = &review.Review
+	}
+
+	return reviews, nil
+}
+
+// pairReview returns the verdict on a pair, or nil when it is unreviewed
+func (r *reviewSet) pairReview(ps similarity.PairSimilarity) *models.Review {
+	return r.pairs[models.PairReviewKey(ps.QID, ps.ArtifactA.AttemptID, ps.ArtifactB.AttemptID)]
+}
+
+// dismissed reports whether a reviewer dismissed a pair; it stays suppressed on recomputes
+func (r *reviewSet) dismissed(ps similarity.PairSimilarity) bool {
+	review := r.pairReview(ps)
+	return review != nil && review.Verdict == models.VerdictDismissed
+}
+
+// applyCandidateReview sets a candidate's verdict and the effective risk it leads to
+func (r *reviewSet) applyCandidateReview(result *models.CandidateResult) {
+	result.Review = r.candidates[result.AttemptID]
+	result.EffectiveRisk = EffectiveRisk(result.Risk, result.Review)
+}
+
+// riskRank orders the candidate risk levels
+var riskRank = map[string]int{
+	similarity.RiskClean:            0,
+	similarity.RiskSuspicious:       1,
+	similarity.RiskHighlySuspicious: 2,
+	similarity.RiskNearCopy:         3,
+}
+
+// EffectiveRisk applies a candidate's verdict to the risk of their (undismissed) pairs:
+// dismissed candidates are clean, confirmed ones at least highly suspicious
+func EffectiveRisk(risk string, review *models.Review) string {
+	if review == nil {
+		return risk
+	}
+
+	switch review.Verdict {
+	case models.VerdictDismissed:
+		return similarity.RiskClean
+	case models.VerdictConfirmed:
+		if riskRank[risk] < riskRank[similarity.RiskHighlySuspicious] {
+			return similarity.RiskHighlySuspicious
+		}
+	}
+	return risk
+}
+
+// RefreshEffectiveRisk rescores a candidate from their stored, unsuppressed pair records
+// and stores the effective risk and risk explanation with their verdict, so reviews take
+// effect without a recomputation. It reports whether the candidate has a result in the drive.
+func RefreshEffectiveRisk(
+	ctx context.Context,
+	resultsRepo *repository.ResultsRepository,
+	reviewsRepo *repository.ReviewsRepository,
+	driveID string,
+	attemptID string,
+	opts Options,
+) (bool, error) {
+	pairResults, err := resultsRepo.GetPairResultsByAttempt(ctx, driveID, attemptID)
+	if err != nil {
+		return false, err
+	}
+
+	pairs := make([]similarity.PairSimilarity, 0, len(pairResults))
+	for _, pairResult := range pairResults {
+		if !pairResult.Suppressed {
+			pairs = append(pairs, pairFromResult(pairResult))
+		}
+	}
+	strategy := candidateStrategy(pairs, opts)
+	score := similarity.AggregateCandidate(pairs, attemptID, strategy).Score
+	explanation := similarity.ExplainCandidateScore(pairs, attemptID, strategy)
+
+	candidateReview, err := reviewsRepo.GetCandidateReview(ctx, driveID, attemptID)
+	if err != nil {
+		return false, err
+	}
+	var review *models.Review
+	if candidateReview != nil {
+		review = &candidateReview.Review
+	}
+
+	effectiveRisk := EffectiveRisk(similarity.GetRiskLevel(score), review)
+	return resultsRepo.UpdateCandidateReview(ctx, driveID, attemptID, effectiveRisk, explanation, review)
+}
+
+// pairFromResult rebuilds the scoring view of a stored pair record
+func pairFromResult(pairResult *models.PairResult) similarity.PairSimilarity {
+	driveB := pairResult.DriveID
+	if pairResult.KnownSource != "" {
+		driveB = models.ReferenceDriveID
+	} else if pairResult.CrossDrive {
+		driveB = pairResult.DriveB
+	}
+
+	return similarity.PairSimilarity{
+		ArtifactA: &models.Artifact{
+			AttemptID: pairResult.AttemptA,
+			Email:     pairResult.EmailA,
+			DriveID:   pairResult.DriveID,
+			Language:  pairResult.Language,
+		},
+		ArtifactB: &models.Artifact{
+			AttemptID: pairResult.AttemptB,
+			Email:     pairResult.EmailB,
+			DriveID:   driveB,
+			Language:  pairResult.Language,
+		},
+		FinalScore: pairResult.FinalScore,
+		Scores: similarity.SimilarityScores{
+			Fingerprint: pairResult.Scores.Fingerprint,
+			Token:       pairResult.Scores.Token,
+			AST:         pairResult.Scores.AST,
+			CFG:         pairResult.Scores.CFG,
+		},
+		CrossLanguage: pairResult.CrossLanguage,
+		CrossDrive:    pairResult.CrossDrive,
+		Calibration:   pairResult.Calibration,
+		QID:           pairResult.QID,
+		Language:      pairResult.Language,
+		Difficulty:    pairResult.Difficulty,
+	}
+}
@@ -0,0 +1,48 @@
+package plagiarism
+
+import (
+	"embed"
+	"fmt"
+	"html/template"
+	"strings"
+	"time"
+)
+
+//go:embed templates
+var templateFS embed.FS
+
+// diffPalette is the number of colours groups cycle through
+const diffPalette = 12
+
+// htmlTemplates holds the diff page and the drive report. Both inline the stylesheet,
+// so rendered pages need no external assets.
+var htmlTemplates = template.Must(template.New("").Funcs(template.FuncMap{
+	"css": func() template.CSS {
+		style, _ := templateFS.ReadFile("templates/style.css")
+		return template.CSS(style)
+	},
+	"group": func(group int) string {
+		if group == 0 {
+			return ""
+		}
+		return fmt.Sprintf("g%d", (group-1)%diffPalette)
+	},
+	"percent": func(score float64) string {
+		return fmt.Sprintf("%.0f%%", score*100)
+	},
+	"deref": func(value *float64) float64 {
+		return *value
+	},
+	"join": func(values []string) string {
+		return strings.Join(values, ", ")
+	},
+	"inc": func(i int) int {
+		return i + 1
+	},
+	"timestamp": func(t time.Time) string {
+		if t.IsZero() {
+			return "-"
+		}
+		return t.UTC().Format("2006-01-02 15:04 UTC")
+	},
+}).ParseFS(templateFS, "templates/*.html"))
@@ -0,0 +1,393 @@
+package preprocess
+
+import (
+	"fmt"
+	"io/fs"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/RishiKendai/aegis/internal/models"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// AI likelihood levels of an artifact
+	AILevelLow    = "low"
+	AILevelMedium = "medium"
+	AILevelHigh   = "high"
+
+	aiLevelMediumThreshold = 0.40
+	aiLevelHighThreshold   = 0.70
+
+	// A near-verbatim match with a known generated solution dominates the style heuristics
+	aiReferenceDominantSimilarity = 0.80
+
+	// Identifier character entropy (bits) treated as fully descriptive naming
+	aiIdentifierEntropyCeiling = 3.5
+	// Comment lines per code line treated as heavily commented
+	aiCommentDensityCeiling = 0.25
+)
+
+// aiReference is one known generated solution, fingerprinted with the local preprocessor
+type aiReference struct {
+	id           string
+	qID          int64 // 0 when the reference applies to every question
+	language     string
+	fingerprints map[string]bool
+}
+
+// AIReferenceCorpus holds known LLM-generated reference solutions.
+// A nil corpus is valid and holds no references.
+type AIReferenceCorpus struct {
+	references []aiReference
+}
+
+// LoadAIReferenceCorpus loads generated reference solutions from a directory laid out as
+// <dir>/<file>.<ext> (any question) or <dir>/<qId>/<file>.<ext>. An empty dir yields an
+// empty corpus; files with unsupported extensions are skipped.
+func LoadAIReferenceCorpus(dir string) (*AIReferenceCorpus, error) {
+	corpus := &AIReferenceCorpus{references: make([]aiReference, 0)}
+	if dir == "" {
+		return corpus, nil
+	}
+
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			return nil
+		}
+
+		language, err := LanguageFromPath(path)
+		if err != nil {
+			log.Warn().Str("path", path).Msg("Skipping AI reference with unsupported extension")
+			return nil
+		}
+		code, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+
+		relative, _ := filepath.Rel(dir, path)
+		var qID int64
+		if parent := filepath.Dir(relative); parent != "." {
+			qID, err = strconv.ParseInt(filepath.Base(parent), 10, 64)
+			if err != nil {
+				return fmt.Errorf("AI reference %s: directory name must be a qId", path)
+			}
+		}
+
+		corpus.references = append(corpus.references, aiReference{
+			id:           filepath.ToSlash(relative),
+			qID:          qID,
+			language:     language,
+			fingerprints: fingerprintSet(PreprocessLocally(string(code), language).Data.Fingerprints),
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to load AI reference corpus: %w", err)
+	}
+
+	return corpus, nil
+}
+
+// Len returns the number of references in the corpus
+func (c *AIReferenceCorpus) Len() int {
+	if c == nil {
+		return 0
+	}
+	return len(c.references)
+}
+
+// bestMatch returns the highest similarity of fingerprints to a reference of the same
+// language and question, the reference id, and whether any reference applied. Shared
+// hashes are divided by the larger of the two sets, so a short snippet that happens to
+// appear in a long reference does not count as a copy of it.
+func (c *AIReferenceCorpus) bestMatch(fingerprints map[string]bool, qID int64, language string) (float64, string, bool) {
+	if c == nil || len(fingerprints) == 0 {
+		return 0.0, "", false
+	}
+
+	best, bestID, applicable := 0.0, "", false
+	for _, reference := range c.references {
+		if !strings.EqualFold(reference.language, language) || (reference.qID != 0 && reference.qID != qID) {
+			continue
+		}
+		applicable = true
+
+		shared := 0
+		for hash := range fingerprints {
+			if reference.fingerprints[hash] {
+				shared++
+			}
+		}
+		if similarity := float64(shared) / float64(max(len(fingerprints), len(reference.fingerprints))); similarity > best {
+			best, bestID = similarity, reference.id
+		}
+	}
+	return best, bestID, applicable
+}
+
+// ComputeAISignal estimates how likely source code is LLM-generated from offline features:
+// style uniformity, comment patterns, identifier entropy and similarity to known generated
+// solutions. It is a per-artifact signal, independent of pairwise plagiarism risk.
+func ComputeAISignal(sourceCode, language string, qID int64, references *AIReferenceCorpus) *models.AISignal {
+	lines := strings.Split(strings.ReplaceAll(sourceCode, "\r\n", "\n"), "\n")
+	local := PreprocessLocally(sourceCode, language)
+
+	signal := &models.AISignal{
+		StyleUniformity:   styleUniformity(lines),
+		CommentPattern:    commentPattern(lines, language),
+		IdentifierEntropy: identifierEntropy(local.Data.Tokens, local.Data.NormalizedTokens),
+	}
+
+	// Style heuristics alone
+	signal.Score = 0.4*signal.StyleUniformity + 0.3*signal.CommentPattern + 0.3*signal.IdentifierEntropy
+
+	// A reference match can only raise the score: code unlike every reference is not
+	// evidence of a human author
+	similarity, referenceID, applicable := references.bestMatch(fingerprintSet(local.Data.Fingerprints), qID, language)
+	if applicable {
+		signal.ReferenceSimilarity = similarity
+		signal.ReferenceID = referenceID
+		signal.Score = math.Max(signal.Score, 0.5*signal.Score+0.5*similarity)
+		if similarity >= aiReferenceDominantSimilarity {
+			signal.Score = math.Max(signal.Score, similarity)
+		}
+	}
+
+	signal.Level = AILevel(signal.Score)
+	return signal
+}
+
+// AILevel maps an AI likelihood score to a level
+func AILevel(score float64) string {
+	switch {
+	case score >= aiLevelHighThreshold:
+		return AILevelHigh
+	case score >= aiLevelMediumThreshold:
+		return AILevelMedium
+	default:
+		return AILevelLow
+	}
+}
+
+// operatorSpacing matches assignment and comparison operators with their surrounding spaces
+var operatorSpacing = regexp.MustCompile(`[\w)\]"'](\s*)(==|!=|<=|>=|\+=|-=|\*=|/=|=)(\s*)[\w(\["'-]`)
+
+// styleUniformity scores how mechanically consistent the formatting is:
+// indentation unit, spacing around operators and absence of trailing whitespace
+func styleUniformity(lines []string) float64 {
+	indented, tabIndented, consistentIndent := 0, 0, 0
+	spaceWidths := make([]int, 0)
+	spaced, tight, operators := 0, 0, 0
+	nonBlank, cleanEndings := 0, 0
+
+	for _, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		nonBlank++
+		if strings.TrimRightFunc(line, unicode.IsSpace) == line {
+			cleanEndings++
+		}
+
+		indent := line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+		if indent != "" {
+			indented++
+			switch {
+			case strings.Trim(indent, "\t") == "":
+				tabIndented++
+			case strings.Trim(indent, " ") == "":
+				spaceWidths = append(spaceWidths, len(indent))
+			}
+		}
+
+		for _, match := range operatorSpacing.FindAllStringSubmatch(line, -1) {
+			operators++
+			switch {
+			case match[1] != "" && match[3] != "":
+				spaced++
+			case match[1] == "" && match[3] == "":
+				tight++
+			}
+		}
+	}
+	if nonBlank == 0 {
+		return 0.0
+	}
+
+	// Indentation: one character kind, in multiples of one unit of at least two spaces
+	indentScore := 0.5
+	if indented > 0 {
+		unit := 0
+		for _, width := range spaceWidths {
+			unit = gcd(unit, width)
+		}
+		if tabIndented >= len(spaceWidths) {
+			consistentIndent = tabIndented
+		} else if unit >= 2 {
+			consistentIndent = len(spaceWidths)
+		}
+		indentScore = float64(consistentIndent) / float64(indented)
+	}
+
+	spacingScore := 0.5
+	if operators > 0 {
+		spacingScore = float64(max(spaced, tight)) / float64(operators)
+	}
+
+	endingScore := float64(cleanEndings) / float64(nonBlank)
+
+	return (indentScore + spacingScore + endingScore) / 3.0
+}
+
+func gcd(a, b int) int {
+	for b != 0 {
+		a, b = b, a%b
+	}
+	return a
+}
+
+// commentPattern scores comment density and how prose-like comments are
+// (capitalised, several words), which generated code tends to have
+func commentPattern(lines []string, language string) float64 {
+	comments := make([]string, 0)
+	codeLines := 0
+	inBlock := ""
+
+	for _, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" {
+			continue
+		}
+
+		if inBlock != "" {
+			comments = append(comments, strings.Trim(trimmed, "*/\"' "))
+			if strings.Contains(trimmed, inBlock) {
+				inBlock = ""
+			}
+			continue
+		}
+
+		switch {
+		case !hashComments(language) && strings.HasPrefix(trimmed, "/*"):
+			comments = append(comments, strings.Trim(trimmed, "*/ "))
+			if !strings.Contains(trimmed[2:], "*/") {
+				inBlock = "*/"
+			}
+			continue
+		case language == "python" && (strings.HasPrefix(trimmed, `"""`) || strings.HasPrefix(trimmed, "'''")):
+			delimiter := trimmed[:3]
+			comments = append(comments, strings.Trim(trimmed, `"' `))
+			if !strings.Contains(trimmed[3:], delimiter) {
+				inBlock = delimiter
+			}
+			continue
+		}
+
+		marker := "//"
+		if hashComments(language) {
+			marker = "#"
+		}
+		code, comment, found := cutComment(trimmed, marker)
+		if found {
+			comments = append(comments, strings.TrimSpace(comment))
+		}
+		if strings.TrimSpace(code) != "" {
+			codeLines++
+		}
+	}
+	if len(comments) == 0 || codeLines == 0 {
+		return 0.0
+	}
+
+	prose := 0
+	for _, comment := range comments {
+		words := strings.Fields(comment)
+		if len(words) >= 3 && unicode.IsUpper([]rune(words[0])[0]) {
+			prose++
+		}
+	}
+
+	density := math.Min(float64(len(comments))/float64(codeLines)/aiCommentDensityCeiling, 1.0)
+	return 0.5*density + 0.5*float64(prose)/float64(len(comments))
+}
+
+// cutComment splits a line at the first comment marker outside string literals
+func cutComment(line, marker string) (string, string, bool) {
+	quote := rune(0)
+	for i, r := range line {
+		switch {
+		case quote != 0:
+			if r == quote && (i == 0 || line[i-1] != '\\') {
+				quote = 0
+			}
+		case r == '"' || r == '\'' || r == '`':
+			quote = r
+		case strings.HasPrefix(line[i:], marker):
+			return line[:i], line[i+len(marker):], true
+		}
+	}
+	return line, "", false
+}
+
+// identifierEntropy scores how descriptive identifiers are: the mean character entropy of
+// distinct identifiers and the share that are longer than a few characters
+func identifierEntropy(tokens, normalized []string) float64 {
+	identifiers := make(map[string]bool)
+	for i, token := range tokens {
+		if i < len(normalized) && normalized[i] == "ID" {
+			identifiers[token] = true
+		}
+	}
+	if len(identifiers) == 0 {
+		return 0.0
+	}
+
+	totalEntropy, descriptive := 0.0, 0
+	for identifier := range identifiers {
+		totalEntropy += shannonEntropy(identifier)
+		if len(identifier) >= 4 {
+			descriptive++
+		}
+	}
+
+	entropyScore := math.Min(totalEntropy/float64(len(identifiers))/aiIdentifierEntropyCeiling, 1.0)
+	return 0.5*entropyScore + 0.5*float64(descriptive)/float64(len(identifiers))
+}
+
+// shannonEntropy returns the character entropy of a string in bits
+func shannonEntropy(value string) float64 {
+	counts := make(map[rune]int)
+	total := 0
+	for _, r := range strings.ToLower(value) {
+		counts[r]++
+		total++
+	}
+
+	entropy := 0.0
+	for _, count := range counts {
+		p := float64(count) / float64(total)
+		entropy -= p * math.Log2(p)
+	}
+	return entropy
+}
+
+func fingerprintSet(fingerprints *models.Fingerprints) map[string]bool {
+	set := make(map[string]bool)
+	if fingerprints == nil {
+		return set
+	}
+	for _, entry := range fingerprints.Hashes {
+		set[entry.Hash] = true
+	}
+	return set
+}
@@ -0,0 +1,276 @@
+package preprocess
+
+import (
+	"fmt"
+	"hash/fnv"
+	"path/filepath"
+	"strings"
+	"unicode"
+
+	"github.com/RishiKendai/aegis/internal/models"
+)
+
+const (
+	// Winnowing parameters of the local preprocessor
+	localKGramSize  = 5
+	localWindowSize = 4
+)
+
+// LocalResult is the output of the built-in lexical preprocessor
+type LocalResult struct {
+	Data models.PreprocessingData
+
+	// TokenLines holds the 1-based source line of every (normalized) token
+	TokenLines []int
+}
+
+// PreprocessLocally tokenizes and fingerprints source code without Astra.
+// Only the lexical layers are produced: AST and CFG are left nil, so callers
+// should score with a lexical-only profile.
+func PreprocessLocally(sourceCode, language string) *LocalResult {
+	tokens, normalized, lines := tokenize(sourceCode, language)
+
+	return &LocalResult{
+		Data: models.PreprocessingData{
+			Tokens:           tokens,
+			NormalizedTokens: normalized,
+			Fingerprints:     winnow(normalized, localKGramSize, localWindowSize),
+		},
+		TokenLines: lines,
+	}
+}
+
+// languageExtensions maps file extensions to Astra language names
+var languageExtensions = map[string]string{
+	".py":    "python",
+	".js":    "javascript",
+	".ts":    "typescript",
+	".java":  "java",
+	".c":     "c",
+	".h":     "c",
+	".cpp":   "cpp",
+	".cc":    "cpp",
+	".cxx":   "cpp",
+	".hpp":   "cpp",
+	".cs":    "csharp",
+	".go":    "go",
+	".rb":    "ruby",
+	".kt":    "kotlin",
+	".rs":    "rust",
+	".php":   "php",
+	".swift": "swift",
+	".scala": "scala",
+}
+
+// FileExtension returns the usual file extension of a language, ".txt" when unknown
+func FileExtension(language string) string {
+	switch language {
+	case "cpp":
+		return ".cpp"
+	case "c":
+		return ".c"
+	}
+	for ext, candidate := range languageExtensions {
+		if candidate == language {
+			return ext
+		}
+	}
+	return ".txt"
+}
+
+// LanguageFromPath returns the language of a source file from its extension
+func LanguageFromPath(path string) (string, error) {
+	ext := strings.ToLower(filepath.Ext(path))
+	language, ok := languageExtensions[ext]
+	if !ok {
+		return "", fmt.Errorf("unsupported file extension %q", ext)
+	}
+	return language, nil
+}
+
+// keywords are kept verbatim in the normalized stream; other identifiers become ID
+var keywords = toSet(
+	"if", "else", "elif", "for", "foreach", "while", "do", "switch", "case", "default",
+	"break", "continue", "return", "goto", "function", "def", "func", "fn", "class",
+	"struct", "interface", "enum", "public", "private", "protected", "static", "final",
+	"const", "var", "let", "val", "new", "delete", "try", "catch", "except", "finally",
+	"throw", "throws", "raise", "import", "from", "package", "include", "using",
+	"namespace", "in", "is", "not", "and", "or", "true", "false", "True", "False",
+	"null", "nil", "None", "this", "self", "lambda", "yield", "async", "await",
+	"extends", "implements", "void", "int", "long", "short", "float", "double",
+	"char", "bool", "boolean", "string", "byte", "unsigned", "signed", "auto",
+	"range", "go", "defer", "select", "chan", "map", "type", "with", "as", "pass",
+	"match", "when", "loop", "mut", "impl", "trait", "where",
+)
+
+// operators lists multi-character operators, longest first
+var operators = []string{
+	">>>=", "<<=", ">>=", "...", "===", "!==", "**=", "//=", ">>>",
+	"==", "!=", "<=", ">=", "&&", "||", "++", "--", "+=", "-=", "*=", "/=", "%=",
+	"&=", "|=", "^=", "<<", ">>", "->", "=>", "::", ":=", "**", "//", "?.", "??",
+}
+
+// hashComments reports whether a language uses # line comments
+func hashComments(language string) bool {
+	switch language {
+	case "python", "ruby":
+		return true
+	default:
+		return false
+	}
+}
+
+// tokenize splits source into raw tokens, normalized tokens and token lines.
+// Comments and whitespace are dropped; identifiers, numbers and strings are
+// normalized to ID, NUM and STR so renaming does not hide copying.
+func tokenize(source, language string) ([]string, []string, []int) {
+	runes := []rune(source)
+	tokens := make([]string, 0)
+	normalized := make([]string, 0)
+	lines := make([]int, 0)
+	line := 1
+
+	emit := func(raw, norm string, tokenLine int) {
+		tokens = append(tokens, raw)
+		normalized = append(normalized, norm)
+		lines = append(lines, tokenLine)
+	}
+	hasPrefix := func(i int, prefix string) bool {
+		return strings.HasPrefix(string(runes[i:min(i+len(prefix), len(runes))]), prefix)
+	}
+
+	for i := 0; i < len(runes); {
+		r := runes[i]
+
+		switch {
+		case r == '\n':
+			line++
+			i++
+
+		case unicode.IsSpace(r):
+			i++
+
+		// Line comments
+		case hashComments(language) && r == '#',
+			!hashComments(language) && hasPrefix(i, "//"):
+			for i < len(runes) && runes[i] != '\n' {
+				i++
+			}
+
+		// Block comments
+		case !hashComments(language) && hasPrefix(i, "/*"):
+			i += 2
+			for i < len(runes) && !hasPrefix(i, "*/") {
+				if runes[i] == '\n' {
+					line++
+				}
+				i++
+			}
+			i = min(i+2, len(runes))
+
+		case unicode.IsLetter(r) || r == '_' || r == '$':
+			start := i
+			for i < len(runes) && (unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i]) || runes[i] == '_' || runes[i] == '$') {
+				i++
+			}
+			word := string(runes[start:i])
+			if keywords[word] {
+				emit(word, strings.ToLower(word), line)
+			} else {
+				emit(word, "ID", line)
+			}
+
+		case unicode.IsDigit(r):
+			start := i
+			for i < len(runes) && (unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i]) || runes[i] == '.' || runes[i] == '_') {
+				i++
+			}
+			emit(string(runes[start:i]), "NUM", line)
+
+		case r == '"' || r == '\'' || r == '`':
+			start, startLine := i, line
+			delimiter := string(r)
+			if hasPrefix(i, `"""`) || hasPrefix(i, "'''") {
+				delimiter = strings.Repeat(string(r), 3)
+			}
+			i += len(delimiter)
+			for i < len(runes) && !hasPrefix(i, delimiter) {
+				if runes[i] == '\\' {
+					i++
+				} else if runes[i] == '\n' {
+					line++
+				}
+				i++
+			}
+			i = min(i+len(delimiter), len(runes))
+			emit(string(runes[start:i]), "STR", startLine)
+
+		default:
+			operator := string(r)
+			for _, candidate := range operators {
+				if hasPrefix(i, candidate) {
+					operator = candidate
+					break
+				}
+			}
+			i += len([]rune(operator))
+			emit(operator, operator, line)
+		}
+	}
+
+	return tokens, normalized, lines
+}
+
+// winnow selects fingerprints from k-gram hashes: the minimum hash of every window,
+// recorded once per position
+func winnow(tokens []string, k, window int) *models.Fingerprints {
+	fingerprints := &models.Fingerprints{
+		Method:     "winnowing",
+		KGramSize:  k,
+		WindowSize: window,
+		Hashes:     make([]models.HashEntry, 0),
+	}
+	if len(tokens) < k {
+		return fingerprints
+	}
+
+	hashes := make([]uint64, 0, len(tokens)-k+1)
+	for i := 0; i+k <= len(tokens); i++ {
+		h := fnv.New64a()
+		h.Write([]byte(strings.Join(tokens[i:i+k], " ")))
+		hashes = append(hashes, h.Sum64())
+	}
+
+	lastPosition := -1
+	for start := 0; start+window <= max(len(hashes), window); start++ {
+		end := min(start+window, len(hashes))
+
+		// Rightmost minimum, as in the original winnowing paper
+		position := start
+		for i := start; i < end; i++ {
+			if hashes[i] <= hashes[position] {
+				position = i
+			}
+		}
+		if position != lastPosition {
+			fingerprints.Hashes = append(fingerprints.Hashes, models.HashEntry{
+				Hash:     fmt.Sprintf("%016x", hashes[position]),
+				Position: position,
+			})
+			lastPosition = position
+		}
+		if end == len(hashes) {
+			break
+		}
+	}
+
+	return fingerprints
+}
+
+func toSet(values ...string) map[string]bool {
+	set := make(map[string]bool, len(values))
+	for _, value := range values {
+		set[value] = true
+	}
+	return set
+}
@@ -7,17 +7,37 @@ import (
 
 	"github.com/RishiKendai/aegis/internal/models"
 	"github.com/RishiKendai/aegis/internal/repository"
+	"github.com/RishiKendai/aegis/similarity"
+	"github.com/rs/zerolog/log"
 )
 
 type Service struct {
-	client        *AstraClient
-	artifactsRepo *repository.ArtifactsRepository
+	client         *AstraClient
+	artifactsRepo  *repository.ArtifactsRepository
+	indexRepo      *repository.FingerprintIndexRepository
+	aiReferences   *AIReferenceCorpus
+	referencesRepo *repository.ReferencesRepository
+
+	// passes returns the normalization passes of a difficulty; the fingerprint index
+	// holds fingerprints normalized with them
+	passes func(difficulty string) []similarity.NormalizationPass
 }
 
-func NewService(client *AstraClient, artifactsRepo *repository.ArtifactsRepository) *Service {
+func NewService(
+	client *AstraClient,
+	artifactsRepo *repository.ArtifactsRepository,
+	indexRepo *repository.FingerprintIndexRepository,
+	aiReferences *AIReferenceCorpus,
+	referencesRepo *repository.ReferencesRepository,
+	passes func(difficulty string) []similarity.NormalizationPass,
+) *Service {
 	return &Service{
-		client:        client,
-		artifactsRepo: artifactsRepo,
+		client:         client,
+		artifactsRepo:  artifactsRepo,
+		indexRepo:      indexRepo,
+		aiReferences:   aiReferences,
+		referencesRepo: referencesRepo,
+		passes:         passes,
 	}
 }
 
@@ -53,12 +73,51 @@ func (s *Service) ProcessSubmission(ctx context.Context, submission *models.Subm
 		AST:              preprocessResp.Preprocessing.AST,
 		CFG:              preprocessResp.Preprocessing.CFG,
 		Fingerprints:     preprocessResp.Preprocessing.Fingerprints,
+		SubmittedAt:      submission.SubmittedAt, // zero when the stream did not carry one
 		CreatedAt:        time.Now(),
 	}
+	artifact.AISignal = ComputeAISignal(artifact.SourceCode, artifact.Language, artifact.QID, s.aiReferences)
+	similarity.NormalizeFingerprints(artifact, s.passes(artifact.Difficulty))
 
 	if err := s.artifactsRepo.InsertArtifact(ctx, artifact); err != nil {
 		return fmt.Errorf("failed to store artifact: %w", err)
 	}
 
+	// Keep the persistent fingerprint index in step with stored artifacts.
+	// Not fatal: retrying would store the artifact twice.
+	if err := s.indexRepo.IndexArtifact(ctx, artifact); err != nil {
+		log.Error().
+			Err(err).
+			Str("driveId", artifact.DriveID).
+			Str("attemptID", artifact.AttemptID).
+			Msg("Failed to index artifact fingerprints")
+	}
+
+	return nil
+}
+
+// ProcessReference preprocesses a reference solution through Astra, like a submission,
+// and stores it for matching against every artifact of its question
+func (s *Service) ProcessReference(ctx context.Context, reference *models.ReferenceSolution) error {
+	preprocessResp, err := s.client.Preprocess(ctx, &PreprocessRequest{
+		EmailID:   reference.Name,
+		AttemptID: reference.ReferenceID,
+		Code:      reference.SourceCode,
+		Language:  reference.Language,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to preprocess: %w", err)
+	}
+
+	reference.Tokens = preprocessResp.Preprocessing.Tokens
+	reference.NormalizedTokens = preprocessResp.Preprocessing.NormalizedTokens
+	reference.AST = preprocessResp.Preprocessing.AST
+	reference.CFG = preprocessResp.Preprocessing.CFG
+	reference.Fingerprints = preprocessResp.Preprocessing.Fingerprints
+
+	if err := s.referencesRepo.InsertReference(ctx, reference); err != nil {
+		return fmt.Errorf("failed to store reference solution: %w", err)
+	}
+
 	return nil
 }
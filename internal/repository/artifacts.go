@@ -7,6 +7,8 @@ import (
 
 	"github.com/RishiKendai/aegis/internal/models"
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 const artifactsCollection = "plagiarism_artifacts"
@@ -31,38 +33,152 @@ func (r *ArtifactsRepository) InsertArtifact(ctx context.Context, artifact *mode
 	return nil
 }
 
-func (r *ArtifactsRepository) GetArtifactsByDriveID(ctx context.Context, driveID string) ([]*models.Artifact, error) {
+// ArtifactFields selects which artifact fields a query loads
+type ArtifactFields int
+
+const (
+	// ArtifactFieldsSummary loads identity and metadata only
+	ArtifactFieldsSummary ArtifactFields = iota
+	// ArtifactFieldsFingerprints adds stored and normalized fingerprints, enough for pair filtering
+	ArtifactFieldsFingerprints
+	// ArtifactFieldsNormalizedTokens adds normalized tokens as well, to rebuild normalized fingerprints
+	ArtifactFieldsNormalizedTokens
+	// ArtifactFieldsAll loads the full document including source, tokens, AST and CFG
+	ArtifactFieldsAll
+	// ArtifactFieldsAST adds the AST only, for the language-neutral candidate pass
+	ArtifactFieldsAST
+)
+
+// summaryFields are the cheap fields every projection keeps
+var summaryFields = []string{
+	"email", "attemptID", "testId", "driveId", "difficulty",
+	"qId", "language", "langCode", "submittedAt", "createdAt", "ai_signal",
+}
+
+func (f ArtifactFields) findOptions() *options.FindOptions {
+	if f == ArtifactFieldsAll {
+		return options.Find()
+	}
+
+	projection := bson.M{}
+	for _, field := range summaryFields {
+		projection[field] = 1
+	}
+	if f == ArtifactFieldsFingerprints || f == ArtifactFieldsNormalizedTokens {
+		projection["fingerprints"] = 1
+		projection["normalizedFingerprints"] = 1
+		projection["fingerprintPasses"] = 1
+	}
+	if f == ArtifactFieldsNormalizedTokens {
+		projection["normalizedTokens"] = 1
+	}
+	if f == ArtifactFieldsAST {
+		projection["ast"] = 1
+	}
+
+	return options.Find().SetProjection(projection)
+}
+
+func (r *ArtifactsRepository) GetArtifactsByDriveID(ctx context.Context, driveID string, fields ArtifactFields) ([]*models.Artifact, error) {
 	filter := bson.M{"driveId": driveID}
+	return r.findArtifacts(ctx, filter, fields)
+}
 
-	cursor, err := r.mongoRepo.FindMany(ctx, artifactsCollection, filter)
+func (r *ArtifactsRepository) GetArtifactsByDriveIDAndQID(ctx context.Context, driveID string, qID int64, fields ArtifactFields) ([]*models.Artifact, error) {
+	filter := bson.M{"driveId": driveID, "qId": qID}
+	return r.findArtifacts(ctx, filter, fields)
+}
+
+// GetArtifactsByAttemptIDs loads full artifacts of one question for the given attempts
+func (r *ArtifactsRepository) GetArtifactsByAttemptIDs(ctx context.Context, driveID string, qID int64, attemptIDs []string) ([]*models.Artifact, error) {
+	return r.GetArtifactFieldsByAttemptIDs(ctx, driveID, qID, attemptIDs, ArtifactFieldsAll)
+}
+
+// GetArtifactFieldsByAttemptIDs loads the selected fields of one question's artifacts for the given attempts
+func (r *ArtifactsRepository) GetArtifactFieldsByAttemptIDs(
+	ctx context.Context,
+	driveID string,
+	qID int64,
+	attemptIDs []string,
+	fields ArtifactFields,
+) ([]*models.Artifact, error) {
+	artifacts := make([]*models.Artifact, 0, len(attemptIDs))
+
+	for start := 0; start < len(attemptIDs); start += postingsQueryChunk {
+		end := min(start+postingsQueryChunk, len(attemptIDs))
+		filter := bson.M{
+			"driveId":   driveID,
+			"qId":       qID,
+			"attemptID": bson.M{"$in": attemptIDs[start:end]},
+		}
+
+		chunk, err := r.findArtifacts(ctx, filter, fields)
+		if err != nil {
+			return nil, err
+		}
+		artifacts = append(artifacts, chunk...)
+	}
+
+	return artifacts, nil
+}
+
+// findArtifacts decodes matching artifacts one by one so partial documents stay small
+func (r *ArtifactsRepository) findArtifacts(ctx context.Context, filter bson.M, fields ArtifactFields) ([]*models.Artifact, error) {
+	cursor, err := r.mongoRepo.FindMany(ctx, artifactsCollection, filter, fields.findOptions())
 	if err != nil {
 		return nil, fmt.Errorf("failed to find artifacts: %w", err)
 	}
 	defer cursor.Close(ctx)
 
-	var artifacts []*models.Artifact
-	if err := cursor.All(ctx, &artifacts); err != nil {
-		return nil, fmt.Errorf("failed to decode artifacts: %w", err)
+	artifacts := make([]*models.Artifact, 0)
+	for cursor.Next(ctx) {
+		var artifact models.Artifact
+		if err := cursor.Decode(&artifact); err != nil {
+			return nil, fmt.Errorf("failed to decode artifact: %w", err)
+		}
+		artifacts = append(artifacts, &artifact)
+	}
+	if err := cursor.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate artifacts: %w", err)
 	}
 
 	return artifacts, nil
 }
 
-func (r *ArtifactsRepository) GetArtifactsByDriveIDAndQID(ctx context.Context, driveID, qID string) ([]*models.Artifact, error) {
-	filter := bson.M{"driveId": driveID, "qId": qID}
+func (r *ArtifactsRepository) GetArtifact(ctx context.Context, driveID, attemptID string, qID int64) (*models.Artifact, error) {
+	filter := bson.M{"driveId": driveID, "attemptID": attemptID, "qId": qID}
 
-	cursor, err := r.mongoRepo.FindMany(ctx, artifactsCollection, filter)
+	var artifact models.Artifact
+	err := r.mongoRepo.FindOne(ctx, artifactsCollection, filter).Decode(&artifact)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
 	if err != nil {
-		return nil, fmt.Errorf("failed to find artifacts: %w", err)
+		return nil, fmt.Errorf("failed to find artifact: %w", err)
 	}
-	defer cursor.Close(ctx)
 
-	var artifacts []*models.Artifact
-	if err := cursor.All(ctx, &artifacts); err != nil {
-		return nil, fmt.Errorf("failed to decode artifacts: %w", err)
+	return &artifact, nil
+}
+
+// SetNormalizedFingerprints stores an artifact's normalized fingerprints and the passes
+// they were built with; nil values clear them
+func (r *ArtifactsRepository) SetNormalizedFingerprints(ctx context.Context, artifact *models.Artifact) error {
+	filter := bson.M{
+		"driveId":   artifact.DriveID,
+		"attemptID": artifact.AttemptID,
+		"qId":       artifact.QID,
+		"language":  artifact.Language,
 	}
+	update := bson.M{"$set": bson.M{
+		"normalizedFingerprints": artifact.NormalizedFingerprints,
+		"fingerprintPasses":      artifact.FingerprintPasses,
+	}}
 
-	return artifacts, nil
+	if _, err := r.mongoRepo.UpdateOne(ctx, artifactsCollection, filter, update); err != nil {
+		return fmt.Errorf("failed to update normalized fingerprints: %w", err)
+	}
+
+	return nil
 }
 
 func (r *ArtifactsRepository) CountArtifactsByDriveID(ctx context.Context, driveID string) (int64, error) {
@@ -0,0 +1,219 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/RishiKendai/aegis/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const (
+	fingerprintIndexCollection = "fingerprint_index"
+
+	// postingsQueryChunk bounds the size of $in lists sent to Mongo
+	postingsQueryChunk = 1000
+)
+
+// FingerprintIndexRepository maintains a persistent inverted index of fingerprint hashes.
+// One posting is stored per unique (artifact, hash).
+type FingerprintIndexRepository struct {
+	mongoRepo *MongoRepository
+}
+
+func NewFingerprintIndexRepository(mongoRepo *MongoRepository) *FingerprintIndexRepository {
+	return &FingerprintIndexRepository{
+		mongoRepo: mongoRepo,
+	}
+}
+
+// EnsureIndexes creates the Mongo indexes the posting lookups rely on
+func (r *FingerprintIndexRepository) EnsureIndexes(ctx context.Context) error {
+	_, err := r.mongoRepo.GetCollection(fingerprintIndexCollection).Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "qId", Value: 1}, {Key: "language", Value: 1}, {Key: "hash", Value: 1}}},
+		{Keys: bson.D{{Key: "driveId", Value: 1}, {Key: "attemptID", Value: 1}, {Key: "qId", Value: 1}, {Key: "language", Value: 1}}},
+		{Keys: bson.D{{Key: "driveId", Value: 1}, {Key: "qId", Value: 1}, {Key: "language", Value: 1}, {Key: "hash", Value: 1}}},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create fingerprint index indexes: %w", err)
+	}
+
+	return nil
+}
+
+// IndexArtifact replaces the postings of an artifact with its current fingerprints,
+// normalized ones when it has them. An attempt may answer a question in several
+// languages; each keeps its own postings.
+func (r *FingerprintIndexRepository) IndexArtifact(ctx context.Context, artifact *models.Artifact) error {
+	filter := bson.M{
+		"driveId":   artifact.DriveID,
+		"attemptID": artifact.AttemptID,
+		"qId":       artifact.QID,
+		"language":  artifact.Language,
+	}
+	if _, err := r.mongoRepo.DeleteMany(ctx, fingerprintIndexCollection, filter); err != nil {
+		return fmt.Errorf("failed to delete fingerprint postings: %w", err)
+	}
+
+	fingerprints := artifact.IndexedFingerprints()
+	if fingerprints == nil || len(fingerprints.Hashes) == 0 {
+		return nil
+	}
+
+	uniqueHashes := make(map[string]bool)
+	for _, hashEntry := range fingerprints.Hashes {
+		uniqueHashes[hashEntry.Hash] = true
+	}
+
+	now := time.Now()
+	documents := make([]interface{}, 0, len(uniqueHashes))
+	for hash := range uniqueHashes {
+		documents = append(documents, &models.FingerprintPosting{
+			Hash:      hash,
+			QID:       artifact.QID,
+			Language:  artifact.Language,
+			DriveID:   artifact.DriveID,
+			AttemptID: artifact.AttemptID,
+			Email:     artifact.Email,
+			HashCount: len(uniqueHashes),
+			CreatedAt: now,
+		})
+	}
+
+	if err := r.mongoRepo.InsertMany(ctx, fingerprintIndexCollection, documents); err != nil {
+		return fmt.Errorf("failed to insert fingerprint postings: %w", err)
+	}
+
+	return nil
+}
+
+// FindCorpusOverlaps returns the artifacts of drives other than excludeDriveID, answering
+// the question in the language, whose fingerprint overlap with an artifact's unique
+// hashes reaches minOverlap; at most limit, strongest first. Shared hashes are counted
+// in Mongo, so only the matches themselves are read back.
+func (r *FingerprintIndexRepository) FindCorpusOverlaps(
+	ctx context.Context,
+	qID int64,
+	language string,
+	hashes []string,
+	excludeDriveID string,
+	minOverlap float64,
+	limit int,
+) ([]*models.CorpusOverlap, error) {
+	if len(hashes) == 0 || limit <= 0 {
+		return []*models.CorpusOverlap{}, nil
+	}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{
+			"qId":      qID,
+			"language": language,
+			"hash":     bson.M{"$in": hashes},
+			"driveId":  bson.M{"$ne": excludeDriveID},
+		}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":       bson.M{"driveId": "$driveId", "attemptID": "$attemptID"},
+			"shared":    bson.M{"$sum": 1},
+			"hashCount": bson.M{"$first": "$hash_count"},
+		}}},
+		{{Key: "$project", Value: bson.M{
+			"_id":       0,
+			"driveId":   "$_id.driveId",
+			"attemptID": "$_id.attemptID",
+			"shared":    1,
+			"overlap": bson.M{"$divide": bson.A{
+				"$shared",
+				bson.M{"$max": bson.A{1, bson.M{"$min": bson.A{len(hashes), "$hashCount"}}}},
+			}},
+		}}},
+		{{Key: "$match", Value: bson.M{"overlap": bson.M{"$gte": minOverlap}}}},
+		{{Key: "$sort", Value: bson.D{{Key: "overlap", Value: -1}, {Key: "driveId", Value: 1}, {Key: "attemptID", Value: 1}}}},
+		{{Key: "$limit", Value: limit}},
+	}
+
+	cursor, err := r.mongoRepo.Aggregate(ctx, fingerprintIndexCollection, pipeline)
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate corpus overlaps: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	matches := make([]*models.CorpusOverlap, 0, limit)
+	if err := cursor.All(ctx, &matches); err != nil {
+		return nil, fmt.Errorf("failed to decode corpus overlaps: %w", err)
+	}
+
+	return matches, nil
+}
+
+// GetBucketPostings returns the inverted index of one driveId/qId/language bucket,
+// restricted to hashes shared by 2+ attempts, along with each attempt's unique hash count.
+// Only postings are read; artifact fingerprint lists are never loaded.
+func (r *FingerprintIndexRepository) GetBucketPostings(
+	ctx context.Context,
+	driveID string,
+	qID int64,
+	language string,
+) (map[string][]string, map[string]int, error) {
+	match := bson.M{"driveId": driveID, "qId": qID, "language": language}
+
+	// hash -> [attemptIDs], keeping only hashes with 2+ attempts
+	postingsPipeline := mongo.Pipeline{
+		{{Key: "$match", Value: match}},
+		{{Key: "$group", Value: bson.M{"_id": "$hash", "attempts": bson.M{"$addToSet": "$attemptID"}}}},
+		{{Key: "$match", Value: bson.M{"attempts.1": bson.M{"$exists": true}}}},
+	}
+
+	cursor, err := r.mongoRepo.Aggregate(ctx, fingerprintIndexCollection, postingsPipeline)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to aggregate fingerprint postings: %w", err)
+	}
+
+	postings := make(map[string][]string)
+	for cursor.Next(ctx) {
+		var row struct {
+			Hash     string   `bson:"_id"`
+			Attempts []string `bson:"attempts"`
+		}
+		if err := cursor.Decode(&row); err != nil {
+			cursor.Close(ctx)
+			return nil, nil, fmt.Errorf("failed to decode fingerprint postings: %w", err)
+		}
+		postings[row.Hash] = row.Attempts
+	}
+	if err := cursor.Err(); err != nil {
+		cursor.Close(ctx)
+		return nil, nil, fmt.Errorf("failed to iterate fingerprint postings: %w", err)
+	}
+	cursor.Close(ctx)
+
+	// attemptID -> unique hash count
+	countsPipeline := mongo.Pipeline{
+		{{Key: "$match", Value: match}},
+		{{Key: "$group", Value: bson.M{"_id": "$attemptID", "count": bson.M{"$first": "$hash_count"}}}},
+	}
+
+	cursor, err = r.mongoRepo.Aggregate(ctx, fingerprintIndexCollection, countsPipeline)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to aggregate fingerprint counts: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	hashCounts := make(map[string]int)
+	for cursor.Next(ctx) {
+		var row struct {
+			AttemptID string `bson:"_id"`
+			Count     int    `bson:"count"`
+		}
+		if err := cursor.Decode(&row); err != nil {
+			return nil, nil, fmt.Errorf("failed to decode fingerprint counts: %w", err)
+		}
+		hashCounts[row.AttemptID] = row.Count
+	}
+	if err := cursor.Err(); err != nil {
+		return nil, nil, fmt.Errorf("failed to iterate fingerprint counts: %w", err)
+	}
+
+	return postings, hashCounts, nil
+}
@@ -46,3 +46,18 @@ func (r *MongoRepository) UpdateOne(ctx context.Context, collection string, filt
 	result, err := r.db.Collection(collection).UpdateOne(ctx, filter, update, opts...)
 	return result, err
 }
+
+func (r *MongoRepository) InsertMany(ctx context.Context, collection string, documents []interface{}, opts ...*options.InsertManyOptions) error {
+	_, err := r.db.Collection(collection).InsertMany(ctx, documents, opts...)
+	return err
+}
+
+func (r *MongoRepository) DeleteMany(ctx context.Context, collection string, filter interface{}, opts ...*options.DeleteOptions) (*mongo.DeleteResult, error) {
+	result, err := r.db.Collection(collection).DeleteMany(ctx, filter, opts...)
+	return result, err
+}
+
+func (r *MongoRepository) Aggregate(ctx context.Context, collection string, pipeline interface{}, opts ...*options.AggregateOptions) (*mongo.Cursor, error) {
+	cursor, err := r.db.Collection(collection).Aggregate(ctx, pipeline, opts...)
+	return cursor, err
+}
@@ -0,0 +1,101 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/RishiKendai/aegis/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const referencesCollection = "reference_solutions"
+
+type ReferencesRepository struct {
+	mongoRepo *MongoRepository
+}
+
+func NewReferencesRepository(mongoRepo *MongoRepository) *ReferencesRepository {
+	return &ReferencesRepository{
+		mongoRepo: mongoRepo,
+	}
+}
+
+func (r *ReferencesRepository) InsertReference(ctx context.Context, reference *models.ReferenceSolution) error {
+	reference.CreatedAt = time.Now()
+	if err := r.mongoRepo.InsertOne(ctx, referencesCollection, reference); err != nil {
+		return fmt.Errorf("failed to insert reference solution: %w", err)
+	}
+
+	return nil
+}
+
+// GetReferencesByQID loads the full reference solutions of a question, ready for matching
+func (r *ReferencesRepository) GetReferencesByQID(ctx context.Context, qID int64) ([]*models.ReferenceSolution, error) {
+	return r.findReferences(ctx, bson.M{"qId": qID}, options.Find())
+}
+
+// GetReference loads a full reference solution, or nil when there is none
+func (r *ReferencesRepository) GetReference(ctx context.Context, referenceID string) (*models.ReferenceSolution, error) {
+	var reference models.ReferenceSolution
+	err := r.mongoRepo.FindOne(ctx, referencesCollection, bson.M{"referenceId": referenceID}).Decode(&reference)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find reference solution: %w", err)
+	}
+
+	return &reference, nil
+}
+
+// ListReferences returns reference metadata and source without preprocessing output.
+// A zero qID lists every question.
+func (r *ReferencesRepository) ListReferences(ctx context.Context, qID int64) ([]*models.ReferenceSolution, error) {
+	filter := bson.M{}
+	if qID != 0 {
+		filter["qId"] = qID
+	}
+	projection := bson.M{"tokens": 0, "normalizedTokens": 0, "ast": 0, "cfg": 0, "fingerprints": 0}
+
+	return r.findReferences(ctx, filter, options.Find().SetProjection(projection).SetSort(bson.M{"createdAt": 1}))
+}
+
+// CountReferencesByQIDs counts reference solutions for any of the questions
+func (r *ReferencesRepository) CountReferencesByQIDs(ctx context.Context, qIDs []int64) (int64, error) {
+	filter := bson.M{"qId": bson.M{"$in": qIDs}}
+
+	count, err := r.mongoRepo.CountDocuments(ctx, referencesCollection, filter)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count reference solutions: %w", err)
+	}
+
+	return count, nil
+}
+
+// DeleteReference removes a reference solution and reports whether it existed
+func (r *ReferencesRepository) DeleteReference(ctx context.Context, referenceID string) (bool, error) {
+	result, err := r.mongoRepo.DeleteMany(ctx, referencesCollection, bson.M{"referenceId": referenceID})
+	if err != nil {
+		return false, fmt.Errorf("failed to delete reference solution: %w", err)
+	}
+
+	return result.DeletedCount > 0, nil
+}
+
+func (r *ReferencesRepository) findReferences(ctx context.Context, filter bson.M, opts *options.FindOptions) ([]*models.ReferenceSolution, error) {
+	cursor, err := r.mongoRepo.FindMany(ctx, referencesCollection, filter, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find reference solutions: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	references := make([]*models.ReferenceSolution, 0)
+	if err := cursor.All(ctx, &references); err != nil {
+		return nil, fmt.Errorf("failed to decode reference solutions: %w", err)
+	}
+
+	return references, nil
+}
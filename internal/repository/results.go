@@ -6,6 +6,8 @@ import (
 	"time"
 
 	"github.com/RishiKendai/aegis/internal/models"
+	"github.com/RishiKendai/aegis/similarity"
+	"github.com/google/uuid"
 	"github.com/rs/zerolog/log"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
@@ -15,6 +17,7 @@ import (
 const (
 	resultsCollection = "results"
 	reportsCollection = "plagiarism_reports"
+	pairsCollection   = "plagiarism_pairs"
 )
 
 type ResultsRepository struct {
@@ -48,10 +51,14 @@ func (r *ResultsRepository) UpdateTestReportByDriveID(ctx context.Context, drive
 	update := bson.M{
 		"$set": bson.M{
 			"risk":               report.Risk,
+			"risk_score":         report.RiskScore,
+			"risk_components":    report.RiskComponents,
 			"status":             report.Status,
 			"flagged_qns":        report.FlaggedQuestions,
 			"flagged_candidates": report.FlaggedCandidates,
 			"total_analyzed":     report.TotalAnalyzed,
+			"clusters":           report.Clusters,
+			"question_stats":     report.QuestionStats,
 		},
 	}
 
@@ -89,12 +96,22 @@ func (r *ResultsRepository) UpdateCandidateResult(ctx context.Context, result *m
 
 	updateOps := bson.M{
 		"$set": bson.M{
-			"risk":              result.Risk,
-			"code_similarity":   result.CodeSimilarity,
-			"algo_similarity":   result.AlgoSimilarity,
-			"plagiarism_status": result.PlagiarismStatus,
-			"flagged_qns":       result.FlaggedQuestions,
-			"plagiarism_peers":  result.PlagiarismPeers,
+			"risk":                 result.Risk,
+			"code_similarity":      result.CodeSimilarity,
+			"algo_similarity":      result.AlgoSimilarity,
+			"plagiarism_status":    result.PlagiarismStatus,
+			"flagged_qns":          result.FlaggedQuestions,
+			"plagiarism_peers":     result.PlagiarismPeers,
+			"likely_sources":       result.LikelySources,
+			"likely_copiers":       result.LikelyCopiers,
+			"corpus_matches":       result.CorpusMatches,
+			"known_source_matches": result.KnownSourceMatches,
+			"ai_likelihood":        result.AILikelihood,
+			"ai_level":             result.AILevel,
+			"ai_signals":           result.AISignals,
+			"risk_explanation":     result.RiskExplanation,
+			"effective_risk":       result.EffectiveRisk,
+			"review":               result.Review,
 		},
 	}
 	updateResult, err := r.mongoRepo.UpdateOne(ctx, resultsCollection, filter, updateOps)
@@ -112,3 +129,243 @@ func (r *ResultsRepository) UpdateCandidateResult(ctx context.Context, result *m
 
 	return nil
 }
+
+// ReplacePairResults replaces all pair records of a drive with the given pairs. The new
+// records are inserted under a fresh run ID and the previous run's records are deleted
+// only after the insert succeeded, so a failed insert keeps the previous records (and
+// the verdicts on them) in place.
+func (r *ResultsRepository) ReplacePairResults(ctx context.Context, driveID string, pairs []*models.PairResult) error {
+	runID := uuid.New().String()
+
+	if len(pairs) > 0 {
+		now := time.Now()
+		documents := make([]interface{}, 0, len(pairs))
+		for _, pair := range pairs {
+			pair.RunID = runID
+			pair.CreatedAt = now
+			documents = append(documents, pair)
+		}
+
+		if err := r.mongoRepo.InsertMany(ctx, pairsCollection, documents); err != nil {
+			// Drop whatever part of this run was written before the failure
+			staged := bson.M{"driveId": driveID, "runId": runID}
+			if _, cleanupErr := r.mongoRepo.DeleteMany(ctx, pairsCollection, staged); cleanupErr != nil {
+				log.Warn().Err(cleanupErr).Str("driveId", driveID).Msg("Failed to remove partially inserted pair results")
+			}
+			return fmt.Errorf("failed to insert pair results: %w", err)
+		}
+	}
+
+	previous := bson.M{"driveId": driveID, "runId": bson.M{"$ne": runID}}
+	if _, err := r.mongoRepo.DeleteMany(ctx, pairsCollection, previous); err != nil {
+		return fmt.Errorf("failed to delete previous pair results: %w", err)
+	}
+
+	return nil
+}
+
+// GetPairResultsByAttempt returns the pair records of a drive that involve an attempt
+func (r *ResultsRepository) GetPairResultsByAttempt(ctx context.Context, driveID, attemptID string) ([]*models.PairResult, error) {
+	filter := bson.M{
+		"driveId": driveID,
+		"$or":     bson.A{bson.M{"attemptA": attemptID}, bson.M{"attemptB": attemptID}},
+	}
+
+	cursor, err := r.mongoRepo.FindMany(ctx, pairsCollection, filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find pair results: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	pairs := make([]*models.PairResult, 0)
+	if err := cursor.All(ctx, &pairs); err != nil {
+		return nil, fmt.Errorf("failed to decode pair results: %w", err)
+	}
+
+	return pairs, nil
+}
+
+// GetFlaggedCandidateResults returns the candidate results of a drive whose risk, before
+// or after reviewer verdicts, is not clean, highest score first
+func (r *ResultsRepository) GetFlaggedCandidateResults(ctx context.Context, driveID string) ([]*models.CandidateResult, error) {
+	notClean := bson.M{"$nin": bson.A{similarity.RiskClean, ""}}
+	filter := bson.M{
+		"driveId": driveID,
+		"$or":     bson.A{bson.M{"risk": notClean}, bson.M{"effective_risk": notClean}},
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "risk_explanation.score", Value: -1}})
+
+	cursor, err := r.mongoRepo.FindMany(ctx, resultsCollection, filter, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find flagged candidate results: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	results := make([]*models.CandidateResult, 0)
+	if err := cursor.All(ctx, &results); err != nil {
+		return nil, fmt.Errorf("failed to decode flagged candidate results: %w", err)
+	}
+
+	return results, nil
+}
+
+// GetTopPairResults returns the highest scoring unsuppressed pair records of a drive
+func (r *ResultsRepository) GetTopPairResults(ctx context.Context, driveID string, limit int64) ([]*models.PairResult, error) {
+	filter := bson.M{"driveId": driveID, "suppressed": bson.M{"$ne": true}}
+	opts := options.Find().SetSort(bson.D{{Key: "final_score", Value: -1}}).SetLimit(limit)
+
+	cursor, err := r.mongoRepo.FindMany(ctx, pairsCollection, filter, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find top pair results: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	pairs := make([]*models.PairResult, 0)
+	if err := cursor.All(ctx, &pairs); err != nil {
+		return nil, fmt.Errorf("failed to decode top pair results: %w", err)
+	}
+
+	return pairs, nil
+}
+
+// StreamCandidateResults calls fn with every candidate result of a drive, in attemptID
+// order, decoding one document at a time
+func (r *ResultsRepository) StreamCandidateResults(ctx context.Context, driveID string, fn func(*models.CandidateResult) error) error {
+	opts := options.Find().SetSort(bson.D{{Key: "attemptID", Value: 1}})
+	cursor, err := r.mongoRepo.FindMany(ctx, resultsCollection, bson.M{"driveId": driveID}, opts)
+	if err != nil {
+		return fmt.Errorf("failed to find candidate results: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var result models.CandidateResult
+		if err := cursor.Decode(&result); err != nil {
+			return fmt.Errorf("failed to decode candidate result: %w", err)
+		}
+		if err := fn(&result); err != nil {
+			return err
+		}
+	}
+
+	return cursor.Err()
+}
+
+// StreamPairResults calls fn with every pair record of a drive, highest score first,
+// decoding one document at a time
+func (r *ResultsRepository) StreamPairResults(ctx context.Context, driveID string, fn func(*models.PairResult) error) error {
+	opts := options.Find().SetSort(bson.D{{Key: "final_score", Value: -1}})
+	cursor, err := r.mongoRepo.FindMany(ctx, pairsCollection, bson.M{"driveId": driveID}, opts)
+	if err != nil {
+		return fmt.Errorf("failed to find pair results: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var pair models.PairResult
+		if err := cursor.Decode(&pair); err != nil {
+			return fmt.Errorf("failed to decode pair result: %w", err)
+		}
+		if err := fn(&pair); err != nil {
+			return err
+		}
+	}
+
+	return cursor.Err()
+}
+
+// GetPairResultsByQID returns the pair records of one question of a drive
+func (r *ResultsRepository) GetPairResultsByQID(ctx context.Context, driveID, qID string) ([]*models.PairResult, error) {
+	filter := bson.M{"driveId": driveID, "qId": qID}
+	opts := options.Find().SetSort(bson.D{{Key: "final_score", Value: -1}})
+
+	cursor, err := r.mongoRepo.FindMany(ctx, pairsCollection, filter, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find pair results: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	pairs := make([]*models.PairResult, 0)
+	if err := cursor.All(ctx, &pairs); err != nil {
+		return nil, fmt.Errorf("failed to decode pair results: %w", err)
+	}
+
+	return pairs, nil
+}
+
+// GetPairResult returns the pair record of two attempts for a question, in either
+// orientation, or nil when the pair was not scored
+func (r *ResultsRepository) GetPairResult(ctx context.Context, driveID, qID, attemptA, attemptB string) (*models.PairResult, error) {
+	filter := bson.M{
+		"driveId": driveID,
+		"qId":     qID,
+		"$or": bson.A{
+			bson.M{"attemptA": attemptA, "attemptB": attemptB},
+			bson.M{"attemptA": attemptB, "attemptB": attemptA},
+		},
+	}
+
+	var pair models.PairResult
+	err := r.mongoRepo.FindOne(ctx, pairsCollection, filter).Decode(&pair)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find pair result: %w", err)
+	}
+
+	return &pair, nil
+}
+
+// UpdatePairReview stores a verdict on the pair record of a question, in either orientation
+func (r *ResultsRepository) UpdatePairReview(ctx context.Context, review *models.PairReview) error {
+	filter := bson.M{
+		"driveId": review.DriveID,
+		"qId":     review.QID,
+		"$or": bson.A{
+			bson.M{"attemptA": review.AttemptA, "attemptB": review.AttemptB},
+			bson.M{"attemptA": review.AttemptB, "attemptB": review.AttemptA},
+		},
+	}
+	update := bson.M{
+		"$set": bson.M{
+			"review":     review.Review,
+			"suppressed": review.Verdict == models.VerdictDismissed,
+		},
+	}
+
+	if _, err := r.mongoRepo.GetCollection(pairsCollection).UpdateMany(ctx, filter, update); err != nil {
+		return fmt.Errorf("failed to update pair review: %w", err)
+	}
+
+	return nil
+}
+
+// UpdateCandidateReview stores a verdict, the resulting effective risk and the rescored
+// explanation on a candidate result. It reports whether the candidate has a result in the drive.
+func (r *ResultsRepository) UpdateCandidateReview(
+	ctx context.Context,
+	driveID, attemptID, effectiveRisk string,
+	explanation *models.RiskExplanation,
+	review *models.Review,
+) (bool, error) {
+	filter := bson.M{
+		"attemptID": attemptID,
+		"driveId":   driveID,
+	}
+	update := bson.M{
+		"$set": bson.M{
+			"effective_risk":    effectiveRisk,
+			"risk_explanation":  explanation,
+			"plagiarism_status": "completed",
+			"review":            review,
+		},
+	}
+
+	result, err := r.mongoRepo.UpdateOne(ctx, resultsCollection, filter, update)
+	if err != nil {
+		return false, fmt.Errorf("failed to update candidate review: %w", err)
+	}
+
+	return result.MatchedCount > 0, nil
+}
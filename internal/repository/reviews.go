@@ -0,0 +1,130 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/RishiKendai/aegis/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	pairReviewsCollection      = "pair_reviews"
+	candidateReviewsCollection = "candidate_reviews"
+)
+
+type ReviewsRepository struct {
+	mongoRepo *MongoRepository
+}
+
+func NewReviewsRepository(mongoRepo *MongoRepository) *ReviewsRepository {
+	return &ReviewsRepository{
+		mongoRepo: mongoRepo,
+	}
+}
+
+// UpsertPairReview records the verdict on a pair, replacing any earlier one
+func (r *ReviewsRepository) UpsertPairReview(ctx context.Context, review *models.PairReview) error {
+	filter := bson.M{
+		"driveId":  review.DriveID,
+		"qId":      review.QID,
+		"attemptA": review.AttemptA,
+		"attemptB": review.AttemptB,
+	}
+	update := bson.M{"$set": review}
+
+	if _, err := r.mongoRepo.UpdateOne(ctx, pairReviewsCollection, filter, update, options.Update().SetUpsert(true)); err != nil {
+		return fmt.Errorf("failed to upsert pair review: %w", err)
+	}
+
+	return nil
+}
+
+// UpsertCandidateReview records the verdict on a candidate, replacing any earlier one
+func (r *ReviewsRepository) UpsertCandidateReview(ctx context.Context, review *models.CandidateReview) error {
+	filter := bson.M{
+		"driveId":   review.DriveID,
+		"attemptID": review.AttemptID,
+	}
+	update := bson.M{"$set": review}
+
+	if _, err := r.mongoRepo.UpdateOne(ctx, candidateReviewsCollection, filter, update, options.Update().SetUpsert(true)); err != nil {
+		return fmt.Errorf("failed to upsert candidate review: %w", err)
+	}
+
+	return nil
+}
+
+// GetPairReviews returns every pair verdict of a drive
+func (r *ReviewsRepository) GetPairReviews(ctx context.Context, driveID string) ([]*models.PairReview, error) {
+	cursor, err := r.mongoRepo.FindMany(ctx, pairReviewsCollection, bson.M{"driveId": driveID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to find pair reviews: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	reviews := make([]*models.PairReview, 0)
+	if err := cursor.All(ctx, &reviews); err != nil {
+		return nil, fmt.Errorf("failed to decode pair reviews: %w", err)
+	}
+
+	return reviews, nil
+}
+
+// GetDecidedPairReviews returns the confirmed and dismissed pair verdicts of a drive,
+// or of every drive when driveID is empty
+func (r *ReviewsRepository) GetDecidedPairReviews(ctx context.Context, driveID string) ([]*models.PairReview, error) {
+	filter := bson.M{
+		"verdict": bson.M{"$in": bson.A{models.VerdictConfirmed, models.VerdictDismissed}},
+	}
+	if driveID != "" {
+		filter["driveId"] = driveID
+	}
+
+	cursor, err := r.mongoRepo.FindMany(ctx, pairReviewsCollection, filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find pair reviews: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	reviews := make([]*models.PairReview, 0)
+	if err := cursor.All(ctx, &reviews); err != nil {
+		return nil, fmt.Errorf("failed to decode pair reviews: %w", err)
+	}
+
+	return reviews, nil
+}
+
+// GetCandidateReviews returns every candidate verdict of a drive
+func (r *ReviewsRepository) GetCandidateReviews(ctx context.Context, driveID string) ([]*models.CandidateReview, error) {
+	cursor, err := r.mongoRepo.FindMany(ctx, candidateReviewsCollection, bson.M{"driveId": driveID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to find candidate reviews: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	reviews := make([]*models.CandidateReview, 0)
+	if err := cursor.All(ctx, &reviews); err != nil {
+		return nil, fmt.Errorf("failed to decode candidate reviews: %w", err)
+	}
+
+	return reviews, nil
+}
+
+// GetCandidateReview returns the verdict on a candidate, or nil when there is none
+func (r *ReviewsRepository) GetCandidateReview(ctx context.Context, driveID, attemptID string) (*models.CandidateReview, error) {
+	filter := bson.M{"driveId": driveID, "attemptID": attemptID}
+
+	var review models.CandidateReview
+	err := r.mongoRepo.FindOne(ctx, candidateReviewsCollection, filter).Decode(&review)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find candidate review: %w", err)
+	}
+
+	return &review, nil
+}
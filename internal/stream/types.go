@@ -2,8 +2,10 @@ package stream
 
 import (
 	"strconv"
+	"time"
 
 	"github.com/RishiKendai/aegis/internal/models"
+	"github.com/rs/zerolog/log"
 )
 
 type StreamMessage struct {
@@ -33,6 +35,21 @@ func ParseSubmission(msg *StreamMessage) (*models.Submission, error) {
 	}
 	submission.QID = qid
 
+	// submittedAt is optional: unix milliseconds or RFC3339. A malformed value is left
+	// out, so copy direction falls back to containment instead of a wrong time.
+	if submittedAt := msg.Fields["submittedAt"]; submittedAt != "" {
+		if millis, err := strconv.ParseInt(submittedAt, 10, 64); err == nil {
+			submission.SubmittedAt = time.UnixMilli(millis)
+		} else if ts, err := time.Parse(time.RFC3339, submittedAt); err == nil {
+			submission.SubmittedAt = ts
+		} else {
+			log.Warn().
+				Str("message_id", msg.ID).
+				Str("submittedAt", submittedAt).
+				Msg("Ignoring malformed submittedAt")
+		}
+	}
+
 	// Validate required fields
 	if submission.AttemptID == "" {
 		return nil, ErrMissingField("attemptId")
@@ -0,0 +1,178 @@
+package similarity
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/RishiKendai/aegis/similarity/model"
+)
+
+// AggregationStrategy names how a candidate's significant pairs are combined into
+// one candidate score
+type AggregationStrategy string
+
+const (
+	// AggregateTopKMean averages the CandidateTopK strongest pairs and adds the peer boost
+	AggregateTopKMean AggregationStrategy = "top_k_mean"
+	// AggregateMax takes the strongest pair and adds the peer boost
+	AggregateMax AggregationStrategy = "max"
+	// AggregatePerQuestion averages each question's strongest pair, weighted by the
+	// question's difficulty, and adds the peer boost
+	AggregatePerQuestion AggregationStrategy = "per_question_weighted"
+	// AggregateNoisyOR treats every pair as independent evidence: 1 - Π(1 - score).
+	// Multiple pairs already compound, so no peer boost is added.
+	AggregateNoisyOR AggregationStrategy = "noisy_or"
+
+	// DefaultAggregationStrategy is the strategy of the built-in profiles
+	DefaultAggregationStrategy = AggregateTopKMean
+)
+
+const (
+	// CandidateTopK is how many of a candidate's strongest significant pairs are averaged
+	CandidateTopK = 3
+	// CandidateBoostPerPeer is added per distinct peer beyond the first, up to CandidateMaxBoost
+	CandidateBoostPerPeer = 0.05
+	CandidateMaxBoost     = 0.15
+)
+
+// AggregationStrategies lists the supported strategies
+var AggregationStrategies = []AggregationStrategy{
+	AggregateTopKMean,
+	AggregateMax,
+	AggregatePerQuestion,
+	AggregateNoisyOR,
+}
+
+// ParseAggregationStrategy parses a strategy name; empty selects the default
+func ParseAggregationStrategy(value string) (AggregationStrategy, error) {
+	value = strings.ToLower(strings.TrimSpace(value))
+	if value == "" {
+		return DefaultAggregationStrategy, nil
+	}
+	for _, strategy := range AggregationStrategies {
+		if AggregationStrategy(value) == strategy {
+			return strategy, nil
+		}
+	}
+	return "", fmt.Errorf("unknown aggregation strategy %q", value)
+}
+
+// CandidateAggregate holds a candidate score and every intermediate value behind it
+type CandidateAggregate struct {
+	Strategy      AggregationStrategy
+	Pairs         []PairSimilarity // significant pairs the score was computed from, strongest first
+	Base          float64          // strategy's score before the peer boost
+	DistinctPeers int              // M
+	Boost         float64
+	Score         float64
+}
+
+// AggregateCandidate scores a candidate from their pairs with a strategy. attemptID
+// picks the candidate's side of each pair, so peers are counted the same whichever
+// way round a pair was built. An empty strategy selects the default.
+func AggregateCandidate(pairs []PairSimilarity, attemptID string, strategy AggregationStrategy) CandidateAggregate {
+	if strategy == "" {
+		strategy = DefaultAggregationStrategy
+	}
+	aggregate := CandidateAggregate{Strategy: strategy}
+
+	significantPairs := make([]PairSimilarity, 0)
+	for _, pair := range pairs {
+		if pair.Significant() {
+			significantPairs = append(significantPairs, pair)
+		}
+	}
+
+	// If no significant pairs, score 0
+	if len(significantPairs) == 0 {
+		return aggregate
+	}
+
+	// Sort by score descending
+	sort.SliceStable(significantPairs, func(i, j int) bool {
+		return significantPairs[i].FinalScore > significantPairs[j].FinalScore
+	})
+
+	// M = number of distinct peers in significant pairs
+	distinctPeers := make(map[string]bool)
+	for _, pair := range significantPairs {
+		distinctPeers[peerOf(pair, attemptID).AttemptID] = true
+	}
+	aggregate.DistinctPeers = len(distinctPeers)
+	boost := math.Min(CandidateMaxBoost, CandidateBoostPerPeer*float64(aggregate.DistinctPeers-1))
+
+	switch strategy {
+	case AggregateMax:
+		aggregate.Pairs = significantPairs[:1]
+		aggregate.Base = significantPairs[0].FinalScore
+		aggregate.Boost = boost
+	case AggregatePerQuestion:
+		aggregate.Pairs = strongestPerQuestion(significantPairs)
+		weightedSum, weightSum := 0.0, 0.0
+		for _, pair := range aggregate.Pairs {
+			weight := questionWeight(pair.Difficulty)
+			weightedSum += weight * pair.FinalScore
+			weightSum += weight
+		}
+		aggregate.Base = weightedSum / weightSum
+		aggregate.Boost = boost
+	case AggregateNoisyOR:
+		aggregate.Pairs = significantPairs
+		missed := 1.0
+		for _, pair := range significantPairs {
+			missed *= 1.0 - math.Max(0.0, math.Min(1.0, pair.FinalScore))
+		}
+		aggregate.Base = 1.0 - missed
+	default:
+		K := min(CandidateTopK, len(significantPairs))
+		aggregate.Pairs = significantPairs[:K]
+		sum := 0.0
+		for _, pair := range aggregate.Pairs {
+			sum += pair.FinalScore
+		}
+		aggregate.Base = sum / float64(K)
+		aggregate.Boost = boost
+	}
+
+	// Clamp to [0, 1]
+	aggregate.Score = math.Max(0.0, math.Min(1.0, aggregate.Base+aggregate.Boost))
+	return aggregate
+}
+
+// strongestPerQuestion keeps the strongest pair of each question; pairs must be sorted
+// by score descending
+func strongestPerQuestion(sorted []PairSimilarity) []PairSimilarity {
+	seen := make(map[string]bool)
+	strongest := make([]PairSimilarity, 0)
+	for _, pair := range sorted {
+		if seen[pair.QID] {
+			continue
+		}
+		seen[pair.QID] = true
+		strongest = append(strongest, pair)
+	}
+	return strongest
+}
+
+// questionWeight is how much a question's strongest pair counts in the per-question
+// strategy: similar solutions to easy questions are weaker evidence
+func questionWeight(difficulty string) float64 {
+	switch difficulty {
+	case "easy":
+		return 0.5
+	case "hard":
+		return 1.5
+	default:
+		return 1.0
+	}
+}
+
+// peerOf returns the other side of a pair from the candidate's point of view
+func peerOf(pair PairSimilarity, attemptID string) *model.Artifact {
+	if pair.ArtifactB.AttemptID == attemptID {
+		return pair.ArtifactA
+	}
+	return pair.ArtifactB
+}
@@ -1,4 +1,4 @@
-package plagiarism
+package similarity
 
 import (
 	"crypto/sha256"
@@ -7,19 +7,24 @@ import (
 	"sort"
 	"strings"
 
-	"github.com/RishiKendai/aegis/internal/models"
+	"github.com/RishiKendai/aegis/similarity/model"
 )
 
 // ASTSimilarity calculates similarity using AST Merkle hashing
 // Uses post-order traversal to build Merkle tree hashes for all subtrees
-func ASTSimilarity(artifactA, artifactB *models.Artifact) float64 {
-	if artifactA.AST == nil || artifactB.AST == nil {
-		return 0.0
-	}
+func ASTSimilarity(artifactA, artifactB *model.Artifact) float64 {
+	score, _ := astScores(NewArtifactFeatures(artifactA), NewArtifactFeatures(artifactB))
+	return score
+}
 
-	// Build multiset of subtree hashes for both ASTs
-	subtreesA := buildSubtreeHashes(artifactA.AST)
-	subtreesB := buildSubtreeHashes(artifactB.AST)
+// astScores returns the symmetric subtree score and both containment directions
+func astScores(featuresA, featuresB *ArtifactFeatures) (float64, model.Containment) {
+	// Multisets of subtree hashes are built once per artifact
+	subtreesA := featuresA.SubtreeHashes()
+	subtreesB := featuresB.SubtreeHashes()
+	if subtreesA == nil || subtreesB == nil {
+		return 0.0, model.Containment{}
+	}
 
 	// Count common subtrees
 	commonCount := 0
@@ -33,7 +38,12 @@ func ASTSimilarity(artifactA, artifactB *models.Artifact) float64 {
 	totalB := len(subtreesB)
 
 	if totalA == 0 || totalB == 0 {
-		return 0.0
+		return 0.0, model.Containment{}
+	}
+
+	containment := model.Containment{
+		AInB: float64(commonCount) / float64(totalA),
+		BInA: float64(commonCount) / float64(totalB),
 	}
 
 	// ASTScore = common_subtrees / min(total_subtrees_A, total_subtrees_B)
@@ -43,22 +53,22 @@ func ASTSimilarity(artifactA, artifactB *models.Artifact) float64 {
 	}
 
 	if minTotal == 0 {
-		return 0.0
+		return 0.0, containment
 	}
 
-	return float64(commonCount) / float64(minTotal)
+	return float64(commonCount) / float64(minTotal), containment
 }
 
 // buildSubtreeHashes builds a multiset of subtree hashes using post-order traversal
 // Returns a set of all subtree hashes (Merkle tree hashes for each node and its descendants)
-func buildSubtreeHashes(node *models.ASTNode) map[string]bool {
+func buildSubtreeHashes(node *model.ASTNode) map[string]bool {
 	if node == nil {
 		return make(map[string]bool)
 	}
 
 	// Hash cache: maps node pointer to its computed hash
 	// This avoids recomputing hashes during traversal (production-ready optimization)
-	hashCache := make(map[*models.ASTNode]string)
+	hashCache := make(map[*model.ASTNode]string)
 	
 	// Set to collect all subtree hashes
 	subtreeHashes := make(map[string]bool)
@@ -73,8 +83,8 @@ func buildSubtreeHashes(node *models.ASTNode) map[string]bool {
 // Post-order ensures children are hashed before their parent, enabling Merkle tree structure
 // hashCache stores computed hashes to avoid redundant recomputation
 func buildSubtreeHashesRecursive(
-	node *models.ASTNode,
-	hashCache map[*models.ASTNode]string,
+	node *model.ASTNode,
+	hashCache map[*model.ASTNode]string,
 	subtreeHashes map[string]bool,
 ) {
 	if node == nil {
@@ -108,7 +118,7 @@ func buildSubtreeHashesRecursive(
 // computeNodeHash computes Merkle hash for a node
 // childHashes should already be sorted and computed from post-order traversal
 // Includes all relevant node properties for accurate similarity detection
-func computeNodeHash(node *models.ASTNode, childHashes []string) string {
+func computeNodeHash(node *model.ASTNode, childHashes []string) string {
 	if node == nil {
 		return ""
 	}
@@ -155,6 +165,16 @@ func computeNodeHash(node *models.ASTNode, childHashes []string) string {
 		parts = append(parts, "params:", strings.Join(paramStrings, ","))
 	}
 	
+	// Include operands and expressions, which Astra keeps outside the children
+	for _, field := range []struct {
+		label string
+		value map[string]interface{}
+	}{{"left:", node.Left}, {"right:", node.Right}, {"expression:", node.Expression}} {
+		if len(field.value) > 0 {
+			parts = append(parts, field.label, valueHash(field.value))
+		}
+	}
+
 	// Include child hashes (already sorted from caller)
 	// This creates the Merkle tree structure: parent hash depends on children hashes
 	if len(childHashes) > 0 {
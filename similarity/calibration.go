@@ -0,0 +1,163 @@
+package similarity
+
+import (
+	"math"
+	"sort"
+
+	"github.com/RishiKendai/aegis/similarity/model"
+)
+
+const (
+	// DefaultCalibrationMinBucketSize is the fewest scored pairs a bucket needs for a baseline
+	DefaultCalibrationMinBucketSize = 20
+	// DefaultCalibrationOutlierZ is the robust z-score at which a pair stands out of its bucket
+	DefaultCalibrationOutlierZ = 3.5
+	// DefaultCalibrationMinScore keeps outliers of very dissimilar buckets from being flagged
+	DefaultCalibrationMinScore = 0.30
+	// DefaultCalibrationAlwaysFlagScore flags near copies even in buckets where most pairs are similar
+	DefaultCalibrationAlwaysFlagScore = 0.85
+
+	// madScale makes the MAD a consistent estimator of the standard deviation
+	madScale = 0.6745
+)
+
+// CalibrationParams tunes how pairs are flagged against their bucket's baseline
+type CalibrationParams struct {
+	Enabled         bool
+	MinBucketSize   int
+	OutlierZ        float64
+	MinScore        float64
+	AlwaysFlagScore float64
+}
+
+func (p CalibrationParams) withDefaults() CalibrationParams {
+	if p.MinBucketSize <= 0 {
+		p.MinBucketSize = DefaultCalibrationMinBucketSize
+	}
+	if p.OutlierZ <= 0 {
+		p.OutlierZ = DefaultCalibrationOutlierZ
+	}
+	if p.MinScore <= 0 {
+		p.MinScore = DefaultCalibrationMinScore
+	}
+	if p.AlwaysFlagScore <= 0 {
+		p.AlwaysFlagScore = DefaultCalibrationAlwaysFlagScore
+	}
+	return p
+}
+
+// BucketBaseline is the distribution of pair scores within one bucket (question and
+// language). A 0.6 means little on a short easy question where every solution looks
+// alike, and a lot on a long hard one; the baseline tells the two apart.
+type BucketBaseline struct {
+	Size   int
+	Median float64
+	MAD    float64 // median absolute deviation
+	Mean   float64
+	StdDev float64
+	Max    float64
+	sorted []float64
+}
+
+// NewBucketBaseline builds the baseline of a bucket from its scored pairs
+func NewBucketBaseline(pairs []PairSimilarity) *BucketBaseline {
+	scores := make([]float64, 0, len(pairs))
+	for _, pair := range pairs {
+		scores = append(scores, pair.FinalScore)
+	}
+	sort.Float64s(scores)
+
+	baseline := &BucketBaseline{Size: len(scores), sorted: scores}
+	if len(scores) == 0 {
+		return baseline
+	}
+
+	baseline.Median = median(scores)
+	baseline.Max = scores[len(scores)-1]
+	deviations := make([]float64, len(scores))
+	sum := 0.0
+	for i, score := range scores {
+		deviations[i] = math.Abs(score - baseline.Median)
+		sum += score
+	}
+	sort.Float64s(deviations)
+	baseline.MAD = median(deviations)
+
+	baseline.Mean = sum / float64(len(scores))
+	variance := 0.0
+	for _, score := range scores {
+		variance += (score - baseline.Mean) * (score - baseline.Mean)
+	}
+	baseline.StdDev = math.Sqrt(variance / float64(len(scores)))
+
+	return baseline
+}
+
+// ZScore returns the robust z-score of a score; it falls back to the standard z-score
+// when more than half the bucket shares one score (MAD of zero)
+func (b *BucketBaseline) ZScore(score float64) float64 {
+	switch {
+	case b.MAD > 0:
+		return madScale * (score - b.Median) / b.MAD
+	case b.StdDev > 0:
+		return (score - b.Mean) / b.StdDev
+	default:
+		return 0.0
+	}
+}
+
+// Percentile returns the share of bucket scores at or below a score, in [0, 1]
+func (b *BucketBaseline) Percentile(score float64) float64 {
+	if b.Size == 0 {
+		return 0.0
+	}
+	atOrBelow := sort.Search(len(b.sorted), func(i int) bool { return b.sorted[i] > score })
+	return float64(atOrBelow) / float64(b.Size)
+}
+
+// Calibrate places a pair within the baseline and decides whether it is an outlier.
+// It returns nil when calibration is disabled or the bucket is too small, in which
+// case the fixed significance threshold applies.
+func (b *BucketBaseline) Calibrate(score float64, params CalibrationParams) *model.Calibration {
+	params = params.withDefaults()
+	if b == nil || !params.Enabled || b.Size < params.MinBucketSize {
+		return nil
+	}
+
+	z := b.ZScore(score)
+	return &model.Calibration{
+		ZScore:       z,
+		Percentile:   b.Percentile(score),
+		Outlier:      score >= params.AlwaysFlagScore || (score >= params.MinScore && z >= params.OutlierZ),
+		BucketSize:   b.Size,
+		BucketMedian: b.Median,
+		BucketMAD:    b.MAD,
+	}
+}
+
+// CalibratePairs annotates pairs against a baseline in place
+func CalibratePairs(pairs []PairSimilarity, baseline *BucketBaseline, params CalibrationParams) {
+	for i := range pairs {
+		pairs[i].Calibration = baseline.Calibrate(pairs[i].FinalScore, params)
+	}
+}
+
+// Significant reports whether a pair is flagged: against its bucket baseline when
+// calibrated, otherwise against SignificantSimilarityThreshold
+func (p PairSimilarity) Significant() bool {
+	if p.Calibration != nil {
+		return p.Calibration.Outlier
+	}
+	return p.FinalScore >= SignificantSimilarityThreshold
+}
+
+func median(sorted []float64) float64 {
+	n := len(sorted)
+	if n == 0 {
+		return 0.0
+	}
+	if n%2 == 1 {
+		return sorted[n/2]
+	}
+	return (sorted[n/2-1] + sorted[n/2]) / 2
+}
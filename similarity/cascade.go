@@ -1,10 +1,7 @@
-package plagiarism
+package similarity
 
 import (
-	"fmt"
-
-	"github.com/RishiKendai/aegis/internal/models"
-	"github.com/rs/zerolog/log"
+	"github.com/RishiKendai/aegis/similarity/model"
 )
 
 // SimilarityScores holds scores from all algorithms
@@ -17,29 +14,59 @@ type SimilarityScores struct {
 
 // CascadeResult holds the result of cascade pipeline
 type CascadeResult struct {
-	Scores         SimilarityScores
-	ShortCircuited bool
-	FinalScore     float64
+	Scores          SimilarityScores
+	Containment     model.LayerContainment
+	ShortCircuited  bool
+	FinalScore      float64
+	Weights         Weights // layer weights of the profile the pair was scored with
+	FunctionMatches []model.FunctionMatch
+	Normalization   []NormalizationPass // passes that changed either artifact
+}
+
+// CascadePipeline runs the whole-file cascade, then per-function matching.
+// A strongly matching function lifts FinalScore so that copying a single helper
+// into an otherwise original solution is not diluted by the rest of the file.
+// Pairs the cascade short-circuited as dissimilar are not matched per function:
+// structural units of common routines (input parsing, small helpers) would lift
+// honest pairs. Features come from a FeatureCache so per-artifact work is shared
+// across pairs.
+func CascadePipeline(featuresA, featuresB *ArtifactFeatures, profile Profile) *CascadeResult {
+	result := runCascadeLayers(featuresA, featuresB, profile)
+	result.Normalization = mergePasses(featuresA.AppliedPasses(), featuresB.AppliedPasses())
+	if result.ShortCircuited {
+		return result
+	}
+
+	result.FunctionMatches = matchFunctionUnits(featuresA.FunctionUnits(), featuresB.FunctionUnits())
+	if len(result.FunctionMatches) > 0 {
+		functionScore := result.FunctionMatches[0].Score * FunctionScoreWeight
+		if functionScore > result.FinalScore {
+			result.FinalScore = functionScore
+		}
+	}
+
+	return result
 }
 
-// CascadePipeline implements progressive short-circuit pipeline
+// runCascadeLayers implements progressive short-circuit pipeline
 // Order: Fingerprint → Token → AST → CFG
-func CascadePipeline(artifactA, artifactB *models.Artifact, difficulty string) *CascadeResult {
+func runCascadeLayers(featuresA, featuresB *ArtifactFeatures, profile Profile) *CascadeResult {
 	result := &CascadeResult{
-		Scores: SimilarityScores{},
+		Scores:  SimilarityScores{},
+		Weights: profile.Weights,
 	}
 
-	weights := getWeights(difficulty)
+	weights := profile.Weights
 
 	// Initialize accumulated score
 	currentScore := 0.0
 	remainingMax := weights.Fingerprint + weights.Token + weights.AST + weights.CFG
 
 	// 1. Fingerprint
-	result.Scores.Fingerprint = FingerprintSimilarity(artifactA, artifactB)
+	result.Scores.Fingerprint, result.Containment.Fingerprint = fingerprintScores(featuresA, featuresB)
 	currentScore += result.Scores.Fingerprint * weights.Fingerprint
 	remainingMax -= weights.Fingerprint
-	layerThreshold := getThreshold(difficulty, "fingerprint")
+	layerThreshold := profile.Thresholds.Fingerprint
 	if shouldShortCircuit(currentScore, remainingMax, layerThreshold) {
 		result.ShortCircuited = true
 		result.FinalScore = currentScore
@@ -47,10 +74,10 @@ func CascadePipeline(artifactA, artifactB *models.Artifact, difficulty string) *
 	}
 
 	// 2. Token (GST)
-	result.Scores.Token = TokenSimilarity(artifactA, artifactB)
+	result.Scores.Token, result.Containment.Token = tokenScores(featuresA, featuresB)
 	currentScore += result.Scores.Token * weights.Token
 	remainingMax -= weights.Token
-	layerThreshold = getThreshold(difficulty, "token")
+	layerThreshold = profile.Thresholds.Token
 	if shouldShortCircuit(currentScore, remainingMax, layerThreshold) {
 		result.ShortCircuited = true
 		result.FinalScore = currentScore
@@ -58,10 +85,10 @@ func CascadePipeline(artifactA, artifactB *models.Artifact, difficulty string) *
 	}
 
 	// 3. AST (Merkle)
-	result.Scores.AST = ASTSimilarity(artifactA, artifactB)
+	result.Scores.AST, result.Containment.AST = astScores(featuresA, featuresB)
 	currentScore += result.Scores.AST * weights.AST
 	remainingMax -= weights.AST
-	layerThreshold = getThreshold(difficulty, "ast")
+	layerThreshold = profile.Thresholds.AST
 	if shouldShortCircuit(currentScore, remainingMax, layerThreshold) {
 		result.ShortCircuited = true
 		result.FinalScore = currentScore
@@ -69,24 +96,25 @@ func CascadePipeline(artifactA, artifactB *models.Artifact, difficulty string) *
 	}
 
 	// 4. CFG (finest, slowest)
-	result.Scores.CFG = CFGSimilarity(artifactA, artifactB)
+	result.Scores.CFG, result.Containment.CFG = cfgScores(featuresA, featuresB)
 	currentScore += result.Scores.CFG * weights.CFG
 	remainingMax -= weights.CFG
-	layerThreshold = getThreshold(difficulty, "cfg")
-	log.Trace().
-		Str("candidate", artifactA.Email).
-		Str("plagiarist", artifactB.Email).
-		Str("difficulty", difficulty).
-		Float64("currentScore", currentScore).
-		Float64("remainingMax", remainingMax).
-		Float64("layerThreshold", layerThreshold).
-		Msg("CFG similarity")
-	fmt.Println("-------------------------------------------")
 	result.FinalScore = currentScore
 
 	return result
 }
 
+// LayerScores computes the score of every layer from shared features, without
+// short-circuiting, on the same normalized inputs CascadePipeline uses
+func LayerScores(featuresA, featuresB *ArtifactFeatures) SimilarityScores {
+	var scores SimilarityScores
+	scores.Fingerprint, _ = fingerprintScores(featuresA, featuresB)
+	scores.Token, _ = tokenScores(featuresA, featuresB)
+	scores.AST, _ = astScores(featuresA, featuresB)
+	scores.CFG, _ = cfgScores(featuresA, featuresB)
+	return scores
+}
+
 func shouldShortCircuit(currentScore, remainingMax, threshold float64) bool {
 	// If current weighted score + max possible from remaining < threshold, short-circuit
 	return currentScore+remainingMax < threshold
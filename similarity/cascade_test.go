@@ -0,0 +1,112 @@
+package similarity
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/RishiKendai/aegis/similarity/model"
+)
+
+// tokenArtifact builds an artifact from a token stream, fingerprinted as the local preprocessor does
+func tokenArtifact(attemptID, tokens string) *model.Artifact {
+	stream := strings.Fields(tokens)
+	return &model.Artifact{AttemptID: attemptID, NormalizedTokens: stream, Fingerprints: winnow(stream, defaultKGramSize, defaultWindowSize)}
+}
+
+func TestRunCascadeLayersShortCircuit(t *testing.T) {
+	const (
+		source    = "int a = read ( ) ; int b = read ( ) ; print ( a + b ) ;"
+		unrelated = "for i in range n : total += i * i return total end loop"
+	)
+	lexical := Weights{Fingerprint: 0.5, Token: 0.5}
+
+	tests := []struct {
+		name             string
+		tokensB          string
+		dropFingerprints bool // B keeps its tokens, so a token score of 0 shows the layer was skipped
+		thresholds       LayerThresholds
+		shortCircuited   bool
+		finalScore       float64
+		tokenScore       float64
+	}{
+		{
+			name:           "identical pair runs every layer",
+			tokensB:        source,
+			thresholds:     LayerThresholds{Fingerprint: 0.6, Token: 0.6},
+			shortCircuited: false,
+			finalScore:     1.0,
+			tokenScore:     1.0,
+		},
+		{
+			name:             "pair below the bound stops after the fingerprint layer",
+			tokensB:          source,
+			dropFingerprints: true,
+			thresholds:       LayerThresholds{Fingerprint: 0.6},
+			shortCircuited:   true,
+			finalScore:       0.0,
+			tokenScore:       0.0,
+		},
+		{
+			name:             "upper bound equal to the threshold keeps going",
+			tokensB:          source,
+			dropFingerprints: true,
+			thresholds:       LayerThresholds{Fingerprint: 0.5},
+			shortCircuited:   false,
+			finalScore:       0.5,
+			tokenScore:       1.0,
+		},
+		{
+			name:           "unrelated pair stops after the token layer",
+			tokensB:        unrelated,
+			thresholds:     LayerThresholds{Token: 0.1},
+			shortCircuited: true,
+			finalScore:     0.0,
+			tokenScore:     0.0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			artifactB := tokenArtifact("b", tt.tokensB)
+			if tt.dropFingerprints {
+				artifactB.Fingerprints = nil
+			}
+			profile := Profile{Name: "medium", Weights: lexical, Thresholds: tt.thresholds}
+
+			result := runCascadeLayers(NewArtifactFeatures(tokenArtifact("a", source)), NewArtifactFeatures(artifactB), profile)
+			if result.ShortCircuited != tt.shortCircuited {
+				t.Errorf("ShortCircuited = %v, want %v", result.ShortCircuited, tt.shortCircuited)
+			}
+			if math.Abs(result.FinalScore-tt.finalScore) > 1e-9 {
+				t.Errorf("FinalScore = %v, want %v", result.FinalScore, tt.finalScore)
+			}
+			if math.Abs(result.Scores.Token-tt.tokenScore) > 1e-9 {
+				t.Errorf("Scores.Token = %v, want %v", result.Scores.Token, tt.tokenScore)
+			}
+		})
+	}
+}
+
+func TestShouldShortCircuit(t *testing.T) {
+	tests := []struct {
+		name         string
+		currentScore float64
+		remainingMax float64
+		threshold    float64
+		want         bool
+	}{
+		{name: "below threshold even at best", currentScore: 0.1, remainingMax: 0.3, threshold: 0.5, want: true},
+		{name: "reaches threshold exactly", currentScore: 0.2, remainingMax: 0.3, threshold: 0.5, want: false},
+		{name: "already above threshold", currentScore: 0.6, remainingMax: 0.0, threshold: 0.5, want: false},
+		{name: "zero threshold never cuts", currentScore: 0.0, remainingMax: 0.0, threshold: 0.0, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := shouldShortCircuit(tt.currentScore, tt.remainingMax, tt.threshold); got != tt.want {
+				t.Errorf("shouldShortCircuit(%v, %v, %v) = %v, want %v", tt.currentScore, tt.remainingMax, tt.threshold, got, tt.want)
+			}
+		})
+	}
+}
@@ -1,27 +1,33 @@
-package plagiarism
+package similarity
 
 import (
 	"math"
 
-	"github.com/RishiKendai/aegis/internal/models"
+	"github.com/RishiKendai/aegis/similarity/model"
 )
 
 // CFGSimilarity calculates similarity using CFG feature vector distance
-func CFGSimilarity(artifactA, artifactB *models.Artifact) float64 {
-	if artifactA.CFG == nil || artifactB.CFG == nil {
-		return 0.0
-	}
+func CFGSimilarity(artifactA, artifactB *model.Artifact) float64 {
+	score, _ := cfgScores(NewArtifactFeatures(artifactA), NewArtifactFeatures(artifactB))
+	return score
+}
 
-	// Extract features
-	featuresA := extractCFGFeatures(artifactA.CFG)
-	featuresB := extractCFGFeatures(artifactB.CFG)
+// cfgScores returns the distance-based score and both containment directions
+func cfgScores(artifactFeaturesA, artifactFeaturesB *ArtifactFeatures) (float64, model.Containment) {
+	// Feature vectors are extracted once per artifact
+	featuresA, okA := artifactFeaturesA.CFGFeatures()
+	featuresB, okB := artifactFeaturesB.CFGFeatures()
+	if !okA || !okB {
+		return 0.0, model.Containment{}
+	}
+	containment := featureContainment(featuresA, featuresB)
 
 	// Calculate normalized distance
 	distance := euclideanDistance(featuresA, featuresB)
 	maxDistance := calculateMaxDistance(featuresA, featuresB)
 
 	if maxDistance == 0 {
-		return 1.0 // Identical CFGs
+		return 1.0, containment // Identical CFGs
 	}
 
 	// CFGScore = 1 - (||vecA - vecB|| / max_distance)
@@ -33,12 +39,34 @@ func CFGSimilarity(artifactA, artifactB *models.Artifact) float64 {
 		score = 1.0
 	}
 
-	return score
+	return score, containment
+}
+
+// featureContainment treats feature vectors as multisets:
+// AInB = sum(min(a_i, b_i)) / sum(a_i), BInA = sum(min(a_i, b_i)) / sum(b_i)
+func featureContainment(vecA, vecB [6]float64) model.Containment {
+	shared, sumA, sumB := 0.0, 0.0, 0.0
+	for i := 0; i < 6; i++ {
+		a := math.Max(0, vecA[i])
+		b := math.Max(0, vecB[i])
+		shared += math.Min(a, b)
+		sumA += a
+		sumB += b
+	}
+
+	containment := model.Containment{}
+	if sumA > 0 {
+		containment.AInB = shared / sumA
+	}
+	if sumB > 0 {
+		containment.BInA = shared / sumB
+	}
+	return containment
 }
 
 // extractCFGFeatures extracts 6-dimensional feature vector:
 // [#Nodes, #Edges, #Branches, #Loops, Max depth, Cyclomatic complexity]
-func extractCFGFeatures(cfg *models.CFG) [6]float64 {
+func extractCFGFeatures(cfg *model.CFG) [6]float64 {
 	features := [6]float64{}
 
 	// #Nodes
@@ -70,7 +98,7 @@ func extractCFGFeatures(cfg *models.CFG) [6]float64 {
 }
 
 // detectLoops detects cycles in the CFG
-func detectLoops(cfg *models.CFG) int {
+func detectLoops(cfg *model.CFG) int {
 	// Build adjacency list
 	adj := make(map[string][]string)
 	for _, edge := range cfg.Edges {
@@ -109,7 +137,7 @@ func detectLoops(cfg *models.CFG) int {
 }
 
 // calculateMaxDepth calculates the longest path from entry to exit
-func calculateMaxDepth(cfg *models.CFG) int {
+func calculateMaxDepth(cfg *model.CFG) int {
 	// Build adjacency list
 	adj := make(map[string][]string)
 	for _, edge := range cfg.Edges {
@@ -0,0 +1,339 @@
+package similarity
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/RishiKendai/aegis/similarity/model"
+)
+
+const (
+	// MinClusterSize is the smallest component reported as a cluster (a lone pair is not a ring)
+	MinClusterSize = 3
+
+	// labelPropagationRounds bounds community detection inside a component
+	labelPropagationRounds = 20
+
+	// sourceCentralityTolerance keeps nodes within this share of the top centrality as source candidates
+	sourceCentralityTolerance = 0.90
+
+	// Cluster shapes
+	ClusterShapeClique = "clique"
+	ClusterShapeStar   = "star"
+	ClusterShapeMixed  = "mixed"
+)
+
+// similarityGraph is an undirected weighted graph of candidates (attemptIDs)
+type similarityGraph struct {
+	nodes       map[string]*model.Artifact
+	submittedAt map[string]time.Time          // attemptID -> earliest submission across its artifacts
+	adjacency   map[string]map[string]float64 // attemptID -> peer -> max score
+	questions   map[string]map[string]bool    // edge key -> qIDs
+}
+
+// DetectClusters builds a similarity graph from significant pairs, finds connected
+// components and communities, and names a likely source for every cluster
+func DetectClusters(pairs []PairSimilarity) []model.Cluster {
+	graph := buildSimilarityGraph(pairs)
+
+	components := graph.connectedComponents()
+	clusters := make([]model.Cluster, 0)
+	for _, members := range components {
+		if len(members) < MinClusterSize {
+			continue
+		}
+		clusters = append(clusters, graph.describeCluster(members))
+	}
+
+	// Largest clusters first, then by score; members break ties so IDs are stable between runs
+	sort.SliceStable(clusters, func(i, j int) bool {
+		if len(clusters[i].Members) != len(clusters[j].Members) {
+			return len(clusters[i].Members) > len(clusters[j].Members)
+		}
+		if clusters[i].AverageScore != clusters[j].AverageScore {
+			return clusters[i].AverageScore > clusters[j].AverageScore
+		}
+		return slices.Compare(clusters[i].Members, clusters[j].Members) < 0
+	})
+	for i := range clusters {
+		clusters[i].ClusterID = fmt.Sprintf("c%d", i+1)
+	}
+
+	return clusters
+}
+
+func buildSimilarityGraph(pairs []PairSimilarity) *similarityGraph {
+	graph := &similarityGraph{
+		nodes:       make(map[string]*model.Artifact),
+		submittedAt: make(map[string]time.Time),
+		adjacency:   make(map[string]map[string]float64),
+		questions:   make(map[string]map[string]bool),
+	}
+
+	for _, pair := range pairs {
+		a := pair.ArtifactA.AttemptID
+		b := pair.ArtifactB.AttemptID
+		if a == b {
+			continue
+		}
+
+		graph.addNode(pair.ArtifactA)
+		graph.addNode(pair.ArtifactB)
+		if graph.adjacency[a] == nil {
+			graph.adjacency[a] = make(map[string]float64)
+		}
+		if graph.adjacency[b] == nil {
+			graph.adjacency[b] = make(map[string]float64)
+		}
+
+		// Candidates may match on several questions; keep the strongest edge
+		if pair.FinalScore > graph.adjacency[a][b] {
+			graph.adjacency[a][b] = pair.FinalScore
+			graph.adjacency[b][a] = pair.FinalScore
+		}
+
+		edgeKey := PairKey(a, b)
+		if graph.questions[edgeKey] == nil {
+			graph.questions[edgeKey] = make(map[string]bool)
+		}
+		graph.questions[edgeKey][pair.QID] = true
+	}
+
+	return graph
+}
+
+// addNode records an attempt's artifact. An attempt answers several questions; the
+// earliest known submission of any of them counts.
+func (g *similarityGraph) addNode(artifact *model.Artifact) {
+	attemptID := artifact.AttemptID
+	if _, exists := g.nodes[attemptID]; !exists {
+		g.nodes[attemptID] = artifact
+	}
+	submittedAt := artifact.SubmittedAt
+	if earliest, known := g.submittedAt[attemptID]; !submittedAt.IsZero() && (!known || submittedAt.Before(earliest)) {
+		g.submittedAt[attemptID] = submittedAt
+	}
+}
+
+// connectedComponents returns the node sets of all components, members sorted
+func (g *similarityGraph) connectedComponents() [][]string {
+	visited := make(map[string]bool)
+	components := make([][]string, 0)
+
+	for _, start := range g.sortedNodes() {
+		if visited[start] {
+			continue
+		}
+
+		component := make([]string, 0)
+		stack := []string{start}
+		visited[start] = true
+		for len(stack) > 0 {
+			node := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			component = append(component, node)
+
+			for peer := range g.adjacency[node] {
+				if !visited[peer] {
+					visited[peer] = true
+					stack = append(stack, peer)
+				}
+			}
+		}
+
+		sort.Strings(component)
+		components = append(components, component)
+	}
+
+	return components
+}
+
+// communities runs weighted label propagation inside a component
+func (g *similarityGraph) communities(members []string) [][]string {
+	labels := make(map[string]string, len(members))
+	for _, member := range members {
+		labels[member] = member
+	}
+
+	for round := 0; round < labelPropagationRounds; round++ {
+		changed := false
+		for _, member := range members {
+			weights := make(map[string]float64)
+			for peer, score := range g.adjacency[member] {
+				weights[labels[peer]] += score
+			}
+
+			best := labels[member]
+			bestWeight := weights[best]
+			for label, weight := range weights {
+				// Deterministic tie-break on the smaller label
+				if weight > bestWeight || (weight == bestWeight && label < best) {
+					best = label
+					bestWeight = weight
+				}
+			}
+
+			if best != labels[member] {
+				labels[member] = best
+				changed = true
+			}
+		}
+		if !changed {
+			break
+		}
+	}
+
+	grouped := make(map[string][]string)
+	for _, member := range members {
+		grouped[labels[member]] = append(grouped[labels[member]], member)
+	}
+
+	communities := make([][]string, 0, len(grouped))
+	for _, community := range grouped {
+		sort.Strings(community)
+		communities = append(communities, community)
+	}
+	sort.Slice(communities, func(i, j int) bool {
+		if len(communities[i]) != len(communities[j]) {
+			return len(communities[i]) > len(communities[j])
+		}
+		return communities[i][0] < communities[j][0]
+	})
+
+	return communities
+}
+
+// describeCluster summarises a component: questions, scores, shape and likely source
+func (g *similarityGraph) describeCluster(members []string) model.Cluster {
+	memberSet := make(map[string]bool, len(members))
+	for _, member := range members {
+		memberSet[member] = true
+	}
+
+	questionSet := make(map[string]bool)
+	scoreSum := 0.0
+	edgeCount := 0
+	centrality := make(map[string]float64, len(members))
+	degree := make(map[string]int, len(members))
+
+	for _, member := range members {
+		for peer, score := range g.adjacency[member] {
+			if !memberSet[peer] {
+				continue
+			}
+			centrality[member] += score
+			degree[member]++
+
+			// Count each undirected edge once
+			if member < peer {
+				scoreSum += score
+				edgeCount++
+				for qID := range g.questions[PairKey(member, peer)] {
+					questionSet[qID] = true
+				}
+			}
+		}
+	}
+
+	questions := make([]string, 0, len(questionSet))
+	for qID := range questionSet {
+		questions = append(questions, qID)
+	}
+	sort.Strings(questions)
+
+	emails := make([]string, 0, len(members))
+	for _, member := range members {
+		emails = append(emails, g.nodes[member].Email)
+	}
+
+	n := len(members)
+	density := 0.0
+	if n > 1 {
+		density = float64(edgeCount) / float64(n*(n-1)/2)
+	}
+
+	averageScore := 0.0
+	if edgeCount > 0 {
+		averageScore = scoreSum / float64(edgeCount)
+	}
+
+	return model.Cluster{
+		Members:      members,
+		MemberEmails: emails,
+		Questions:    questions,
+		AverageScore: averageScore,
+		Density:      density,
+		Shape:        clusterShape(members, degree, density),
+		LikelySource: g.likelySource(members, centrality),
+		Communities:  g.communities(members),
+	}
+}
+
+// clusterShape classifies a component as a clique, a star around one node, or mixed
+func clusterShape(members []string, degree map[string]int, density float64) string {
+	n := len(members)
+	if density >= 0.8 {
+		return ClusterShapeClique
+	}
+
+	hubs := 0
+	leaves := 0
+	for _, member := range members {
+		switch degree[member] {
+		case n - 1:
+			hubs++
+		case 1:
+			leaves++
+		}
+	}
+	if hubs == 1 && leaves == n-1 {
+		return ClusterShapeStar
+	}
+
+	return ClusterShapeMixed
+}
+
+// likelySource picks the earliest submitter among the most central members.
+// Members without a timestamp lose to those with one; ties go to higher centrality.
+func (g *similarityGraph) likelySource(members []string, centrality map[string]float64) string {
+	maxCentrality := 0.0
+	for _, member := range members {
+		maxCentrality = math.Max(maxCentrality, centrality[member])
+	}
+
+	candidates := make([]string, 0)
+	for _, member := range members {
+		if centrality[member] >= maxCentrality*sourceCentralityTolerance {
+			candidates = append(candidates, member)
+		}
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		timeI := g.submittedAt[candidates[i]]
+		timeJ := g.submittedAt[candidates[j]]
+		if timeI.IsZero() != timeJ.IsZero() {
+			return !timeI.IsZero()
+		}
+		if !timeI.Equal(timeJ) {
+			return timeI.Before(timeJ)
+		}
+		return centrality[candidates[i]] > centrality[candidates[j]]
+	})
+
+	if len(candidates) == 0 {
+		return ""
+	}
+	return candidates[0]
+}
+
+func (g *similarityGraph) sortedNodes() []string {
+	nodes := make([]string, 0, len(g.nodes))
+	for node := range g.nodes {
+		nodes = append(nodes, node)
+	}
+	sort.Strings(nodes)
+	return nodes
+}
@@ -0,0 +1,46 @@
+package similarity
+
+import (
+	"testing"
+	"time"
+
+	"github.com/RishiKendai/aegis/similarity/model"
+)
+
+func TestDetectClusters(t *testing.T) {
+	base := time.Date(2026, 1, 1, 9, 0, 0, 0, time.UTC)
+	artifact := func(attemptID string, minutes int) *model.Artifact {
+		return &model.Artifact{AttemptID: attemptID, SubmittedAt: base.Add(time.Duration(minutes) * time.Minute)}
+	}
+	pair := func(a, b *model.Artifact, qID string) PairSimilarity {
+		return PairSimilarity{ArtifactA: a, ArtifactB: b, FinalScore: 0.9, QID: qID}
+	}
+
+	// "a" submitted q2 first but q1 last; its earliest submission makes it the source
+	pairs := []PairSimilarity{
+		pair(artifact("a", 30), artifact("b", 10), "q1"),
+		pair(artifact("b", 10), artifact("c", 20), "q1"),
+		pair(artifact("a", 30), artifact("c", 20), "q1"),
+		pair(artifact("a", 5), artifact("b", 40), "q2"),
+		// A second triangle with the same size and score
+		pair(artifact("x", 1), artifact("y", 2), "q1"),
+		pair(artifact("y", 2), artifact("z", 3), "q1"),
+		pair(artifact("x", 1), artifact("z", 3), "q1"),
+	}
+
+	for run := 0; run < 10; run++ {
+		clusters := DetectClusters(pairs)
+		if len(clusters) != 2 {
+			t.Fatalf("DetectClusters() returned %d clusters, want 2", len(clusters))
+		}
+		if clusters[0].ClusterID != "c1" || clusters[0].Members[0] != "a" {
+			t.Fatalf("first cluster = %s %v, want c1 starting with a", clusters[0].ClusterID, clusters[0].Members)
+		}
+		if clusters[0].LikelySource != "a" {
+			t.Errorf("likely source = %s, want a", clusters[0].LikelySource)
+		}
+		if clusters[1].LikelySource != "x" {
+			t.Errorf("likely source = %s, want x", clusters[1].LikelySource)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+// Package similarity is the Aegis detection engine: fingerprint, token, AST and CFG
+// similarity, the cascade that combines them, worthy-pair generation and candidate
+// scoring. It works on preprocessed artifacts only and has no dependency on Mongo,
+// Redis or the service's logging, so it can be embedded in other Go programs.
+package similarity
+
+import (
+	"sort"
+
+	"github.com/RishiKendai/aegis/similarity/model"
+)
+
+// Result is the outcome of comparing two artifacts
+type Result struct {
+	FinalScore      float64
+	Scores          SimilarityScores
+	Containment     model.LayerContainment
+	ShortCircuited  bool
+	FunctionMatches []model.FunctionMatch
+	Normalization   []NormalizationPass
+	Direction       *Direction // nil when undetermined
+	Risk            string     // candidate risk level implied by FinalScore
+}
+
+// Direction names the likely source and copier of a compared pair by attempt ID
+type Direction struct {
+	Source string
+	Copier string
+	Basis  string // DirectionBasisTimestamp or DirectionBasisContainment
+}
+
+// PairResult is the result of comparing the artifacts at indices A and B of a set
+type PairResult struct {
+	A, B int
+	Result
+}
+
+// Compare runs the full cascade on two preprocessed artifacts of the same language.
+// The profile's normalization passes are applied to both artifacts first.
+// To compare many artifacts, use CompareAll.
+func Compare(a, b *model.Artifact, profile Profile) Result {
+	return compareArtifacts(a, b, nil, profile)
+}
+
+// CompareAll compares the worthy pairs among preprocessed artifacts answering one
+// question in one language, filtered by the worthy threshold of the profile's name.
+// Per-artifact features are computed once and shared by all pairs. Attempt IDs must
+// be unique. Results are ordered by A, then B.
+func CompareAll(artifacts []*model.Artifact, profile Profile) []PairResult {
+	indices := make(map[*model.Artifact]int, len(artifacts))
+	for i, artifact := range artifacts {
+		indices[artifact] = i
+	}
+
+	gii := BuildGII(artifacts, profile.Normalization...)
+	features := NewFeatureCache(profile.Normalization...)
+	results := make([]PairResult, 0)
+	for _, pair := range GetWorthyPairs(gii, artifacts, profile.Name, profile.Normalization...) {
+		a, b := indices[pair.ArtifactA], indices[pair.ArtifactB]
+		if a > b {
+			a, b = b, a
+		}
+		results = append(results, PairResult{
+			A:      a,
+			B:      b,
+			Result: compareArtifacts(artifacts[a], artifacts[b], features, profile),
+		})
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].A != results[j].A {
+			return results[i].A < results[j].A
+		}
+		return results[i].B < results[j].B
+	})
+	return results
+}
+
+// compareArtifacts runs the cascade on two artifacts; a nil cache builds uncached features
+func compareArtifacts(a, b *model.Artifact, features *FeatureCache, profile Profile) Result {
+	featuresA := NewArtifactFeatures(a, profile.Normalization...)
+	featuresB := NewArtifactFeatures(b, profile.Normalization...)
+	if features != nil {
+		featuresA, featuresB = features.Get(a), features.Get(b)
+	}
+	cascade := CascadePipeline(featuresA, featuresB, profile)
+
+	result := Result{
+		FinalScore:      cascade.FinalScore,
+		Scores:          cascade.Scores,
+		Containment:     cascade.Containment,
+		ShortCircuited:  cascade.ShortCircuited,
+		FunctionMatches: cascade.FunctionMatches,
+		Normalization:   cascade.Normalization,
+		Risk:            GetRiskLevel(cascade.FinalScore),
+	}
+	if direction := InferCopyDirection(a, b, cascade.Containment); direction != nil {
+		result.Direction = &Direction{
+			Source: direction.Source.AttemptID,
+			Copier: direction.Copier.AttemptID,
+			Basis:  direction.Basis,
+		}
+	}
+	return result
+}
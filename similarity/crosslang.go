@@ -0,0 +1,329 @@
+package similarity
+
+import (
+	"strings"
+
+	"github.com/RishiKendai/aegis/similarity/model"
+)
+
+// crossLanguageKGramSize is the k-gram length used over the language-neutral stream
+const crossLanguageKGramSize = 4
+
+// CrossLanguageScores holds scores from the language-neutral comparison
+type CrossLanguageScores struct {
+	Structure float64 // GST over the neutral node stream
+	KGram     float64 // k-gram containment over the neutral node stream
+	CFG       float64 // CFG feature vector similarity
+}
+
+// CrossLanguageResult holds the result of the cross-language pipeline
+type CrossLanguageResult struct {
+	Scores     CrossLanguageScores
+	FinalScore float64
+}
+
+// CrossLanguagePipeline compares two artifacts written in different languages
+// using only the AST node types and CFG structure returned by Astra
+func CrossLanguagePipeline(featuresA, featuresB *ArtifactFeatures, difficulty string) *CrossLanguageResult {
+	result := &CrossLanguageResult{}
+	weights := getCrossLanguageWeights(difficulty)
+
+	streamA := featuresA.NeutralStream()
+	streamB := featuresB.NeutralStream()
+
+	if len(streamA) > 0 && len(streamB) > 0 {
+		matched := greedyStringTiling(streamA, streamB, minLength)
+		result.Scores.Structure = 2.0 * float64(matched) / float64(len(streamA)+len(streamB))
+		result.Scores.KGram = setOverlap(featuresA.NeutralKGrams(), featuresB.NeutralKGrams())
+	}
+
+	result.Scores.CFG, _ = cfgScores(featuresA, featuresB)
+
+	result.FinalScore = result.Scores.Structure*weights.Structure +
+		result.Scores.KGram*weights.KGram +
+		result.Scores.CFG*weights.CFG
+
+	return result
+}
+
+// NeutralStream maps an AST to a language-neutral sequence of node categories.
+// Language-specific nodes (imports, blocks, modules) are dropped.
+func NeutralStream(root *model.ASTNode) []string {
+	stream := make([]string, 0)
+	if root == nil {
+		return stream
+	}
+
+	var walk func(node *model.ASTNode)
+	walk = func(node *model.ASTNode) {
+		if node == nil {
+			return
+		}
+
+		if category := neutralCategory(node.Type); category != "" {
+			stream = append(stream, category)
+		}
+		if node.Operator != "" {
+			stream = append(stream, "OP:"+neutralOperator(node.Operator))
+		}
+
+		for _, child := range node.Children {
+			walk(child)
+		}
+	}
+	walk(root)
+
+	return stream
+}
+
+// neutralCategory maps a language-specific AST node type to a neutral category.
+// Types are split into words so "IfStatement", "If" and "IfExp" all map to BRANCH
+// while "Identifier" does not.
+func neutralCategory(nodeType string) string {
+	words := make(map[string]bool)
+	for _, word := range splitTypeWords(nodeType) {
+		words[word] = true
+	}
+
+	hasAny := func(candidates ...string) bool {
+		for _, candidate := range candidates {
+			if words[candidate] {
+				return true
+			}
+		}
+		return false
+	}
+
+	switch {
+	case hasAny("call", "invocation"):
+		return "CALL"
+	case hasAny("function", "method", "constructor", "lambda"):
+		return "FUNC"
+	case hasAny("class"):
+		return "CLASS"
+	case hasAny("for", "foreach", "while", "do", "loop"):
+		return "LOOP"
+	case hasAny("if", "switch", "case", "conditional", "ternary"):
+		return "BRANCH"
+	case hasAny("return"):
+		return "RETURN"
+	case hasAny("break", "continue"):
+		return "JUMP"
+	case hasAny("try", "catch", "except"):
+		return "TRY"
+	case hasAny("assign", "assignment"):
+		return "ASSIGN"
+	case hasAny("declaration", "declarator"):
+		return "DECL"
+	case hasAny("binary", "bin", "unary", "compare", "bool"):
+		return "EXPR"
+	default:
+		return ""
+	}
+}
+
+// splitTypeWords splits CamelCase and snake_case node types into lowercase words
+func splitTypeWords(nodeType string) []string {
+	words := make([]string, 0)
+	current := strings.Builder{}
+	flush := func() {
+		if current.Len() > 0 {
+			words = append(words, strings.ToLower(current.String()))
+			current.Reset()
+		}
+	}
+
+	for _, r := range nodeType {
+		switch {
+		case r == '_' || r == '-' || r == ' ':
+			flush()
+		case r >= 'A' && r <= 'Z':
+			flush()
+			current.WriteRune(r)
+		default:
+			current.WriteRune(r)
+		}
+	}
+	flush()
+
+	return words
+}
+
+// neutralOperator folds language-specific spellings of the same operator
+func neutralOperator(operator string) string {
+	switch operator {
+	case "&&", "and":
+		return "and"
+	case "||", "or":
+		return "or"
+	case "!", "not":
+		return "not"
+	case "===":
+		return "=="
+	case "!==":
+		return "!="
+	case "//":
+		return "/"
+	default:
+		return operator
+	}
+}
+
+// setOverlap = shared / min(|A|, |B|)
+func setOverlap(setA, setB map[string]bool) float64 {
+	if len(setA) == 0 || len(setB) == 0 {
+		return 0.0
+	}
+
+	shared := 0
+	for hash := range setA {
+		if setB[hash] {
+			shared++
+		}
+	}
+
+	return float64(shared) / float64(min(len(setA), len(setB)))
+}
+
+// GetCrossLanguagePairs finds worthy pairs across language buckets of one qId.
+// Candidates share neutral k-grams; pairs within the same language are skipped
+// since the regular pass already covers them. K-grams found in more than
+// params.MaxBucketSize artifacts are stop-grams (loops, returns, assignments every
+// solution has) and do not make candidates on their own; questions with more
+// artifacts than params.BucketThreshold use MinHash/LSH instead of posting lists.
+// Every candidate is kept only if its exact neutral k-gram overlap reaches the
+// cross-language worthy threshold. Neutral streams come from the feature cache.
+func GetCrossLanguagePairs(langBuckets map[string][]*model.Artifact, difficulty string, features *FeatureCache, params LSHParams) []Pair {
+	if len(langBuckets) < 2 {
+		return nil
+	}
+	params = params.withDefaults(difficulty)
+
+	// Several languages of the same attempt share an attemptID
+	hashSets := make(map[string]map[string]bool)
+	artifactMap := make(map[string]*model.Artifact)
+	for _, bucketArtifacts := range langBuckets {
+		for _, artifact := range bucketArtifacts {
+			hashes := features.Get(artifact).NeutralKGrams()
+			if len(hashes) == 0 {
+				continue
+			}
+			key := crossLanguageKey(artifact)
+			hashSets[key] = hashes
+			artifactMap[key] = artifact
+		}
+	}
+
+	var candidates [][2]string
+	if params.UseLSH(len(hashSets)) {
+		candidates = lshCandidates(hashSets, params)
+	} else {
+		candidates = postingCandidates(hashSets, params.MaxBucketSize)
+	}
+
+	threshold := getCrossLanguageWorthyThreshold(difficulty)
+	pairs := make([]Pair, 0)
+	for _, candidate := range candidates {
+		artifactA, artifactB := artifactMap[candidate[0]], artifactMap[candidate[1]]
+		if artifactA.Language == artifactB.Language || artifactA.AttemptID == artifactB.AttemptID {
+			continue
+		}
+		if setOverlap(hashSets[candidate[0]], hashSets[candidate[1]]) >= threshold {
+			pairs = append(pairs, Pair{ArtifactA: artifactA, ArtifactB: artifactB})
+		}
+	}
+
+	return pairs
+}
+
+// crossLanguageKey identifies an artifact among the language buckets of one qId
+func crossLanguageKey(artifact *model.Artifact) string {
+	return artifact.AttemptID + "\x00" + artifact.Language
+}
+
+// postingCandidates returns the pairs of keys sharing at least one hash whose posting
+// list holds at most maxPostings keys
+func postingCandidates(hashSets map[string]map[string]bool, maxPostings int) [][2]string {
+	index := make(map[string][]string)
+	for key, hashes := range hashSets {
+		for hash := range hashes {
+			index[hash] = append(index[hash], key)
+		}
+	}
+
+	seen := make(map[[2]string]bool)
+	candidates := make([][2]string, 0)
+	for _, keys := range index {
+		if len(keys) > maxPostings {
+			continue
+		}
+		for i := 0; i < len(keys); i++ {
+			for j := i + 1; j < len(keys); j++ {
+				candidate := orderedKeys(keys[i], keys[j])
+				if !seen[candidate] {
+					seen[candidate] = true
+					candidates = append(candidates, candidate)
+				}
+			}
+		}
+	}
+	return candidates
+}
+
+// CrossLanguageWeights holds weights of the cross-language layers by difficulty
+type CrossLanguageWeights struct {
+	Structure float64
+	KGram     float64
+	CFG       float64
+}
+
+// getCrossLanguageWeights returns cross-language weights based on difficulty
+func getCrossLanguageWeights(difficulty string) CrossLanguageWeights {
+	switch difficulty {
+	case "easy":
+		return CrossLanguageWeights{
+			Structure: 0.50,
+			KGram:     0.30,
+			CFG:       0.20,
+		}
+	case "hard":
+		return CrossLanguageWeights{
+			Structure: 0.40,
+			KGram:     0.25,
+			CFG:       0.35,
+		}
+	default:
+		// medium and unknown
+		return CrossLanguageWeights{
+			Structure: 0.45,
+			KGram:     0.25,
+			CFG:       0.30,
+		}
+	}
+}
+
+// CrossLanguageThreshold returns the significance threshold for cross-language pairs.
+// Solutions to the same question share a lot of neutral structure, so these sit
+// well above SignificantSimilarityThreshold.
+func CrossLanguageThreshold(difficulty string) float64 {
+	switch difficulty {
+	case "easy":
+		return 0.85 // Easy problems converge to the same shape in every language
+	case "hard":
+		return 0.72
+	default:
+		return 0.78
+	}
+}
+
+// getCrossLanguageWorthyThreshold returns the neutral k-gram overlap needed to compare a pair
+func getCrossLanguageWorthyThreshold(difficulty string) float64 {
+	switch difficulty {
+	case "easy":
+		return 0.60
+	case "hard":
+		return 0.40
+	default:
+		return 0.50
+	}
+}
@@ -0,0 +1,75 @@
+package similarity
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/RishiKendai/aegis/similarity/model"
+)
+
+// neutralArtifact builds an artifact whose neutral stream is one expression per operator
+func neutralArtifact(attemptID, language, prefix string, from, to int) *model.Artifact {
+	root := &model.ASTNode{Type: "Program"}
+	for i := from; i < to; i++ {
+		root.Children = append(root.Children, &model.ASTNode{Type: "BinaryExpression", Operator: fmt.Sprintf("%s%d", prefix, i)})
+	}
+	return &model.Artifact{AttemptID: attemptID, Language: language, AST: root}
+}
+
+func TestGetCrossLanguagePairs(t *testing.T) {
+	tests := []struct {
+		name    string
+		buckets map[string][]*model.Artifact
+		params  LSHParams
+		want    []string
+	}{
+		{
+			name: "an attempt answering in two languages keeps both artifacts",
+			buckets: map[string][]*model.Artifact{
+				"python": {neutralArtifact("a", "python", "x", 0, 40)},
+				"java":   {neutralArtifact("a", "java", "y", 0, 40), neutralArtifact("b", "java", "x", 0, 40)},
+			},
+			want: []string{"a/python-b/java"},
+		},
+		{
+			name: "k-grams shared by more artifacts than the posting cap make no candidates",
+			buckets: map[string][]*model.Artifact{
+				"python": {neutralArtifact("a", "python", "x", 0, 40), neutralArtifact("b", "python", "x", 0, 40)},
+				"java":   {neutralArtifact("c", "java", "x", 0, 40)},
+			},
+			params: LSHParams{MaxBucketSize: 2},
+			want:   []string{},
+		},
+		{
+			name: "large questions use LSH",
+			buckets: map[string][]*model.Artifact{
+				"python": {neutralArtifact("a", "python", "x", 0, 40), neutralArtifact("b", "python", "z", 0, 40)},
+				"java":   {neutralArtifact("c", "java", "x", 0, 40), neutralArtifact("d", "java", "w", 0, 40)},
+			},
+			params: LSHParams{BucketThreshold: 2},
+			want:   []string{"a/python-c/java"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pairs := GetCrossLanguagePairs(tt.buckets, "medium", NewFeatureCache(), tt.params)
+			got := make(map[string]bool, len(pairs))
+			for _, pair := range pairs {
+				a, b := pair.ArtifactA, pair.ArtifactB
+				if a.AttemptID > b.AttemptID {
+					a, b = b, a
+				}
+				got[a.AttemptID+"/"+a.Language+"-"+b.AttemptID+"/"+b.Language] = true
+			}
+			if len(got) != len(tt.want) {
+				t.Errorf("GetCrossLanguagePairs() = %v, want %v", got, tt.want)
+			}
+			for _, key := range tt.want {
+				if !got[key] {
+					t.Errorf("GetCrossLanguagePairs() is missing %s", key)
+				}
+			}
+		})
+	}
+}
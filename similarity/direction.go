@@ -0,0 +1,75 @@
+package similarity
+
+import (
+	"github.com/RishiKendai/aegis/similarity/model"
+)
+
+const (
+	// DirectionBasisTimestamp means the earlier submission is taken as the source. Only
+	// submission times count: ingest order reflects the queue, not who wrote first.
+	DirectionBasisTimestamp = "timestamp"
+
+	// DirectionBasisContainment means timestamps were unusable and containment asymmetry decided
+	DirectionBasisContainment = "containment"
+
+	// minContainmentGap is the asymmetry needed before containment alone suggests a direction
+	minContainmentGap = 0.10
+)
+
+// CopyDirection suggests which artifact of a pair is the likely source and which the copier
+type CopyDirection struct {
+	Source *model.Artifact
+	Copier *model.Artifact
+	Basis  string
+}
+
+// InferCopyDirection uses submission timestamps to suggest a likely source and copier.
+// When both artifacts carry the same (or no) timestamp, the artifact that is largely
+// contained in the other is taken as the derived one. Returns nil when undetermined.
+func InferCopyDirection(artifactA, artifactB *model.Artifact, containment model.LayerContainment) *CopyDirection {
+	timeA := artifactA.SubmittedAt
+	timeB := artifactB.SubmittedAt
+
+	if !timeA.IsZero() && !timeB.IsZero() && !timeA.Equal(timeB) {
+		if timeA.Before(timeB) {
+			return &CopyDirection{Source: artifactA, Copier: artifactB, Basis: DirectionBasisTimestamp}
+		}
+		return &CopyDirection{Source: artifactB, Copier: artifactA, Basis: DirectionBasisTimestamp}
+	}
+
+	aInB, bInA := meanContainment(containment)
+	if aInB-bInA >= minContainmentGap {
+		return &CopyDirection{Source: artifactB, Copier: artifactA, Basis: DirectionBasisContainment}
+	}
+	if bInA-aInB >= minContainmentGap {
+		return &CopyDirection{Source: artifactA, Copier: artifactB, Basis: DirectionBasisContainment}
+	}
+
+	return nil
+}
+
+// meanContainment averages both directions over the layers that produced a value
+func meanContainment(containment model.LayerContainment) (float64, float64) {
+	layers := []model.Containment{
+		containment.Fingerprint,
+		containment.Token,
+		containment.AST,
+		containment.CFG,
+	}
+
+	sumAInB, sumBInA := 0.0, 0.0
+	count := 0
+	for _, layer := range layers {
+		if layer.AInB == 0 && layer.BInA == 0 {
+			continue // Layer short-circuited or missing
+		}
+		sumAInB += layer.AInB
+		sumBInA += layer.BInA
+		count++
+	}
+
+	if count == 0 {
+		return 0.0, 0.0
+	}
+	return sumAInB / float64(count), sumBInA / float64(count)
+}
@@ -0,0 +1,127 @@
+package similarity
+
+import (
+	"fmt"
+
+	"github.com/RishiKendai/aegis/similarity/model"
+)
+
+// ExplainCandidateScore breaks a candidate's score down into the values
+// AggregateCandidate computed it from, so reviewers can see why a candidate was
+// flagged. It returns nil when no pair is significant.
+func ExplainCandidateScore(pairs []PairSimilarity, attemptID string, strategy AggregationStrategy) *model.RiskExplanation {
+	aggregate := AggregateCandidate(pairs, attemptID, strategy)
+	if len(aggregate.Pairs) == 0 {
+		return nil
+	}
+
+	explanation := &model.RiskExplanation{
+		Score:          aggregate.Score,
+		Strategy:       string(aggregate.Strategy),
+		K:              len(aggregate.Pairs),
+		TopPairs:       make([]model.ExplainedPair, 0, len(aggregate.Pairs)),
+		BaseScore:      aggregate.Base,
+		DistinctPeers:  aggregate.DistinctPeers,
+		Boost:          aggregate.Boost,
+		QuestionMaxima: make(map[string]float64),
+	}
+
+	significant, algorithmic, outlier := false, false, false
+	for _, pair := range pairs {
+		if !pair.Significant() {
+			continue
+		}
+		if pair.FinalScore > explanation.QuestionMaxima[pair.QID] {
+			explanation.QuestionMaxima[pair.QID] = pair.FinalScore
+		}
+		significant = significant || pair.FinalScore >= SignificantSimilarityThreshold
+		algorithmic = algorithmic || pair.FinalScore >= AlgorithmicSimilarityThreshold
+		outlier = outlier || (pair.Calibration != nil && pair.Calibration.Outlier)
+	}
+
+	for _, pair := range aggregate.Pairs {
+		explained := explainPair(pair, attemptID)
+		explanation.LayerContributions.Fingerprint += explained.Contributions.Fingerprint
+		explanation.LayerContributions.Token += explained.Contributions.Token
+		explanation.LayerContributions.AST += explained.Contributions.AST
+		explanation.LayerContributions.CFG += explained.Contributions.CFG
+		explanation.TopPairs = append(explanation.TopPairs, explained)
+	}
+	k := float64(explanation.K)
+	explanation.LayerContributions.Fingerprint /= k
+	explanation.LayerContributions.Token /= k
+	explanation.LayerContributions.AST /= k
+	explanation.LayerContributions.CFG /= k
+
+	crossed := make([]string, 0)
+	if significant {
+		crossed = append(crossed, thresholdLabel("significant", SignificantSimilarityThreshold))
+	}
+	if outlier {
+		crossed = append(crossed, "calibrated_outlier")
+	}
+	if algorithmic {
+		crossed = append(crossed, thresholdLabel("algorithmic", AlgorithmicSimilarityThreshold))
+	}
+	for _, level := range []struct {
+		risk      string
+		threshold float64
+	}{
+		{RiskSuspicious, RiskSuspiciousThreshold},
+		{RiskHighlySuspicious, RiskHighlySuspiciousThreshold},
+		{RiskNearCopy, RiskNearCopyThreshold},
+	} {
+		if aggregate.Score >= level.threshold {
+			crossed = append(crossed, thresholdLabel(level.risk, level.threshold))
+		}
+	}
+	explanation.ThresholdsCrossed = crossed
+
+	return explanation
+}
+
+// explainPair describes a pair from the candidate's side, with each layer's share of
+// the weighted score. Shares use the weights the pair was scored with, so they add up
+// to its cascade score; pairs without them fall back to the built-in difficulty weights.
+func explainPair(pair PairSimilarity, attemptID string) model.ExplainedPair {
+	peer := peerOf(pair, attemptID)
+
+	weights := getWeights(pair.Difficulty)
+	if pair.Weights != nil {
+		weights = *pair.Weights
+	}
+	explained := model.ExplainedPair{
+		QID:           pair.QID,
+		PeerAttemptID: peer.AttemptID,
+		PeerEmail:     peer.Email,
+		FinalScore:    pair.FinalScore,
+		Scores: model.LayerScores{
+			Fingerprint: pair.Scores.Fingerprint,
+			Token:       pair.Scores.Token,
+			AST:         pair.Scores.AST,
+			CFG:         pair.Scores.CFG,
+		},
+		Contributions: model.LayerScores{
+			Fingerprint: pair.Scores.Fingerprint * weights.Fingerprint,
+			Token:       pair.Scores.Token * weights.Token,
+			AST:         pair.Scores.AST * weights.AST,
+			CFG:         pair.Scores.CFG * weights.CFG,
+		},
+	}
+
+	weighted := explained.Contributions.Fingerprint + explained.Contributions.Token +
+		explained.Contributions.AST + explained.Contributions.CFG
+	if len(pair.FunctionMatches) > 0 && pair.FinalScore > weighted {
+		explained.FunctionLifted = pair.FunctionMatches[0].Score*FunctionScoreWeight >= pair.FinalScore
+	}
+	if pair.Calibration != nil {
+		z := pair.Calibration.ZScore
+		explained.ZScore = &z
+	}
+
+	return explained
+}
+
+func thresholdLabel(name string, threshold float64) string {
+	return fmt.Sprintf("%s>=%.2f", name, threshold)
+}
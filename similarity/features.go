@@ -0,0 +1,170 @@
+package similarity
+
+import (
+	"sync"
+
+	"github.com/RishiKendai/aegis/similarity/model"
+)
+
+// ArtifactFeatures holds the per-artifact inputs of every layer. The same artifact
+// takes part in many pairs, so each feature is computed once, on first use, and then
+// shared by all pairs (and workers) of the computation.
+type ArtifactFeatures struct {
+	Artifact *model.Artifact
+
+	// passes canonicalise the AST and tokens before any structural feature is built.
+	// The two are normalized separately, so AST features work on artifacts loaded
+	// with their AST only.
+	passes      []NormalizationPass
+	astOnce     sync.Once
+	ast         *model.ASTNode
+	astPasses   []NormalizationPass
+	tokensOnce  sync.Once
+	tokens      []string
+	tokenPasses []NormalizationPass
+
+	fingerprintsOnce sync.Once
+	fingerprints     map[string]bool
+
+	subtreesOnce sync.Once
+	subtrees     map[string]bool
+
+	cfgOnce     sync.Once
+	cfgFeatures [6]float64
+	hasCFG      bool
+
+	functionsOnce sync.Once
+	functions     []*FunctionUnit
+
+	neutralOnce   sync.Once
+	neutral       []string
+	neutralKGrams map[string]bool
+}
+
+// NewArtifactFeatures wraps an artifact; nothing is computed until requested.
+// Token and AST features are built from the artifact after the given passes.
+func NewArtifactFeatures(artifact *model.Artifact, passes ...NormalizationPass) *ArtifactFeatures {
+	return &ArtifactFeatures{Artifact: artifact, passes: passes}
+}
+
+// normalizeAST applies the normalization passes to the AST once; the artifact itself is left untouched
+func (f *ArtifactFeatures) normalizeAST() {
+	f.astOnce.Do(func() {
+		f.ast, f.astPasses = NormalizeAST(f.Artifact.AST, f.passes)
+	})
+}
+
+// normalizeTokens applies the normalization passes to the normalized tokens once
+func (f *ArtifactFeatures) normalizeTokens() {
+	f.tokensOnce.Do(func() {
+		f.tokens, f.tokenPasses = NormalizeTokens(f.Artifact.NormalizedTokens, f.passes)
+	})
+}
+
+// AppliedPasses returns the normalization passes that changed this artifact
+func (f *ArtifactFeatures) AppliedPasses() []NormalizationPass {
+	f.normalizeAST()
+	f.normalizeTokens()
+	return mergePasses(f.astPasses, f.tokenPasses)
+}
+
+// Fingerprints returns the set of unique fingerprint hashes; with passes they are
+// winnowed from the normalized tokens, as FingerprintSet does
+func (f *ArtifactFeatures) Fingerprints() map[string]bool {
+	f.fingerprintsOnce.Do(func() {
+		if len(f.passes) == 0 {
+			f.fingerprints = hashSet(f.Artifact.Fingerprints)
+			return
+		}
+		f.fingerprints = hashSet(winnowArtifact(f.Artifact, f.Tokens()))
+	})
+	return f.fingerprints
+}
+
+// Tokens returns the normalized token stream used by GST
+func (f *ArtifactFeatures) Tokens() []string {
+	f.normalizeTokens()
+	return f.tokens
+}
+
+// SubtreeHashes returns the Merkle hashes of all AST subtrees
+func (f *ArtifactFeatures) SubtreeHashes() map[string]bool {
+	f.subtreesOnce.Do(func() {
+		f.normalizeAST()
+		if f.ast != nil {
+			f.subtrees = buildSubtreeHashes(f.ast)
+		}
+	})
+	return f.subtrees
+}
+
+// CFGFeatures returns the CFG feature vector and whether the artifact has a CFG
+func (f *ArtifactFeatures) CFGFeatures() ([6]float64, bool) {
+	f.cfgOnce.Do(func() {
+		if f.Artifact.CFG != nil {
+			f.cfgFeatures = extractCFGFeatures(f.Artifact.CFG)
+			f.hasCFG = true
+		}
+	})
+	return f.cfgFeatures, f.hasCFG
+}
+
+// FunctionUnits returns the function-level units of the AST
+func (f *ArtifactFeatures) FunctionUnits() []*FunctionUnit {
+	f.functionsOnce.Do(func() {
+		f.normalizeAST()
+		if f.ast != nil {
+			f.functions = ExtractFunctionUnits(f.ast)
+		}
+	})
+	return f.functions
+}
+
+// NeutralStream returns the language-neutral node stream of the AST
+func (f *ArtifactFeatures) NeutralStream() []string {
+	f.neutralOnce.Do(func() {
+		f.normalizeAST()
+		f.neutral = NeutralStream(f.ast)
+		f.neutralKGrams = kGramHashes(f.neutral, crossLanguageKGramSize)
+	})
+	return f.neutral
+}
+
+// NeutralKGrams returns the k-gram hashes of the language-neutral node stream
+func (f *ArtifactFeatures) NeutralKGrams() map[string]bool {
+	f.NeutralStream()
+	return f.neutralKGrams
+}
+
+// FeatureCache shares ArtifactFeatures between the pairs of one computation.
+// A nil cache is valid and hands out uncached, unnormalized features.
+type FeatureCache struct {
+	mu       sync.Mutex
+	passes   []NormalizationPass
+	features map[*model.Artifact]*ArtifactFeatures
+}
+
+// NewFeatureCache creates a cache whose features are built after the given passes
+func NewFeatureCache(passes ...NormalizationPass) *FeatureCache {
+	return &FeatureCache{
+		passes:   passes,
+		features: make(map[*model.Artifact]*ArtifactFeatures),
+	}
+}
+
+// Get returns the cached features of an artifact, creating them on first use
+func (c *FeatureCache) Get(artifact *model.Artifact) *ArtifactFeatures {
+	if c == nil {
+		return NewArtifactFeatures(artifact)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	features, exists := c.features[artifact]
+	if !exists {
+		features = NewArtifactFeatures(artifact, c.passes...)
+		c.features[artifact] = features
+	}
+	return features
+}
@@ -0,0 +1,43 @@
+package similarity
+
+import (
+	"github.com/RishiKendai/aegis/similarity/model"
+)
+
+// FingerprintSimilarity calculates similarity using pre-computed fingerprints
+func FingerprintSimilarity(artifactA, artifactB *model.Artifact) float64 {
+	score, _ := fingerprintScores(NewArtifactFeatures(artifactA), NewArtifactFeatures(artifactB))
+	return score
+}
+
+// fingerprintScores returns the symmetric score and both containment directions
+func fingerprintScores(featuresA, featuresB *ArtifactFeatures) (float64, model.Containment) {
+	// Hash sets are built once per artifact
+	hashesA := featuresA.Fingerprints()
+	hashesB := featuresB.Fingerprints()
+
+	// Count shared hashes
+	sharedCount := 0
+	for hash := range hashesA {
+		if hashesB[hash] {
+			sharedCount++
+		}
+	}
+
+	totalA := len(hashesA)
+	totalB := len(hashesB)
+
+	if totalA == 0 || totalB == 0 {
+		return 0.0, model.Containment{}
+	}
+
+	// FP_Score = shared_hashes / min(total_hashes_A, total_hashes_B)
+	minTotal := min(totalA, totalB)
+
+	containment := model.Containment{
+		AInB: float64(sharedCount) / float64(totalA),
+		BInA: float64(sharedCount) / float64(totalB),
+	}
+
+	return float64(sharedCount) / float64(minTotal), containment
+}
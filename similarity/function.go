@@ -0,0 +1,287 @@
+package similarity
+
+import (
+	"hash/fnv"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/RishiKendai/aegis/similarity/model"
+)
+
+const (
+	// functionKGramSize is the k-gram length used to fingerprint function units
+	functionKGramSize = 5
+
+	// minFunctionTokens skips trivial units (getters, empty mains) that match everywhere
+	minFunctionTokens = 15
+
+	// minFunctionSizeRatio is the smallest token count ratio of two matched units, so a
+	// short routine contained in a long function does not count as a copy of it
+	minFunctionSizeRatio = 0.5
+
+	// FunctionMatchThreshold is the minimum unit score reported as a function match
+	FunctionMatchThreshold = 0.60
+
+	// FunctionScoreWeight scales the best function match when it is folded into FinalScore
+	FunctionScoreWeight = 0.75
+
+	// maxFunctionMatches caps the number of function pairs reported per artifact pair
+	maxFunctionMatches = 5
+)
+
+// FunctionUnit is a function- or method-level slice of an artifact's AST
+type FunctionUnit struct {
+	Name   string
+	Type   string
+	Arity  int
+	Tokens []string        // Structural token stream of the subtree (identifiers excluded)
+	Hashes map[string]bool // k-gram fingerprints over Tokens
+}
+
+// ExtractFunctionUnits splits an AST into function- and method-level units
+func ExtractFunctionUnits(root *model.ASTNode) []*FunctionUnit {
+	units := make([]*FunctionUnit, 0)
+	if root == nil {
+		return units
+	}
+
+	var walk func(node *model.ASTNode)
+	walk = func(node *model.ASTNode) {
+		if node == nil {
+			return
+		}
+
+		if isFunctionNode(node) {
+			tokens := make([]string, 0)
+			collectStructuralTokens(node, &tokens)
+			if len(tokens) >= minFunctionTokens {
+				units = append(units, &FunctionUnit{
+					Name:   node.Name,
+					Type:   node.Type,
+					Arity:  len(node.Parameters),
+					Tokens: tokens,
+					Hashes: kGramHashes(tokens, functionKGramSize),
+				})
+			}
+		}
+
+		// Nested functions and methods inside classes are units of their own
+		for _, child := range node.Children {
+			walk(child)
+		}
+	}
+	walk(root)
+
+	return units
+}
+
+// isFunctionNode reports whether an AST node declares a function, method or constructor
+func isFunctionNode(node *model.ASTNode) bool {
+	nodeType := strings.ToLower(node.Type)
+	if strings.Contains(nodeType, "call") || strings.Contains(nodeType, "invocation") {
+		return false
+	}
+	if !strings.Contains(nodeType, "function") &&
+		!strings.Contains(nodeType, "method") &&
+		!strings.Contains(nodeType, "constructor") {
+		return false
+	}
+	return node.Name != "" || node.Parameters != nil
+}
+
+// collectStructuralTokens emits node types, operators and return types in pre-order
+// Identifier names are left out so renaming does not change the stream
+func collectStructuralTokens(node *model.ASTNode, tokens *[]string) {
+	if node == nil {
+		return
+	}
+
+	*tokens = append(*tokens, node.Type)
+	if node.Operator != "" {
+		*tokens = append(*tokens, "op:"+node.Operator)
+	}
+	if node.ReturnType != "" {
+		*tokens = append(*tokens, "ret:"+node.ReturnType)
+	}
+	for _, param := range node.Parameters {
+		*tokens = append(*tokens, "param:"+param.Type)
+	}
+
+	collectMapTokens(node.Expression, tokens)
+	collectMapTokens(node.Left, tokens)
+	collectMapTokens(node.Right, tokens)
+	collectMapTokens(node.Body, tokens)
+	for _, statement := range node.Statements {
+		collectMapTokens(statement, tokens)
+	}
+
+	for _, child := range node.Children {
+		collectStructuralTokens(child, tokens)
+	}
+}
+
+// collectMapTokens walks the loosely-typed sub-trees Astra returns as JSON objects
+func collectMapTokens(value interface{}, tokens *[]string) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		if len(v) == 0 {
+			return
+		}
+		if nodeType, ok := v["type"].(string); ok {
+			*tokens = append(*tokens, nodeType)
+		}
+		if operator, ok := v["operator"].(string); ok && operator != "" {
+			*tokens = append(*tokens, "op:"+operator)
+		}
+
+		// Sort keys so the stream is deterministic
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			if key == "type" || key == "operator" || key == "name" {
+				continue
+			}
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			collectMapTokens(v[key], tokens)
+		}
+	case []interface{}:
+		for _, item := range v {
+			collectMapTokens(item, tokens)
+		}
+	case []map[string]interface{}:
+		for _, item := range v {
+			collectMapTokens(item, tokens)
+		}
+	}
+}
+
+// kGramHashes hashes every k-gram of a token stream into a set
+func kGramHashes(tokens []string, k int) map[string]bool {
+	hashes := make(map[string]bool)
+	if len(tokens) < k {
+		if len(tokens) > 0 {
+			hashes[hashTokens(tokens)] = true
+		}
+		return hashes
+	}
+
+	for i := 0; i+k <= len(tokens); i++ {
+		hashes[hashTokens(tokens[i:i+k])] = true
+	}
+	return hashes
+}
+
+func hashTokens(tokens []string) string {
+	h := fnv.New64a()
+	for _, token := range tokens {
+		h.Write([]byte(token))
+		h.Write([]byte{0})
+	}
+	return strconv.FormatUint(h.Sum64(), 16)
+}
+
+// MatchFunctions runs fingerprint and token matching per function unit and
+// returns the best one-to-one function pairs, highest score first
+func MatchFunctions(artifactA, artifactB *model.Artifact) []model.FunctionMatch {
+	return matchFunctionUnits(NewArtifactFeatures(artifactA).FunctionUnits(), NewArtifactFeatures(artifactB).FunctionUnits())
+}
+
+// matchFunctionUnits pairs up already extracted function units
+func matchFunctionUnits(unitsA, unitsB []*FunctionUnit) []model.FunctionMatch {
+	if len(unitsA) == 0 || len(unitsB) == 0 {
+		return nil
+	}
+
+	type candidate struct {
+		i, j  int
+		match model.FunctionMatch
+	}
+
+	candidates := make([]candidate, 0)
+	for i, unitA := range unitsA {
+		for j, unitB := range unitsB {
+			shorter, longer := len(unitA.Tokens), len(unitB.Tokens)
+			if shorter > longer {
+				shorter, longer = longer, shorter
+			}
+			if float64(shorter) < minFunctionSizeRatio*float64(longer) {
+				continue
+			}
+
+			containment := unitContainment(unitA, unitB)
+			if containment == 0 {
+				continue
+			}
+
+			matched := greedyStringTiling(unitA.Tokens, unitB.Tokens, minLength)
+			tokenScore := 2.0 * float64(matched) / float64(len(unitA.Tokens)+len(unitB.Tokens))
+
+			score := (containment + tokenScore) / 2.0
+			if score < FunctionMatchThreshold {
+				continue
+			}
+
+			candidates = append(candidates, candidate{
+				i: i,
+				j: j,
+				match: model.FunctionMatch{
+					FunctionA:   unitA.Name,
+					FunctionB:   unitB.Name,
+					Containment: containment,
+					TokenScore:  tokenScore,
+					Score:       score,
+				},
+			})
+		}
+	}
+
+	// Ties keep the order of the functions in A, then B, so the assignment is deterministic
+	sort.SliceStable(candidates, func(x, y int) bool {
+		if candidates[x].match.Score != candidates[y].match.Score {
+			return candidates[x].match.Score > candidates[y].match.Score
+		}
+		if candidates[x].i != candidates[y].i {
+			return candidates[x].i < candidates[y].i
+		}
+		return candidates[x].j < candidates[y].j
+	})
+
+	// Greedy one-to-one assignment: each function is reported in at most one pair
+	usedA := make(map[int]bool)
+	usedB := make(map[int]bool)
+	matches := make([]model.FunctionMatch, 0)
+	for _, c := range candidates {
+		if usedA[c.i] || usedB[c.j] {
+			continue
+		}
+		usedA[c.i] = true
+		usedB[c.j] = true
+		matches = append(matches, c.match)
+		if len(matches) == maxFunctionMatches {
+			break
+		}
+	}
+
+	return matches
+}
+
+// unitContainment = shared k-grams / min(kgrams_A, kgrams_B)
+func unitContainment(unitA, unitB *FunctionUnit) float64 {
+	totalA := len(unitA.Hashes)
+	totalB := len(unitB.Hashes)
+	if totalA == 0 || totalB == 0 {
+		return 0.0
+	}
+
+	shared := 0
+	for hash := range unitA.Hashes {
+		if unitB.Hashes[hash] {
+			shared++
+		}
+	}
+
+	return float64(shared) / float64(min(totalA, totalB))
+}
@@ -0,0 +1,37 @@
+package similarity
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func functionUnit(name, tokens string) *FunctionUnit {
+	stream := strings.Fields(tokens)
+	return &FunctionUnit{Name: name, Tokens: stream, Hashes: kGramHashes(stream, functionKGramSize)}
+}
+
+func TestMatchFunctionUnitsTieBreak(t *testing.T) {
+	body := "FUNC PARAM DECL ASSIGN LOOP BRANCH EXPR RETURN CALL ASSIGN EXPR RETURN"
+	unitsA := make([]*FunctionUnit, 0, maxFunctionMatches)
+	unitsB := make([]*FunctionUnit, 0, maxFunctionMatches)
+	want := make([]string, 0, maxFunctionMatches)
+	for i := 1; i <= maxFunctionMatches; i++ {
+		unitsA = append(unitsA, functionUnit(fmt.Sprintf("a%d", i), body))
+		unitsB = append(unitsB, functionUnit(fmt.Sprintf("b%d", i), body))
+		want = append(want, fmt.Sprintf("a%d-b%d", i, i))
+	}
+
+	// Every candidate has the same score; the assignment must follow function order
+	for run := 0; run < 20; run++ {
+		matches := matchFunctionUnits(unitsA, unitsB)
+		if len(matches) != len(want) {
+			t.Fatalf("matchFunctionUnits() returned %d matches, want %d", len(matches), len(want))
+		}
+		for i, match := range matches {
+			if got := match.FunctionA + "-" + match.FunctionB; got != want[i] {
+				t.Fatalf("match %d = %s, want %s", i, got, want[i])
+			}
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package similarity
+
+import (
+	"github.com/RishiKendai/aegis/similarity/model"
+)
+
+// GII (Global Inverted Index) maps hash → [submission_ids]
+type GII map[string][]string
+
+// BuildGII indexes the fingerprint sets of the artifacts, after the given normalization passes
+func BuildGII(artifacts []*model.Artifact, passes ...NormalizationPass) GII {
+	gii := make(GII)
+
+	// First pass: Build hash → [attemptIds] mapping
+	for _, artifact := range artifacts {
+		attemptID := artifact.AttemptID
+		for hash := range FingerprintSet(artifact, passes...) {
+			gii[hash] = append(gii[hash], attemptID)
+		}
+	}
+
+	// Second pass: Filter out hashes with only 1 candidate (optimization)
+	filteredGII := make(GII)
+	for hash, attemptIDs := range gii {
+		if len(attemptIDs) >= 2 {
+			// Only include hashes with 2+ candidates
+			filteredGII[hash] = attemptIDs
+		}
+	}
+
+	return filteredGII
+}
+
+// GetWorthyPairs finds worthy pairs based on difficulty threshold; passes must match the ones the GII was built with
+func GetWorthyPairs(gii GII, artifacts []*model.Artifact, difficulty string, passes ...NormalizationPass) []Pair {
+	// Unique hash count per artifact, used as the overlap denominator
+	hashCounts := make(map[string]int)
+	for _, artifact := range artifacts {
+		hashCounts[artifact.AttemptID] = UniqueHashCount(artifact, passes...)
+	}
+
+	return GetWorthyPairsWithCounts(gii, artifacts, hashCounts, difficulty)
+}
+
+// GetWorthyPairsWithCounts finds worthy pairs using precomputed unique hash counts,
+// so artifacts' fingerprint lists are not needed
+func GetWorthyPairsWithCounts(gii GII, artifacts []*model.Artifact, hashCounts map[string]int, difficulty string) []Pair {
+	// Build artifact map for quick lookup
+	artifactMap := make(map[string]*model.Artifact)
+	for _, artifact := range artifacts {
+		artifactMap[artifact.AttemptID] = artifact
+	}
+
+	// Get threshold based on difficulty
+	threshold := WorthyThreshold(difficulty)
+
+	sharedPairCount := make(map[string]int)
+	pairArtifacts := make(map[string]Pair)
+
+	for _, attemptIDs := range gii {
+		if len(attemptIDs) < 2 {
+			continue
+		}
+
+		// Get artifacts for this hash
+		hashArtifacts := make([]*model.Artifact, 0)
+		for _, attemptID := range attemptIDs {
+			if artifact, ok := artifactMap[attemptID]; ok {
+				hashArtifacts = append(hashArtifacts, artifact)
+			}
+		}
+
+		// Calculate shared hashes for each pair
+		for i := 0; i < len(hashArtifacts); i++ {
+			for j := i + 1; j < len(hashArtifacts); j++ {
+				artifactA := hashArtifacts[i]
+				artifactB := hashArtifacts[j]
+
+				pairKey := PairKey(artifactA.AttemptID, artifactB.AttemptID)
+				sharedPairCount[pairKey]++
+				if _, exists := pairArtifacts[pairKey]; !exists {
+					pairArtifacts[pairKey] = Pair{
+						ArtifactA: artifactA,
+						ArtifactB: artifactB,
+					}
+				}
+			}
+		}
+	}
+
+	// Convert map to slice
+	worthyPairs := make([]Pair, 0)
+	for pairKey, sharedCount := range sharedPairCount {
+		pair := pairArtifacts[pairKey]
+		overlap := calculateOverlap(
+			hashCounts[pair.ArtifactA.AttemptID],
+			hashCounts[pair.ArtifactB.AttemptID],
+			sharedCount,
+		)
+
+		if overlap >= threshold {
+			worthyPairs = append(worthyPairs, pair)
+		}
+	}
+	return worthyPairs
+}
+
+// calculateOverlap = shared / min(unique_hashes_A, unique_hashes_B)
+func calculateOverlap(totalA, totalB, sharedCount int) float64 {
+	if totalA == 0 || totalB == 0 {
+		return 0.0
+	}
+
+	minTotal := min(totalA, totalB)
+	return float64(sharedCount) / float64(minTotal)
+}
+
+// UniqueHashCount returns the number of distinct fingerprint hashes of an artifact
+func UniqueHashCount(artifact *model.Artifact, passes ...NormalizationPass) int {
+	return len(FingerprintSet(artifact, passes...))
+}
+
+// WorthyThreshold returns threshold based on difficulty
+func WorthyThreshold(difficulty string) float64 {
+	switch difficulty {
+	case "easy":
+		return 0.15 // 15%
+	case "medium":
+		return 0.10 // 10%
+	case "hard":
+		return 0.05 // 5%
+	default:
+		return 0.10 // Default to medium
+	}
+}
+
+// Pair represents a pair of artifacts to compare
+type Pair struct {
+	ArtifactA *model.Artifact
+	ArtifactB *model.Artifact
+
+	// EstimatedJaccard is the MinHash Jaccard estimate, set only for LSH candidates
+	EstimatedJaccard float64
+}
+
+// PairKey creates a sorted key for a pair to avoid duplicates
+func PairKey(id1, id2 string) string {
+	if id1 < id2 {
+		return id1 + ":" + id2
+	}
+	return id2 + ":" + id1
+}
@@ -0,0 +1,278 @@
+package similarity
+
+import (
+	"hash/fnv"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/RishiKendai/aegis/similarity/model"
+)
+
+const (
+	// DefaultLSHBucketThreshold is the bucket size above which LSH replaces exact GII pairing
+	DefaultLSHBucketThreshold = 1000
+
+	// DefaultLSHRows is one row per band: the worthy thresholds imply Jaccard values of
+	// 0.03-0.08, far below what multi-row bands can reach with a practical band count
+	DefaultLSHRows = 1
+
+	// DefaultLSHRecall is the candidate probability the derived band count gives a pair
+	// exactly at the difficulty's worthy threshold
+	DefaultLSHRecall = 0.95
+
+	// DefaultLSHMaxBucketSize bounds the artifacts compared pairwise in one band bucket
+	DefaultLSHMaxBucketSize = 200
+
+	// maxLSHBands bounds the derived band count
+	maxLSHBands = 512
+
+	// minSignatureSize keeps the Jaccard estimate precise when few bands are needed
+	minSignatureSize = 128
+)
+
+// LSHParams controls MinHash/LSH candidate generation.
+// More bands (or fewer rows) raise recall at the cost of more candidate pairs;
+// a pair with Jaccard J becomes a candidate with probability 1 - (1 - J^rows)^bands.
+// Unset bands are derived from the difficulty's worthy threshold.
+type LSHParams struct {
+	BucketThreshold int
+	Bands           int
+	Rows            int
+
+	// MaxBucketSize caps a band bucket; larger ones are split on further signature
+	// positions, and what is still too large is skipped
+	MaxBucketSize int
+}
+
+// withDefaults fills unset parameters, deriving the band count from the Jaccard of
+// two equally sized sets at the difficulty's worthy overlap
+func (p LSHParams) withDefaults(difficulty string) LSHParams {
+	if p.BucketThreshold <= 0 {
+		p.BucketThreshold = DefaultLSHBucketThreshold
+	}
+	if p.Rows <= 0 {
+		p.Rows = DefaultLSHRows
+	}
+	if p.Bands <= 0 {
+		overlap := WorthyThreshold(difficulty)
+		p.Bands = lshBands(overlap/(2-overlap), p.Rows, DefaultLSHRecall)
+	}
+	if p.MaxBucketSize <= 0 {
+		p.MaxBucketSize = DefaultLSHMaxBucketSize
+	}
+	return p
+}
+
+// lshBands returns the fewest bands giving a pair with the Jaccard the recall:
+// 1 - (1 - J^rows)^bands >= recall
+func lshBands(jaccard float64, rows int, recall float64) int {
+	collision := math.Pow(jaccard, float64(rows))
+	if collision <= 0 {
+		return maxLSHBands
+	}
+	if collision >= 1 {
+		return 1
+	}
+	bands := int(math.Ceil(math.Log(1-recall) / math.Log(1-collision)))
+	return max(1, min(bands, maxLSHBands))
+}
+
+// UseLSH reports whether a bucket is large enough for LSH candidate generation
+func (p LSHParams) UseLSH(bucketSize int) bool {
+	return bucketSize > p.withDefaults("").BucketThreshold
+}
+
+// MinHashSignature is a fixed-length sketch of a fingerprint set
+type MinHashSignature []uint64
+
+// BuildMinHashSignature computes a signature of numHashes permutations over a hash set
+func BuildMinHashSignature(hashes map[string]bool, numHashes int) MinHashSignature {
+	signature := make(MinHashSignature, numHashes)
+	for i := range signature {
+		signature[i] = ^uint64(0)
+	}
+
+	for hash := range hashes {
+		base := hash64(hash)
+		for i := 0; i < numHashes; i++ {
+			value := mix64(base ^ minHashSeed(i))
+			if value < signature[i] {
+				signature[i] = value
+			}
+		}
+	}
+
+	return signature
+}
+
+// EstimateJaccard returns the share of signature positions that agree
+func EstimateJaccard(a, b MinHashSignature) float64 {
+	if len(a) == 0 || len(a) != len(b) {
+		return 0.0
+	}
+
+	equal := 0
+	for i := range a {
+		if a[i] == b[i] {
+			equal++
+		}
+	}
+	return float64(equal) / float64(len(a))
+}
+
+// GetLSHPairs turns fingerprint sets into candidate pairs with MinHash + LSH banding.
+// Only pairs colliding in at least one band are considered, then they are kept if their
+// exact overlap reaches the difficulty's worthy threshold. The estimate is too coarse to
+// filter on at the low Jaccard values the worthy thresholds imply.
+// Fingerprint sets are taken after the given normalization passes.
+func GetLSHPairs(artifacts []*model.Artifact, difficulty string, params LSHParams, passes ...NormalizationPass) []Pair {
+	params = params.withDefaults(difficulty)
+
+	hashSets := make(map[string]map[string]bool, len(artifacts))
+	artifactMap := make(map[string]*model.Artifact, len(artifacts))
+	for _, artifact := range artifacts {
+		hashes := FingerprintSet(artifact, passes...)
+		if len(hashes) == 0 {
+			continue
+		}
+		hashSets[artifact.AttemptID] = hashes
+		artifactMap[artifact.AttemptID] = artifact
+	}
+
+	signatures := buildSignatures(hashSets, params)
+	threshold := WorthyThreshold(difficulty)
+	pairs := make([]Pair, 0)
+	for _, candidate := range bandCandidates(signatures, params) {
+		hashesA, hashesB := hashSets[candidate[0]], hashSets[candidate[1]]
+		if len(hashesA) > len(hashesB) {
+			hashesA, hashesB = hashesB, hashesA
+		}
+		shared := 0
+		for hash := range hashesA {
+			if hashesB[hash] {
+				shared++
+			}
+		}
+		overlap := float64(shared) / float64(len(hashesA))
+
+		if overlap >= threshold {
+			pairs = append(pairs, Pair{
+				ArtifactA:        artifactMap[candidate[0]],
+				ArtifactB:        artifactMap[candidate[1]],
+				EstimatedJaccard: EstimateJaccard(signatures[candidate[0]], signatures[candidate[1]]),
+			})
+		}
+	}
+
+	return pairs
+}
+
+// lshCandidates returns the pairs of keys whose hash sets collide in at least one band
+func lshCandidates(hashSets map[string]map[string]bool, params LSHParams) [][2]string {
+	return bandCandidates(buildSignatures(hashSets, params), params)
+}
+
+// buildSignatures computes a MinHash signature per hash set, long enough for the bands,
+// the positions shardBucket splits on and a precise Jaccard estimate
+func buildSignatures(hashSets map[string]map[string]bool, params LSHParams) map[string]MinHashSignature {
+	numHashes := max(params.Bands*params.Rows, minSignatureSize)
+	signatures := make(map[string]MinHashSignature, len(hashSets))
+	for key, hashes := range hashSets {
+		signatures[key] = BuildMinHashSignature(hashes, numHashes)
+	}
+	return signatures
+}
+
+// bandCandidates bands the signatures; keys sharing a band key become candidates
+func bandCandidates(signatures map[string]MinHashSignature, params LSHParams) [][2]string {
+	bandHashes := params.Bands * params.Rows
+	numHashes := max(bandHashes, minSignatureSize)
+
+	seen := make(map[[2]string]bool)
+	candidates := make([][2]string, 0)
+	for band := 0; band < params.Bands; band++ {
+		buckets := make(map[string][]string)
+		for key, signature := range signatures {
+			bandValue := bandKey(signature[band*params.Rows : (band+1)*params.Rows])
+			buckets[bandValue] = append(buckets[bandValue], key)
+		}
+
+		for _, keys := range buckets {
+			// The next positions past the bands shard an oversized bucket
+			for _, shard := range shardBucket(keys, signatures, bandHashes, numHashes, params.MaxBucketSize) {
+				for i := 0; i < len(shard); i++ {
+					for j := i + 1; j < len(shard); j++ {
+						candidate := orderedKeys(shard[i], shard[j])
+						if !seen[candidate] {
+							seen[candidate] = true
+							candidates = append(candidates, candidate)
+						}
+					}
+				}
+			}
+		}
+	}
+	return candidates
+}
+
+// orderedKeys returns two keys in a fixed order, so each pair is seen once
+func orderedKeys(a, b string) [2]string {
+	if a > b {
+		a, b = b, a
+	}
+	return [2]string{a, b}
+}
+
+// shardBucket splits a band bucket larger than maxSize on one more signature position
+// at a time, starting at position, and drops shards still too large once positions run
+// out. Such a bucket is a fingerprint shared by most of the bucket, like boilerplate.
+func shardBucket(attemptIDs []string, signatures map[string]MinHashSignature, position, numHashes, maxSize int) [][]string {
+	if len(attemptIDs) <= maxSize {
+		return [][]string{attemptIDs}
+	}
+	if position >= numHashes {
+		return nil
+	}
+
+	shards := make(map[uint64][]string)
+	for _, attemptID := range attemptIDs {
+		value := signatures[attemptID][position]
+		shards[value] = append(shards[value], attemptID)
+	}
+
+	result := make([][]string, 0, len(shards))
+	for _, shard := range shards {
+		result = append(result, shardBucket(shard, signatures, position+1, numHashes, maxSize)...)
+	}
+	return result
+}
+
+func bandKey(rows []uint64) string {
+	parts := make([]string, len(rows))
+	for i, value := range rows {
+		parts[i] = strconv.FormatUint(value, 16)
+	}
+	return strings.Join(parts, ":")
+}
+
+func hash64(value string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(value))
+	return h.Sum64()
+}
+
+// minHashSeed derives a distinct seed per permutation
+func minHashSeed(i int) uint64 {
+	return mix64(uint64(i+1) * 0x9E3779B97F4A7C15)
+}
+
+// mix64 is the splitmix64 finalizer, used as a cheap permutation of 64-bit values
+func mix64(x uint64) uint64 {
+	x ^= x >> 30
+	x *= 0xBF58476D1CE4E5B9
+	x ^= x >> 27
+	x *= 0x94D049BB133111EB
+	x ^= x >> 31
+	return x
+}
@@ -0,0 +1,213 @@
+package similarity
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/RishiKendai/aegis/similarity/model"
+)
+
+// hashRange returns the hashes h<from>..h<to-1>
+func hashRange(from, to int) map[string]bool {
+	hashes := make(map[string]bool, to-from)
+	for i := from; i < to; i++ {
+		hashes[fmt.Sprintf("h%d", i)] = true
+	}
+	return hashes
+}
+
+func TestEstimateJaccard(t *testing.T) {
+	tests := []struct {
+		name      string
+		a, b      map[string]bool
+		want      float64
+		tolerance float64
+	}{
+		{name: "identical sets", a: hashRange(0, 200), b: hashRange(0, 200), want: 1.0, tolerance: 0},
+		{name: "disjoint sets", a: hashRange(0, 200), b: hashRange(200, 400), want: 0.0, tolerance: 0.03},
+		{name: "one third shared", a: hashRange(0, 200), b: hashRange(100, 300), want: 1.0 / 3.0, tolerance: 0.1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := EstimateJaccard(BuildMinHashSignature(tt.a, 256), BuildMinHashSignature(tt.b, 256))
+			if math.Abs(got-tt.want) > tt.tolerance {
+				t.Errorf("EstimateJaccard() = %v, want %v ± %v", got, tt.want, tt.tolerance)
+			}
+		})
+	}
+}
+
+func TestLSHParamsWithDefaults(t *testing.T) {
+	tests := []struct {
+		name       string
+		params     LSHParams
+		difficulty string
+		bands      int
+		rows       int
+	}{
+		{name: "easy derives from overlap 0.15", difficulty: "easy", bands: 36, rows: 1},
+		{name: "medium derives from overlap 0.10", difficulty: "medium", bands: 56, rows: 1},
+		{name: "hard derives from overlap 0.05", difficulty: "hard", bands: 116, rows: 1},
+		{name: "configured bands are kept", params: LSHParams{Bands: 20, Rows: 3}, difficulty: "medium", bands: 20, rows: 3},
+		{name: "more rows need more bands", params: LSHParams{Rows: 2}, difficulty: "easy", bands: 455, rows: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.params.withDefaults(tt.difficulty)
+			if got.Bands != tt.bands || got.Rows != tt.rows {
+				t.Errorf("withDefaults(%q) = %d bands x %d rows, want %d x %d", tt.difficulty, got.Bands, got.Rows, tt.bands, tt.rows)
+			}
+			if got.MaxBucketSize != DefaultLSHMaxBucketSize {
+				t.Errorf("MaxBucketSize = %d, want %d", got.MaxBucketSize, DefaultLSHMaxBucketSize)
+			}
+		})
+	}
+}
+
+func TestGetLSHPairs(t *testing.T) {
+	stream := func(from, to int) string {
+		tokens := make([]string, 0, to-from)
+		for i := from; i < to; i++ {
+			tokens = append(tokens, fmt.Sprintf("t%d", i))
+		}
+		return strings.Join(tokens, " ")
+	}
+
+	tests := []struct {
+		name      string
+		artifacts []*model.Artifact
+		want      []string
+	}{
+		{
+			name: "copied submission is a candidate, unrelated ones are not",
+			artifacts: []*model.Artifact{
+				tokenArtifact("original", stream(0, 300)),
+				tokenArtifact("copy", stream(0, 280)+" x y z"),
+				tokenArtifact("other", stream(1000, 1300)),
+			},
+			want: []string{PairKey("original", "copy")},
+		},
+		{
+			name: "small shared region above the worthy overlap is a candidate",
+			artifacts: []*model.Artifact{
+				tokenArtifact("a", stream(0, 300)),
+				tokenArtifact("b", stream(0, 60)+" "+stream(2000, 2240)),
+			},
+			want: []string{PairKey("a", "b")},
+		},
+		{
+			name: "artifacts without fingerprints are skipped",
+			artifacts: []*model.Artifact{
+				tokenArtifact("a", stream(0, 300)),
+				{AttemptID: "empty"},
+			},
+			want: []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pairs := GetLSHPairs(tt.artifacts, "medium", LSHParams{})
+			got := make(map[string]bool, len(pairs))
+			for _, pair := range pairs {
+				got[PairKey(pair.ArtifactA.AttemptID, pair.ArtifactB.AttemptID)] = true
+			}
+			if len(got) != len(tt.want) {
+				t.Errorf("GetLSHPairs() = %v, want %v", got, tt.want)
+			}
+			for _, key := range tt.want {
+				if !got[key] {
+					t.Errorf("GetLSHPairs() is missing %s", key)
+				}
+			}
+		})
+	}
+}
+
+func TestShardBucket(t *testing.T) {
+	signature := func(values ...uint64) MinHashSignature { return MinHashSignature(values) }
+
+	tests := []struct {
+		name       string
+		signatures map[string]MinHashSignature
+		maxSize    int
+		shards     int
+		kept       int
+	}{
+		{
+			name:       "bucket within the cap is kept whole",
+			signatures: map[string]MinHashSignature{"a": signature(1, 1), "b": signature(1, 2)},
+			maxSize:    2,
+			shards:     1,
+			kept:       2,
+		},
+		{
+			name:       "oversized bucket splits on the next position",
+			signatures: map[string]MinHashSignature{"a": signature(1, 1), "b": signature(1, 1), "c": signature(1, 2)},
+			maxSize:    2,
+			shards:     2,
+			kept:       3,
+		},
+		{
+			name:       "bucket still oversized when positions run out is dropped",
+			signatures: map[string]MinHashSignature{"a": signature(1, 1), "b": signature(1, 1), "c": signature(1, 1)},
+			maxSize:    2,
+			shards:     0,
+			kept:       0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attemptIDs := make([]string, 0, len(tt.signatures))
+			for attemptID := range tt.signatures {
+				attemptIDs = append(attemptIDs, attemptID)
+			}
+
+			shards := shardBucket(attemptIDs, tt.signatures, 1, 2, tt.maxSize)
+			kept := 0
+			for _, shard := range shards {
+				kept += len(shard)
+			}
+			if len(shards) != tt.shards || kept != tt.kept {
+				t.Errorf("shardBucket() = %d shards of %d artifacts, want %d of %d", len(shards), kept, tt.shards, tt.kept)
+			}
+		})
+	}
+}
+
+func TestGetLSHPairsRecallAtWorthyOverlap(t *testing.T) {
+	stream := func(prefix string, from, to int) string {
+		tokens := make([]string, 0, to-from)
+		for i := from; i < to; i++ {
+			tokens = append(tokens, fmt.Sprintf("%s%d", prefix, i))
+		}
+		return strings.Join(tokens, " ")
+	}
+
+	// Each pair shares about 12% of its fingerprints, just above the medium worthy overlap
+	const numPairs = 40
+	artifacts := make([]*model.Artifact, 0, 2*numPairs)
+	for i := 0; i < numPairs; i++ {
+		prefix := fmt.Sprintf("p%d_", i)
+		artifacts = append(artifacts,
+			tokenArtifact(prefix+"a", stream(prefix, 0, 400)),
+			tokenArtifact(prefix+"b", stream(prefix, 0, 50)+" "+stream(prefix+"b", 0, 350)),
+		)
+	}
+
+	found := 0
+	for _, pair := range GetLSHPairs(artifacts, "medium", LSHParams{}) {
+		a, b := pair.ArtifactA.AttemptID, pair.ArtifactB.AttemptID
+		if a[:len(a)-1] == b[:len(b)-1] {
+			found++
+		}
+	}
+	if recall := float64(found) / numPairs; recall < 0.9 {
+		t.Errorf("recall at the worthy overlap = %.2f, want >= 0.90", recall)
+	}
+}
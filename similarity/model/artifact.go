@@ -0,0 +1,108 @@
+// Package model holds the data types the similarity engine reads and produces. The
+// service persists them as they are, so they carry both JSON and BSON tags; the JSON
+// layout of artifacts is Astra's, so preprocessing output decodes into them directly.
+package model
+
+import "time"
+
+// Artifact represents a preprocessed submission for one question
+type Artifact struct {
+	Email                  string        `bson:"email" json:"email"`
+	AttemptID              string        `bson:"attemptID" json:"attemptID"`
+	TestID                 string        `bson:"testId" json:"testId"`
+	DriveID                string        `bson:"driveId" json:"driveId"`
+	Difficulty             string        `bson:"difficulty" json:"difficulty"`
+	SourceCode             string        `bson:"sourceCode" json:"sourceCode"`
+	QID                    int64         `bson:"qId" json:"qId"`
+	Language               string        `bson:"language" json:"language"`
+	LangCode               string        `bson:"langCode" json:"langCode"`
+	Tokens                 []string      `bson:"tokens" json:"tokens"`
+	NormalizedTokens       []string      `bson:"normalizedTokens" json:"normalizedTokens"`
+	AST                    *ASTNode      `bson:"ast" json:"ast"`
+	CFG                    *CFG          `bson:"cfg" json:"cfg"`
+	Fingerprints           *Fingerprints `bson:"fingerprints" json:"fingerprints"`
+	NormalizedFingerprints *Fingerprints `bson:"normalizedFingerprints,omitempty" json:"normalizedFingerprints,omitempty"` // winnowed after FingerprintPasses; used for candidate generation
+	FingerprintPasses      []string      `bson:"fingerprintPasses,omitempty" json:"fingerprintPasses,omitempty"`
+	SubmittedAt            time.Time     `bson:"submittedAt" json:"submittedAt"`                 // zero when unknown
+	AISignal               *AISignal     `bson:"ai_signal,omitempty" json:"ai_signal,omitempty"` // computed at ingest
+	CreatedAt              time.Time     `bson:"createdAt" json:"createdAt"`
+}
+
+// IndexedFingerprints returns the fingerprints candidate generation matches on: the
+// normalized ones when normalization passes are enabled, the stored ones otherwise
+func (a *Artifact) IndexedFingerprints() *Fingerprints {
+	if a.NormalizedFingerprints != nil {
+		return a.NormalizedFingerprints
+	}
+	return a.Fingerprints
+}
+
+// AISignal estimates how likely an artifact is LLM-generated. It is independent of the
+// pairwise plagiarism risk: candidates using different generations rarely match each other.
+type AISignal struct {
+	Score               float64 `bson:"score" json:"score"`
+	Level               string  `bson:"level" json:"level"`                       // low, medium, high
+	StyleUniformity     float64 `bson:"style_uniformity" json:"style_uniformity"` // consistent indentation, operator spacing, line endings
+	CommentPattern      float64 `bson:"comment_pattern" json:"comment_pattern"`   // comment density and prose-like comments
+	IdentifierEntropy   float64 `bson:"identifier_entropy" json:"identifier_entropy"`
+	ReferenceSimilarity float64 `bson:"reference_similarity" json:"reference_similarity"` // best match against known generated solutions
+	ReferenceID         string  `bson:"reference_id,omitempty" json:"reference_id,omitempty"`
+}
+
+// ASTNode represents an AST node
+type ASTNode struct {
+	Type       string                   `json:"type"`
+	Name       string                   `json:"name,omitempty"`
+	Modifiers  []string                 `json:"modifiers,omitempty"`
+	ReturnType string                   `json:"returnType,omitempty"`
+	Parameters []*Parameter             `json:"parameters,omitempty"`
+	Body       map[string]interface{}   `json:"body,omitempty"`
+	Children   []*ASTNode               `json:"children,omitempty"`
+	Expression map[string]interface{}   `json:"expression,omitempty"`
+	Operator   string                   `json:"operator,omitempty"`
+	Left       map[string]interface{}   `json:"left,omitempty"`
+	Right      map[string]interface{}   `json:"right,omitempty"`
+	Statements []map[string]interface{} `json:"statements,omitempty"`
+}
+
+// Parameter represents a function parameter
+type Parameter struct {
+	Type      string `json:"type"`
+	ParamType string `json:"paramType"`
+	Name      string `json:"name"`
+}
+
+// CFG represents a Control Flow Graph
+type CFG struct {
+	Nodes []*CFGNode `json:"nodes"`
+	Edges []*CFGEdge `json:"edges"`
+}
+
+// CFGNode represents a CFG node
+type CFGNode struct {
+	ID         string `json:"id"`
+	Type       string `json:"type"`
+	Label      string `json:"label"`
+	LineNumber int    `json:"lineNumber,omitempty"`
+}
+
+// CFGEdge represents a CFG edge
+type CFGEdge struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+	Type string `json:"type"`
+}
+
+// Fingerprints represents fingerprint data
+type Fingerprints struct {
+	Method     string      `json:"method"`
+	KGramSize  int         `json:"kGramSize"`
+	WindowSize int         `json:"windowSize"`
+	Hashes     []HashEntry `json:"hashes"`
+}
+
+// HashEntry represents a single hash entry
+type HashEntry struct {
+	Hash     string `json:"hash"`
+	Position int    `json:"position"`
+}
@@ -0,0 +1,98 @@
+package model
+
+// LayerScores holds the per-layer scores of the cascade for a pair
+type LayerScores struct {
+	Fingerprint float64 `bson:"fingerprint" json:"fingerprint"`
+	Token       float64 `bson:"token" json:"token"`
+	AST         float64 `bson:"ast" json:"ast"`
+	CFG         float64 `bson:"cfg" json:"cfg"`
+}
+
+// Containment holds both directions of a layer's overlap
+// AInB is the share of A found in B, BInA the share of B found in A
+type Containment struct {
+	AInB float64 `bson:"a_in_b" json:"a_in_b"`
+	BInA float64 `bson:"b_in_a" json:"b_in_a"`
+}
+
+// LayerContainment holds containment in both directions for every cascade layer
+type LayerContainment struct {
+	Fingerprint Containment `bson:"fingerprint" json:"fingerprint"`
+	Token       Containment `bson:"token" json:"token"`
+	AST         Containment `bson:"ast" json:"ast"`
+	CFG         Containment `bson:"cfg" json:"cfg"`
+}
+
+// FunctionMatch represents a matching function pair between two artifacts
+type FunctionMatch struct {
+	FunctionA   string  `bson:"function_a" json:"function_a"`
+	FunctionB   string  `bson:"function_b" json:"function_b"`
+	Containment float64 `bson:"containment" json:"containment"`
+	TokenScore  float64 `bson:"token_score" json:"token_score"`
+	Score       float64 `bson:"score" json:"score"`
+}
+
+// Calibration places a pair's score within the score distribution of its bucket
+// (same question and language)
+type Calibration struct {
+	ZScore       float64 `bson:"z_score" json:"z_score"`       // robust z-score against the bucket median and MAD
+	Percentile   float64 `bson:"percentile" json:"percentile"` // share of bucket scores at or below this one, 0..1
+	Outlier      bool    `bson:"outlier" json:"outlier"`       // flagged against the bucket baseline
+	BucketSize   int     `bson:"bucket_size" json:"bucket_size"`
+	BucketMedian float64 `bson:"bucket_median" json:"bucket_median"`
+	BucketMAD    float64 `bson:"bucket_mad" json:"bucket_mad"`
+}
+
+// Cluster represents a group of candidates connected by significant pairs (a collusion ring)
+type Cluster struct {
+	ClusterID    string     `bson:"clusterId" json:"clusterId"`
+	Members      []string   `bson:"members" json:"members"` // attemptIds
+	MemberEmails []string   `bson:"member_emails" json:"member_emails"`
+	Questions    []string   `bson:"questions" json:"questions"`
+	AverageScore float64    `bson:"avg_score" json:"avg_score"`
+	Density      float64    `bson:"density" json:"density"`
+	Shape        string     `bson:"shape" json:"shape"`                 // clique, star, mixed
+	LikelySource string     `bson:"likely_source" json:"likely_source"` // attemptId
+	Communities  [][]string `bson:"communities" json:"communities"`
+}
+
+// RiskExplanation records how a candidate's score and risk level were reached:
+// the aggregate of their strongest significant pairs, plus a boost for every
+// distinct peer beyond the first
+type RiskExplanation struct {
+	Score              float64            `bson:"score" json:"score"`
+	Strategy           string             `bson:"strategy" json:"strategy"` // top_k_mean, max, per_question_weighted, noisy_or
+	K                  int                `bson:"k" json:"k"`               // number of pairs the score was computed from
+	TopPairs           []ExplainedPair    `bson:"top_pairs" json:"top_pairs"`
+	BaseScore          float64            `bson:"base_score" json:"base_score"`         // strategy's score before the boost
+	DistinctPeers      int                `bson:"distinct_peers" json:"distinct_peers"` // M
+	Boost              float64            `bson:"boost" json:"boost"`
+	QuestionMaxima     map[string]float64 `bson:"question_maxima" json:"question_maxima"`         // qId -> highest significant pair score
+	LayerContributions LayerScores        `bson:"layer_contributions" json:"layer_contributions"` // mean weighted layer scores of the top pairs
+	ThresholdsCrossed  []string           `bson:"thresholds_crossed" json:"thresholds_crossed"`
+}
+
+// ExplainedPair is one of the pairs a candidate's score was computed from
+type ExplainedPair struct {
+	QID            string      `bson:"qId" json:"qId"`
+	PeerAttemptID  string      `bson:"peer_attempt_id" json:"peer_attempt_id"`
+	PeerEmail      string      `bson:"peer_email" json:"peer_email"`
+	FinalScore     float64     `bson:"final_score" json:"final_score"`
+	Scores         LayerScores `bson:"scores" json:"scores"`
+	Contributions  LayerScores `bson:"contributions" json:"contributions"`     // layer score times its difficulty weight
+	FunctionLifted bool        `bson:"function_lifted" json:"function_lifted"` // FinalScore comes from the best function match
+	ZScore         *float64    `bson:"z_score,omitempty" json:"z_score,omitempty"`
+}
+
+// TestRiskComponents holds the numeric test risk and the values it was computed from
+type TestRiskComponents struct {
+	Score            float64 `bson:"score" json:"score"`
+	Level            string  `bson:"level" json:"level"`
+	Threshold        float64 `bson:"threshold" json:"threshold"` // question/difficulty-adjusted average similarity of a fully suspicious test
+	AvgSimilarity    float64 `bson:"avg_similarity" json:"avg_similarity"`
+	SimilarityFactor float64 `bson:"similarity_factor" json:"similarity_factor"` // min(1, avg_similarity / threshold)
+	AvgDifficulty    float64 `bson:"avg_difficulty" json:"avg_difficulty"`
+	TotalQuestions   int     `bson:"total_questions" json:"total_questions"`
+	FlaggedQuestions int     `bson:"flagged_questions" json:"flagged_questions"`
+	FlaggedRatio     float64 `bson:"flagged_ratio" json:"flagged_ratio"`
+}
@@ -0,0 +1,460 @@
+package similarity
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/RishiKendai/aegis/similarity/model"
+)
+
+// NormalizationPass is a canonicalisation applied to an artifact's AST and
+// normalized tokens before matching, so common obfuscations do not hide copying
+type NormalizationPass string
+
+const (
+	// PassLoops maps every loop form (for, while, do, foreach) to one canonical loop
+	PassLoops NormalizationPass = "loops"
+	// PassCommutative orders the operands of commutative operators (+, *, ==, &&, ...)
+	PassCommutative NormalizationPass = "commutative"
+	// PassDeadCode drops unreachable statements after return/break/continue/throw and no-op statements
+	PassDeadCode NormalizationPass = "dead_code"
+	// PassDeclarationOrder sorts runs of adjacent declarations (variables, functions, classes, imports)
+	PassDeclarationOrder NormalizationPass = "declaration_order"
+)
+
+// AllNormalizationPasses lists every pass in the order they are applied
+var AllNormalizationPasses = []NormalizationPass{PassDeadCode, PassLoops, PassCommutative, PassDeclarationOrder}
+
+// ParseNormalizationPasses parses a comma-separated list of passes.
+// "all" selects every pass and "none" (or an empty string) selects none.
+func ParseNormalizationPasses(value string) ([]NormalizationPass, error) {
+	value = strings.TrimSpace(value)
+	switch value {
+	case "", "none":
+		return []NormalizationPass{}, nil
+	case "all":
+		return append([]NormalizationPass{}, AllNormalizationPasses...), nil
+	}
+
+	selected := make(map[NormalizationPass]bool)
+	for _, name := range strings.Split(value, ",") {
+		pass := NormalizationPass(strings.TrimSpace(name))
+		if !isNormalizationPass(pass) {
+			return nil, fmt.Errorf("unknown normalization pass %q", pass)
+		}
+		selected[pass] = true
+	}
+
+	// Keep the canonical application order regardless of how they were listed
+	passes := make([]NormalizationPass, 0, len(selected))
+	for _, pass := range AllNormalizationPasses {
+		if selected[pass] {
+			passes = append(passes, pass)
+		}
+	}
+	return passes, nil
+}
+
+func isNormalizationPass(pass NormalizationPass) bool {
+	for _, known := range AllNormalizationPasses {
+		if pass == known {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizationResult records which passes changed something
+type normalizationResult map[NormalizationPass]bool
+
+// list returns the applied passes in application order
+func (r normalizationResult) list() []NormalizationPass {
+	applied := make([]NormalizationPass, 0, len(r))
+	for _, pass := range AllNormalizationPasses {
+		if r[pass] {
+			applied = append(applied, pass)
+		}
+	}
+	return applied
+}
+
+// NormalizeAST returns a canonicalised copy of the AST and the passes that changed it.
+// The input tree is not modified.
+func NormalizeAST(root *model.ASTNode, passes []NormalizationPass) (*model.ASTNode, []NormalizationPass) {
+	if root == nil || len(passes) == 0 {
+		return root, nil
+	}
+
+	enabled := passSet(passes)
+	applied := make(normalizationResult)
+	normalized := copyAST(root)
+	normalizeNode(normalized, enabled, applied)
+
+	return normalized, applied.list()
+}
+
+// copyAST deep-copies the node tree, including the operand and expression maps the
+// commutative pass reorders; bodies and statements are shared since passes never touch them
+func copyAST(node *model.ASTNode) *model.ASTNode {
+	if node == nil {
+		return nil
+	}
+	copied := *node
+	copied.Left = copyMap(node.Left)
+	copied.Right = copyMap(node.Right)
+	copied.Expression = copyMap(node.Expression)
+	if node.Children != nil {
+		copied.Children = make([]*model.ASTNode, len(node.Children))
+		for i, child := range node.Children {
+			copied.Children[i] = copyAST(child)
+		}
+	}
+	return &copied
+}
+
+func copyMap(value map[string]interface{}) map[string]interface{} {
+	if value == nil {
+		return nil
+	}
+	copied := make(map[string]interface{}, len(value))
+	for key, item := range value {
+		copied[key] = copyValue(item)
+	}
+	return copied
+}
+
+func copyValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return copyMap(v)
+	case []interface{}:
+		copied := make([]interface{}, len(v))
+		for i, item := range v {
+			copied[i] = copyValue(item)
+		}
+		return copied
+	default:
+		return value
+	}
+}
+
+// normalizeNode applies the enabled passes bottom-up and returns the node's Merkle hash,
+// which is used to order reorderable children deterministically
+func normalizeNode(node *model.ASTNode, enabled map[NormalizationPass]bool, applied normalizationResult) string {
+	if node == nil {
+		return ""
+	}
+
+	if enabled[PassDeadCode] && isStatementList(node) {
+		if kept := dropDeadStatements(node.Children); len(kept) != len(node.Children) {
+			node.Children = kept
+			applied[PassDeadCode] = true
+		}
+	}
+
+	childHashes := make(map[*model.ASTNode]string, len(node.Children))
+	for _, child := range node.Children {
+		childHashes[child] = normalizeNode(child, enabled, applied)
+	}
+	byHash := func(nodes []*model.ASTNode) func(i, j int) bool {
+		return func(i, j int) bool { return childHashes[nodes[i]] < childHashes[nodes[j]] }
+	}
+
+	if enabled[PassLoops] && neutralCategory(node.Type) == "LOOP" && node.Type != canonicalLoopType {
+		node.Type = canonicalLoopType
+		applied[PassLoops] = true
+	}
+
+	if enabled[PassCommutative] {
+		// Astra keeps binary operands in Left/Right, nested ones as JSON objects
+		swapped := orderOperands(node.Left)
+		swapped = orderOperands(node.Right) || swapped
+		swapped = orderOperands(node.Expression) || swapped
+		if commutativeOperators[node.Operator] && len(node.Left) > 0 && len(node.Right) > 0 &&
+			valueHash(node.Left) > valueHash(node.Right) {
+			node.Left, node.Right = node.Right, node.Left
+			swapped = true
+		}
+		if commutativeOperators[node.Operator] && len(node.Children) == 2 &&
+			!sort.SliceIsSorted(node.Children, byHash(node.Children)) {
+			sort.SliceStable(node.Children, byHash(node.Children))
+			swapped = true
+		}
+		applied[PassCommutative] = applied[PassCommutative] || swapped
+	}
+
+	if enabled[PassDeclarationOrder] && isStatementList(node) {
+		for start := 0; start < len(node.Children); {
+			end := start
+			for end < len(node.Children) && isReorderableDeclaration(node.Children[end]) {
+				end++
+			}
+			if end-start > 1 {
+				run := node.Children[start:end]
+				if !sort.SliceIsSorted(run, byHash(run)) {
+					sort.SliceStable(run, byHash(run))
+					applied[PassDeclarationOrder] = true
+				}
+			}
+			start = end + 1
+		}
+	}
+
+	hashes := make([]string, 0, len(node.Children))
+	for _, child := range node.Children {
+		hashes = append(hashes, childHashes[child])
+	}
+	return computeNodeHash(node, hashes)
+}
+
+// orderOperands orders the left and right operands of every commutative operation in a
+// JSON sub-tree, innermost first, and reports whether any were swapped
+func orderOperands(value interface{}) bool {
+	swapped := false
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for _, item := range v {
+			swapped = orderOperands(item) || swapped
+		}
+		operator, _ := v["operator"].(string)
+		left, leftOK := v["left"].(map[string]interface{})
+		right, rightOK := v["right"].(map[string]interface{})
+		if commutativeOperators[operator] && leftOK && rightOK && valueHash(left) > valueHash(right) {
+			v["left"], v["right"] = right, left
+			swapped = true
+		}
+	case []interface{}:
+		for _, item := range v {
+			swapped = orderOperands(item) || swapped
+		}
+	}
+	return swapped
+}
+
+// valueHash hashes a JSON value; map keys are marshalled in sorted order, so equal values hash equally
+func valueHash(value interface{}) string {
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return computeHash(fmt.Sprint(value))
+	}
+	return computeHash(string(encoded))
+}
+
+// canonicalLoopType replaces every loop node type under PassLoops
+const canonicalLoopType = "Loop"
+
+// commutativeOperators are operators whose operands may be swapped without changing meaning.
+// "+" is left out: it also concatenates strings and lists, where order matters.
+var commutativeOperators = map[string]bool{
+	"*": true, "==": true, "!=": true, "===": true, "!==": true,
+	"&": true, "|": true, "^": true, "&&": true, "||": true, "and": true, "or": true,
+}
+
+// isStatementList reports whether a node's children are a sequence of statements.
+// Switch, case and match containers are excluded: their children are arms, and a jump
+// ends only the arm it belongs to.
+func isStatementList(node *model.ASTNode) bool {
+	words := splitTypeWords(node.Type)
+	for _, word := range words {
+		if switchWords[word] {
+			return false
+		}
+	}
+	for _, word := range words {
+		switch word {
+		case "block", "body", "module", "program", "suite", "compound", "statements", "unit":
+			return true
+		}
+	}
+	return false
+}
+
+// switchWords mark switch/case containers and arm labels
+var switchWords = map[string]bool{"switch": true, "case": true, "default": true, "match": true, "when": true, "arm": true}
+
+// dropDeadStatements removes no-op statements and everything after an unconditional jump.
+// A case or default label makes the statements after it reachable again, which covers
+// languages whose switch body is an ordinary compound statement.
+func dropDeadStatements(children []*model.ASTNode) []*model.ASTNode {
+	kept := make([]*model.ASTNode, 0, len(children))
+	unreachable := false
+	for _, child := range children {
+		if unreachable && !isCaseLabel(child) {
+			continue
+		}
+		unreachable = false
+		if isNoOpStatement(child) {
+			continue
+		}
+		kept = append(kept, child)
+		if isJumpStatement(child) {
+			unreachable = true
+		}
+	}
+	return kept
+}
+
+func isCaseLabel(node *model.ASTNode) bool {
+	if node == nil {
+		return false
+	}
+	for _, word := range splitTypeWords(node.Type) {
+		if switchWords[word] || word == "label" || word == "labeled" {
+			return true
+		}
+	}
+	return false
+}
+
+func isJumpStatement(node *model.ASTNode) bool {
+	if node == nil {
+		return false
+	}
+	for _, word := range splitTypeWords(node.Type) {
+		switch word {
+		case "return", "break", "continue", "throw", "raise":
+			return true
+		}
+	}
+	return false
+}
+
+// noOpStatementTypes are the node types parsers emit for statements that do nothing
+var noOpStatementTypes = map[string]bool{
+	"Pass": true, "PassStatement": true, "pass_statement": true,
+	"EmptyStatement": true, "empty_statement": true, "EmptyStmt": true, "empty_stmt": true,
+}
+
+func isNoOpStatement(node *model.ASTNode) bool {
+	if node == nil {
+		return true
+	}
+	return len(node.Children) == 0 && noOpStatementTypes[node.Type]
+}
+
+// isReorderableDeclaration reports whether a statement declares something whose
+// position among adjacent declarations does not matter
+func isReorderableDeclaration(node *model.ASTNode) bool {
+	if node == nil {
+		return false
+	}
+	switch neutralCategory(node.Type) {
+	case "DECL", "FUNC", "CLASS":
+		return true
+	}
+	for _, word := range splitTypeWords(node.Type) {
+		if word == "import" || word == "include" {
+			return true
+		}
+	}
+	return false
+}
+
+// NormalizeTokens returns a canonicalised copy of a normalized token stream and the
+// passes that changed it. Declaration reordering needs structure and is AST-only.
+func NormalizeTokens(tokens []string, passes []NormalizationPass) ([]string, []NormalizationPass) {
+	if len(tokens) == 0 || len(passes) == 0 {
+		return tokens, nil
+	}
+
+	enabled := passSet(passes)
+	applied := make(normalizationResult)
+	normalized := make([]string, 0, len(tokens))
+
+	for _, token := range tokens {
+		if enabled[PassDeadCode] {
+			// pass statements and empty statements (repeated semicolons) do nothing
+			if strings.ToLower(token) == "pass" ||
+				(token == ";" && len(normalized) > 0 && normalized[len(normalized)-1] == ";") {
+				applied[PassDeadCode] = true
+				continue
+			}
+		}
+		if enabled[PassLoops] && loopKeywords[strings.ToLower(token)] {
+			if token != canonicalLoopToken {
+				applied[PassLoops] = true
+			}
+			token = canonicalLoopToken
+		}
+		normalized = append(normalized, token)
+	}
+
+	if enabled[PassCommutative] {
+		// Swap atom operands of an isolated commutative operation: "NUM + ID" -> "ID + NUM".
+		// Operands bound more tightly by a neighbouring operator are left alone.
+		for i := 1; i+1 < len(normalized); i++ {
+			if !commutativeOperators[normalized[i]] || !isAtomToken(normalized[i-1]) || !isAtomToken(normalized[i+1]) {
+				continue
+			}
+			if i >= 2 && bindsRight[normalized[i-2]] {
+				continue
+			}
+			if i+2 < len(normalized) && bindsLeft[normalized[i+2]] {
+				continue
+			}
+			if normalized[i-1] > normalized[i+1] {
+				normalized[i-1], normalized[i+1] = normalized[i+1], normalized[i-1]
+				applied[PassCommutative] = true
+			}
+		}
+	}
+
+	return normalized, applied.list()
+}
+
+// canonicalLoopToken replaces every loop keyword under PassLoops
+const canonicalLoopToken = "loop"
+
+var loopKeywords = map[string]bool{"for": true, "foreach": true, "while": true, "do": true, "loop": true}
+
+// bindingOperators bind an operand more tightly than (or as tightly as) a commutative operator
+var bindingOperators = []string{
+	"+", "-", "*", "/", "%", "**", "//", "==", "!=", "===", "!==", "<", ">", "<=", ">=",
+	"&", "|", "^", "&&", "||", "<<", ">>", "!", "~", "and", "or", "not", "in", "is",
+	".", "->", "::", "?.",
+}
+
+// bindsRight holds tokens that claim the operand on their right
+var bindsRight = toTokenSet(bindingOperators)
+
+// bindsLeft holds tokens that claim the operand on their left (including calls and indexing)
+var bindsLeft = toTokenSet(append([]string{"(", "["}, bindingOperators...))
+
+func toTokenSet(tokens []string) map[string]bool {
+	set := make(map[string]bool, len(tokens))
+	for _, token := range tokens {
+		set[token] = true
+	}
+	return set
+}
+
+// isAtomToken reports whether a token is a single operand (identifier, number or literal)
+func isAtomToken(token string) bool {
+	if token == "" || commutativeOperators[token] || bindsLeft[token] || loopKeywords[token] {
+		return false
+	}
+	r := token[0]
+	return r == '_' || r == '"' || r == '\'' || r == '`' ||
+		(r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9')
+}
+
+func passSet(passes []NormalizationPass) map[NormalizationPass]bool {
+	enabled := make(map[NormalizationPass]bool, len(passes))
+	for _, pass := range passes {
+		enabled[pass] = true
+	}
+	return enabled
+}
+
+// mergePasses returns the union of applied passes in application order
+func mergePasses(lists ...[]NormalizationPass) []NormalizationPass {
+	merged := make(normalizationResult)
+	for _, list := range lists {
+		for _, pass := range list {
+			merged[pass] = true
+		}
+	}
+	return merged.list()
+}
@@ -0,0 +1,95 @@
+package similarity
+
+import (
+	"testing"
+
+	"github.com/RishiKendai/aegis/similarity/model"
+)
+
+func nodeTypes(nodes []*model.ASTNode) []string {
+	types := make([]string, len(nodes))
+	for i, node := range nodes {
+		types[i] = node.Type
+	}
+	return types
+}
+
+func TestNormalizeASTDeadCode(t *testing.T) {
+	caseArm := func(label string) *model.ASTNode {
+		return &model.ASTNode{Type: "SwitchCase", Name: label, Children: []*model.ASTNode{
+			{Type: "ExpressionStatement", Name: label},
+			{Type: "BreakStatement"},
+			{Type: "ExpressionStatement", Name: "dead"},
+		}}
+	}
+
+	tests := []struct {
+		name string
+		root *model.ASTNode
+		want []string
+	}{
+		{
+			name: "multi-case switch keeps every case",
+			root: &model.ASTNode{Type: "SwitchStatement", Children: []*model.ASTNode{
+				caseArm("a"), caseArm("b"), {Type: "SwitchDefault", Children: []*model.ASTNode{{Type: "ReturnStatement"}}},
+			}},
+			want: []string{"SwitchCase", "SwitchCase", "SwitchDefault"},
+		},
+		{
+			name: "compound switch body resumes at case labels",
+			root: &model.ASTNode{Type: "compound_statement", Children: []*model.ASTNode{
+				{Type: "case_statement"}, {Type: "break_statement"}, {Type: "expression_statement"},
+				{Type: "case_statement"}, {Type: "break_statement"},
+			}},
+			want: []string{"case_statement", "break_statement", "case_statement", "break_statement"},
+		},
+		{
+			name: "block drops statements after return and no-ops",
+			root: &model.ASTNode{Type: "BlockStatement", Children: []*model.ASTNode{
+				{Type: "EmptyStatement"}, {Type: "ReturnStatement"}, {Type: "ExpressionStatement"},
+			}},
+			want: []string{"ReturnStatement"},
+		},
+		{
+			name: "only exact no-op types are dropped",
+			root: &model.ASTNode{Type: "BlockStatement", Children: []*model.ASTNode{
+				{Type: "EmptyListLiteral"}, {Type: "pass_statement"},
+			}},
+			want: []string{"EmptyListLiteral"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			normalized, _ := NormalizeAST(tt.root, []NormalizationPass{PassDeadCode})
+			got := nodeTypes(normalized.Children)
+			if len(got) != len(tt.want) {
+				t.Fatalf("children = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("children = %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestNormalizeTokensCommutative(t *testing.T) {
+	tests := []struct {
+		tokens []string
+		want   []string
+	}{
+		{tokens: []string{"b", "*", "a"}, want: []string{"a", "*", "b"}},
+		{tokens: []string{"\"x\"", "+", "\"a\""}, want: []string{"\"x\"", "+", "\"a\""}},
+	}
+	for _, tt := range tests {
+		got, _ := NormalizeTokens(tt.tokens, []NormalizationPass{PassCommutative})
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("NormalizeTokens(%v) = %v, want %v", tt.tokens, got, tt.want)
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package similarity
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// LayerThresholds holds the short-circuit threshold checked after each cascade layer
+type LayerThresholds struct {
+	Fingerprint float64
+	Token       float64
+	AST         float64
+}
+
+// Profile is a scoring profile: the layer weights and short-circuit thresholds
+// the cascade uses for a pair, the normalization passes run before matching and
+// how a candidate's pairs are aggregated into a candidate score
+type Profile struct {
+	Name          string
+	Weights       Weights
+	Thresholds    LayerThresholds
+	Normalization []NormalizationPass
+	Aggregation   AggregationStrategy
+}
+
+// DifficultyProfile returns the built-in profile for a question difficulty
+// ("easy", "medium", "hard"; anything else is treated as medium)
+func DifficultyProfile(difficulty string) Profile {
+	return Profile{
+		Name:    difficulty,
+		Weights: getWeights(difficulty),
+		Thresholds: LayerThresholds{
+			Fingerprint: getThreshold(difficulty, "fingerprint"),
+			Token:       getThreshold(difficulty, "token"),
+			AST:         getThreshold(difficulty, "ast"),
+		},
+		Normalization: append([]NormalizationPass{}, AllNormalizationPasses...),
+		Aggregation:   DefaultAggregationStrategy,
+	}
+}
+
+// LoadProfiles reads a JSON array of profiles, as written by "aegis tune -out"
+func LoadProfiles(path string) ([]Profile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read profile file: %w", err)
+	}
+	var profiles []Profile
+	if err := json.Unmarshal(data, &profiles); err != nil {
+		return nil, fmt.Errorf("failed to parse profile file: %w", err)
+	}
+	return profiles, nil
+}
+
+// LexicalOnly returns a copy of the profile for artifacts without AST and CFG
+// (e.g. preprocessed offline): the structural weights are moved onto the
+// fingerprint and token layers in proportion, so full matches still score 1.0
+func (p Profile) LexicalOnly() Profile {
+	lexical := p.Weights.Fingerprint + p.Weights.Token
+	if lexical == 0 {
+		return p
+	}
+
+	restricted := p
+	restricted.Name = p.Name + "-lexical"
+	restricted.Weights = Weights{
+		Fingerprint: p.Weights.Fingerprint / lexical,
+		Token:       p.Weights.Token / lexical,
+	}
+	return restricted
+}
@@ -0,0 +1,171 @@
+package similarity
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/RishiKendai/aegis/similarity/model"
+)
+
+const (
+	SignificantSimilarityThreshold = 0.55
+
+	AlgorithmicSimilarityThreshold = 0.70
+
+	// Risk level constants for candidate results (standardized format)
+	RiskClean            = "clean"
+	RiskSuspicious       = "suspicious"
+	RiskHighlySuspicious = "highly_suspicious"
+	RiskNearCopy         = "near_copy"
+
+	// Risk level constants for test reports (standardized format)
+	TestRiskSafe     = "safe"
+	TestRiskModerate = "moderate"
+	TestRiskHigh     = "high"
+	TestRiskCritical = "critical"
+)
+
+// PairSimilarity represents similarity between a pair of artifacts
+type PairSimilarity struct {
+	ArtifactA        *model.Artifact
+	ArtifactB        *model.Artifact
+	FinalScore       float64
+	Scores           SimilarityScores
+	Weights          *Weights // layer weights the cascade scored with; nil when unknown
+	Containment      model.LayerContainment
+	Direction        *CopyDirection
+	FunctionMatches  []model.FunctionMatch
+	CrossLanguage    bool
+	CrossDrive       bool                // ArtifactB belongs to another drive (corpus mode)
+	EstimatedJaccard float64             // MinHash estimate when the pair came from LSH
+	Normalization    []NormalizationPass // normalization passes that changed either artifact
+	Calibration      *model.Calibration  // position within the bucket's scores; nil when uncalibrated
+	QID              string
+	Language         string
+	Difficulty       string
+}
+
+const (
+	// Candidate score thresholds of the risk levels
+	RiskSuspiciousThreshold       = 0.30
+	RiskHighlySuspiciousThreshold = 0.60
+	RiskNearCopyThreshold         = 0.85
+)
+
+// CandidateScore scores a candidate from their pairs with the default strategy;
+// attemptID picks the candidate's side of each pair
+func CandidateScore(pairs []PairSimilarity, attemptID string) float64 {
+	return AggregateCandidate(pairs, attemptID, DefaultAggregationStrategy).Score
+}
+
+// GetRiskLevel returns risk level based on candidate score
+func GetRiskLevel(score float64) string {
+	if score < RiskSuspiciousThreshold {
+		return RiskClean
+	} else if score < RiskHighlySuspiciousThreshold {
+		return RiskSuspicious
+	} else if score < RiskNearCopyThreshold {
+		return RiskHighlySuspicious
+	}
+	return RiskNearCopy
+}
+
+// TestRiskThresholds are the lower bounds of the test risk levels
+type TestRiskThresholds struct {
+	Safe     float64
+	Moderate float64
+	High     float64
+	Critical float64
+}
+
+// DefaultTestRiskThresholds returns the built-in test risk cutoffs
+func DefaultTestRiskThresholds() TestRiskThresholds {
+	return TestRiskThresholds{
+		Safe:     0.0,
+		Moderate: 0.40,
+		High:     0.60,
+		Critical: 0.80,
+	}
+}
+
+// Level returns the test risk level of a numeric test risk
+func (t TestRiskThresholds) Level(risk float64) string {
+	if t == (TestRiskThresholds{}) {
+		t = DefaultTestRiskThresholds()
+	}
+
+	switch {
+	case risk >= t.Critical:
+		return TestRiskCritical
+	case risk >= t.High:
+		return TestRiskHigh
+	case risk >= t.Moderate:
+		return TestRiskModerate
+	default:
+		return TestRiskSafe
+	}
+}
+
+// Validate checks the cutoffs are ordered and within [0, 1]
+func (t TestRiskThresholds) Validate() error {
+	if t.Safe < 0 || t.Critical > 1 {
+		return fmt.Errorf("test risk thresholds must be within [0, 1]")
+	}
+	if t.Safe > t.Moderate || t.Moderate > t.High || t.High > t.Critical {
+		return fmt.Errorf("test risk thresholds must be ordered safe <= moderate <= high <= critical")
+	}
+	return nil
+}
+
+// TestRisk calculates test risk using the formula
+//
+//	threshold = clamp(0.70 · 1/√Q · (0.5 + D), 0.35, 0.85)
+//	risk      = 0.7 · min(1, S / threshold) + 0.3 · R/Q
+//
+// where S is the average pair similarity, R the flagged questions out of Q and D the
+// average difficulty. The threshold is the average similarity at which a test is
+// fully suspicious: lower with more questions, higher on hard ones.
+func TestRisk(totalQuestions int, avgDifficulty float64, avgSimilarity float64, flaggedQuestions int, thresholds TestRiskThresholds) *model.TestRiskComponents {
+	Q := float64(max(1, totalQuestions))
+	D := avgDifficulty // 0..1 (EASY=0.33, MEDIUM=0.66, HARD=1.0)
+	BASE := 0.70
+
+	// Calculate threshold
+	questionFactor := 1.0 / math.Sqrt(Q)
+	difficultyFactor := 0.5 + D
+	threshold := BASE * questionFactor * difficultyFactor
+	threshold = math.Max(0.35, math.Min(0.85, threshold)) // Clamp [0.35, 0.85]
+
+	// Calculate risk
+	S := avgSimilarity
+	R := float64(flaggedQuestions)
+	similarityFactor := math.Min(1.0, S/threshold)
+	flaggedRatio := R / Q
+	risk := (0.7 * similarityFactor) + (0.3 * flaggedRatio)
+
+	return &model.TestRiskComponents{
+		Score:            risk,
+		Level:            thresholds.Level(risk),
+		Threshold:        threshold,
+		AvgSimilarity:    S,
+		SimilarityFactor: similarityFactor,
+		AvgDifficulty:    D,
+		TotalQuestions:   totalQuestions,
+		FlaggedQuestions: flaggedQuestions,
+		FlaggedRatio:     flaggedRatio,
+	}
+}
+
+// DifficultyToFloat converts difficulty string to float (0..1)
+func DifficultyToFloat(difficulty string) float64 {
+	switch difficulty {
+	case "easy":
+		return 0.33
+	case "medium":
+		return 0.66
+	case "hard":
+		return 1.0
+	default:
+		return 0.5 // Default to medium
+	}
+}
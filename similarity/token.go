@@ -0,0 +1,116 @@
+package similarity
+
+import (
+	"github.com/RishiKendai/aegis/similarity/model"
+)
+
+const minLength = 5
+
+// calculate similarity using Greedy String Tiling (GST)
+func TokenSimilarity(artifactA, artifactB *model.Artifact) float64 {
+	score, _ := tokenScores(NewArtifactFeatures(artifactA), NewArtifactFeatures(artifactB))
+	return score
+}
+
+// tokenScores returns the symmetric GST score and both containment directions
+func tokenScores(featuresA, featuresB *ArtifactFeatures) (float64, model.Containment) {
+	tokensA := featuresA.Tokens()
+	tokensB := featuresB.Tokens()
+
+	if len(tokensA) == 0 || len(tokensB) == 0 {
+		return 0.0, model.Containment{}
+	}
+
+	// Find maximal common token substrings (min length ≥ 5)
+	matchedTokens := greedyStringTiling(tokensA, tokensB, minLength)
+
+	// TokenScore = 2 * matched_tokens / (lenA + lenB)
+	totalLen := len(tokensA) + len(tokensB)
+
+	containment := model.Containment{
+		AInB: float64(matchedTokens) / float64(len(tokensA)),
+		BInA: float64(matchedTokens) / float64(len(tokensB)),
+	}
+
+	return 2.0 * float64(matchedTokens) / float64(totalLen), containment
+}
+
+// Tile is a maximal common run of tokens found by Greedy String Tiling,
+// given as start offsets into both token streams
+type Tile struct {
+	StartA int
+	StartB int
+	Length int
+}
+
+// MatchedTiles returns the common token runs of two normalized token streams,
+// longest first, using the same minimum run length as the token layer
+func MatchedTiles(tokensA, tokensB []string) []Tile {
+	return greedyStringTiles(tokensA, tokensB, minLength)
+}
+
+// Greedy String Tiling algorithm
+func greedyStringTiling(tokensA, tokensB []string, minLength int) int {
+	totalMatched := 0
+	for _, tile := range greedyStringTiles(tokensA, tokensB, minLength) {
+		totalMatched += tile.Length
+	}
+	return totalMatched
+}
+
+// greedyStringTiles repeatedly marks the longest unmarked common substring
+func greedyStringTiles(tokensA, tokensB []string, minLength int) []Tile {
+	matched := make([]bool, len(tokensA))
+	matchedB := make([]bool, len(tokensB))
+	tiles := make([]Tile, 0)
+
+	for {
+		maxMatch := 0
+		maxStartA := -1
+		maxStartB := -1
+
+		// Find maximal common substring
+		for i := 0; i < len(tokensA); i++ {
+			if matched[i] {
+				continue
+			}
+
+			for j := 0; j < len(tokensB); j++ {
+				if matchedB[j] {
+					continue
+				}
+
+				// Try to match from this position
+				matchLen := 0
+				for k := 0; i+k < len(tokensA) && j+k < len(tokensB); k++ {
+					if matched[i+k] || matchedB[j+k] {
+						break
+					}
+					if tokensA[i+k] != tokensB[j+k] {
+						break
+					}
+					matchLen++
+				}
+
+				if matchLen >= minLength && matchLen > maxMatch {
+					maxMatch = matchLen
+					maxStartA = i
+					maxStartB = j
+				}
+			}
+		}
+
+		if maxMatch == 0 {
+			break // No more matches
+		}
+
+		// Mark matched tokens
+		for k := 0; k < maxMatch; k++ {
+			matched[maxStartA+k] = true
+			matchedB[maxStartB+k] = true
+		}
+		tiles = append(tiles, Tile{StartA: maxStartA, StartB: maxStartB, Length: maxMatch})
+	}
+
+	return tiles
+}
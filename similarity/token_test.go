@@ -0,0 +1,66 @@
+package similarity
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestGreedyStringTiling(t *testing.T) {
+	tests := []struct {
+		name    string
+		tokensA string
+		tokensB string
+		matched int
+		tiles   []Tile
+	}{
+		{
+			name:    "identical streams form one tile",
+			tokensA: "a b c d e f",
+			tokensB: "a b c d e f",
+			matched: 6,
+			tiles:   []Tile{{StartA: 0, StartB: 0, Length: 6}},
+		},
+		{
+			name:    "runs shorter than the minimum length are ignored",
+			tokensA: "a b c d x",
+			tokensB: "a b c d y",
+			matched: 0,
+			tiles:   []Tile{},
+		},
+		{
+			name:    "reordered blocks are both found, longest first",
+			tokensA: "a b c d e f g h i j k l",
+			tokensB: "h i j k l a b c d e f g",
+			matched: 12,
+			tiles:   []Tile{{StartA: 0, StartB: 5, Length: 7}, {StartA: 7, StartB: 0, Length: 5}},
+		},
+		{
+			name:    "each token is matched at most once",
+			tokensA: "a b c d e",
+			tokensB: "a b c d e a b c d e",
+			matched: 5,
+			tiles:   []Tile{{StartA: 0, StartB: 0, Length: 5}},
+		},
+		{
+			name:    "empty stream matches nothing",
+			tokensA: "",
+			tokensB: "a b c d e",
+			matched: 0,
+			tiles:   []Tile{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tokensA, tokensB := strings.Fields(tt.tokensA), strings.Fields(tt.tokensB)
+
+			if got := greedyStringTiling(tokensA, tokensB, minLength); got != tt.matched {
+				t.Errorf("greedyStringTiling() = %d, want %d", got, tt.matched)
+			}
+			if got := MatchedTiles(tokensA, tokensB); !reflect.DeepEqual(got, tt.tiles) {
+				t.Errorf("MatchedTiles() = %v, want %v", got, tt.tiles)
+			}
+		})
+	}
+}
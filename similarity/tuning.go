@@ -0,0 +1,269 @@
+package similarity
+
+import (
+	"fmt"
+	"math"
+)
+
+// LabelledPair is a reviewed pair: its layer scores and whether a reviewer confirmed
+// (true) or dismissed (false) it
+type LabelledPair struct {
+	Scores    SimilarityScores
+	Confirmed bool
+}
+
+// TuningParams tunes the logistic regression behind TuneProfile
+type TuningParams struct {
+	Iterations   int     // gradient descent steps
+	LearningRate float64 // step size
+	L2           float64 // ridge penalty on the layer coefficients
+	Folds        int     // cross-validation folds of the after estimate
+	MinPerClass  int     // fewest confirmed and dismissed pairs needed to tune
+}
+
+func (p TuningParams) withDefaults() TuningParams {
+	if p.Iterations <= 0 {
+		p.Iterations = 2000
+	}
+	if p.LearningRate <= 0 {
+		p.LearningRate = 0.5
+	}
+	if p.L2 < 0 {
+		p.L2 = 0
+	}
+	if p.Folds < 2 {
+		p.Folds = 5
+	}
+	if p.MinPerClass <= 0 {
+		p.MinPerClass = 10
+	}
+	return p
+}
+
+// LogisticModel is a fitted P(confirmed) = σ(intercept + Σ coefficient·layer score)
+type LogisticModel struct {
+	Fingerprint float64 `json:"fingerprint"`
+	Token       float64 `json:"token"`
+	AST         float64 `json:"ast"`
+	CFG         float64 `json:"cfg"`
+	Intercept   float64 `json:"intercept"`
+}
+
+// PrecisionEstimate is how a profile's weights and threshold classify reviewed pairs
+type PrecisionEstimate struct {
+	Threshold float64 `json:"threshold"`
+	Precision float64 `json:"precision"`
+	Recall    float64 `json:"recall"`
+	TP        int     `json:"tp"`
+	FP        int     `json:"fp"`
+	TN        int     `json:"tn"`
+	FN        int     `json:"fn"`
+}
+
+// TuningResult is a proposed profile fitted to reviewer verdicts, awaiting approval
+type TuningResult struct {
+	Profile   Profile       `json:"profile"`
+	Model     LogisticModel `json:"model"`
+	Samples   int           `json:"samples"`
+	Confirmed int           `json:"confirmed"`
+	Dismissed int           `json:"dismissed"`
+	// Before scores the pairs with the base profile at SignificantSimilarityThreshold
+	Before PrecisionEstimate `json:"before"`
+	// After scores the pairs with the proposed weights at SignificantSimilarityThreshold,
+	// the threshold the service applies. It is cross-validated: each fold is scored with
+	// weights fitted on the other folds, so it does not reward overfitting.
+	After PrecisionEstimate `json:"after"`
+}
+
+// TuneProfile fits layer weights and short-circuit thresholds to reviewer verdicts by
+// logistic regression over the four layer scores. The positive coefficients, normalised
+// to sum to one, become the weights; each short-circuit threshold is lowered so that no
+// confirmed pair would be cut short at that layer under the new weights. Layers a pair
+// short-circuited before have a score of zero, as stored on the pair record.
+func TuneProfile(base Profile, samples []LabelledPair, params TuningParams) (*TuningResult, error) {
+	params = params.withDefaults()
+
+	result := &TuningResult{Samples: len(samples)}
+	for _, sample := range samples {
+		if sample.Confirmed {
+			result.Confirmed++
+		} else {
+			result.Dismissed++
+		}
+	}
+	if result.Confirmed < params.MinPerClass || result.Dismissed < params.MinPerClass {
+		return nil, fmt.Errorf("need at least %d confirmed and %d dismissed pairs, have %d and %d",
+			params.MinPerClass, params.MinPerClass, result.Confirmed, result.Dismissed)
+	}
+
+	result.Model = fitLogistic(samples, params)
+	weights := weightsFromModel(result.Model, base.Weights)
+
+	result.Profile = base
+	result.Profile.Weights = weights
+	floors := confirmedBounds(samples, weights)
+	result.Profile.Thresholds = LayerThresholds{
+		Fingerprint: math.Min(base.Thresholds.Fingerprint, floors[0]),
+		Token:       math.Min(base.Thresholds.Token, floors[1]),
+		AST:         math.Min(base.Thresholds.AST, floors[2]),
+	}
+
+	result.Before = estimatePrecision(samples, base.Weights, SignificantSimilarityThreshold)
+	result.After = crossValidate(samples, base.Weights, params)
+
+	return result, nil
+}
+
+// fitLogistic fits the model by batch gradient descent
+func fitLogistic(samples []LabelledPair, params TuningParams) LogisticModel {
+	var coefficients [4]float64
+	intercept := 0.0
+	n := float64(len(samples))
+
+	for iteration := 0; iteration < params.Iterations; iteration++ {
+		var gradient [4]float64
+		interceptGradient := 0.0
+		for _, sample := range samples {
+			features := layerVector(sample.Scores)
+			z := intercept
+			for i, feature := range features {
+				z += coefficients[i] * feature
+			}
+			label := 0.0
+			if sample.Confirmed {
+				label = 1.0
+			}
+			residual := 1.0/(1.0+math.Exp(-z)) - label
+			for i, feature := range features {
+				gradient[i] += residual * feature
+			}
+			interceptGradient += residual
+		}
+		for i := range coefficients {
+			coefficients[i] -= params.LearningRate * (gradient[i]/n + params.L2*coefficients[i])
+		}
+		intercept -= params.LearningRate * interceptGradient / n
+	}
+
+	return LogisticModel{
+		Fingerprint: coefficients[0],
+		Token:       coefficients[1],
+		AST:         coefficients[2],
+		CFG:         coefficients[3],
+		Intercept:   intercept,
+	}
+}
+
+// weightsFromModel keeps the layers that raise the odds of a confirmed verdict, in
+// proportion; it falls back to the base weights when none does
+func weightsFromModel(model LogisticModel, fallback Weights) Weights {
+	coefficients := [4]float64{
+		math.Max(0, model.Fingerprint),
+		math.Max(0, model.Token),
+		math.Max(0, model.AST),
+		math.Max(0, model.CFG),
+	}
+	sum := coefficients[0] + coefficients[1] + coefficients[2] + coefficients[3]
+	if sum == 0 {
+		return fallback
+	}
+
+	return Weights{
+		Fingerprint: coefficients[0] / sum,
+		Token:       coefficients[1] / sum,
+		AST:         coefficients[2] / sum,
+		CFG:         coefficients[3] / sum,
+	}
+}
+
+// crossValidate estimates the proposed weights on pairs they were not fitted on, at
+// SignificantSimilarityThreshold
+func crossValidate(samples []LabelledPair, fallback Weights, params TuningParams) PrecisionEstimate {
+	total := PrecisionEstimate{Threshold: SignificantSimilarityThreshold}
+	for fold := 0; fold < params.Folds; fold++ {
+		train := make([]LabelledPair, 0, len(samples))
+		test := make([]LabelledPair, 0, len(samples)/params.Folds+1)
+		for i, sample := range samples {
+			if i%params.Folds == fold {
+				test = append(test, sample)
+			} else {
+				train = append(train, sample)
+			}
+		}
+		if len(test) == 0 || len(train) == 0 {
+			continue
+		}
+
+		weights := weightsFromModel(fitLogistic(train, params), fallback)
+		estimate := estimatePrecision(test, weights, SignificantSimilarityThreshold)
+		total.TP += estimate.TP
+		total.FP += estimate.FP
+		total.TN += estimate.TN
+		total.FN += estimate.FN
+	}
+
+	total.Precision, total.Recall = precisionRecall(total.TP, total.FP, total.FN)
+	return total
+}
+
+// estimatePrecision classifies the pairs whose weighted score reaches threshold
+func estimatePrecision(samples []LabelledPair, weights Weights, threshold float64) PrecisionEstimate {
+	estimate := PrecisionEstimate{Threshold: threshold}
+	for _, sample := range samples {
+		flagged := weightedScore(sample.Scores, weights) >= threshold
+		switch {
+		case flagged && sample.Confirmed:
+			estimate.TP++
+		case flagged:
+			estimate.FP++
+		case sample.Confirmed:
+			estimate.FN++
+		default:
+			estimate.TN++
+		}
+	}
+	estimate.Precision, estimate.Recall = precisionRecall(estimate.TP, estimate.FP, estimate.FN)
+	return estimate
+}
+
+func precisionRecall(tp, fp, fn int) (float64, float64) {
+	precision, recall := 0.0, 0.0
+	if tp+fp > 0 {
+		precision = float64(tp) / float64(tp+fp)
+	}
+	if tp+fn > 0 {
+		recall = float64(tp) / float64(tp+fn)
+	}
+	return precision, recall
+}
+
+// confirmedBounds returns, after the fingerprint, token and AST layers, the lowest
+// bound the cascade checks a confirmed pair against: its weighted score so far plus the
+// weight of the layers still to run (see shouldShortCircuit). A threshold at or below
+// the bound of a layer cuts no confirmed pair short there.
+func confirmedBounds(samples []LabelledPair, weights Weights) [3]float64 {
+	layerWeights := [4]float64{weights.Fingerprint, weights.Token, weights.AST, weights.CFG}
+	bounds := [3]float64{math.Inf(1), math.Inf(1), math.Inf(1)}
+	for _, sample := range samples {
+		if !sample.Confirmed {
+			continue
+		}
+		scores := layerVector(sample.Scores)
+		current, remaining := 0.0, layerWeights[0]+layerWeights[1]+layerWeights[2]+layerWeights[3]
+		for layer := range bounds {
+			current += scores[layer] * layerWeights[layer]
+			remaining -= layerWeights[layer]
+			bounds[layer] = math.Min(bounds[layer], current+remaining)
+		}
+	}
+	return bounds
+}
+
+func weightedScore(scores SimilarityScores, weights Weights) float64 {
+	return scores.Fingerprint*weights.Fingerprint + scores.Token*weights.Token +
+		scores.AST*weights.AST + scores.CFG*weights.CFG
+}
+
+func layerVector(scores SimilarityScores) [4]float64 {
+	return [4]float64{scores.Fingerprint, scores.Token, scores.AST, scores.CFG}
+}
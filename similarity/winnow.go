@@ -0,0 +1,139 @@
+package similarity
+
+import (
+	"fmt"
+	"hash/fnv"
+	"strings"
+
+	"github.com/RishiKendai/aegis/similarity/model"
+)
+
+// Winnowing parameters used when the artifact does not record its own
+const (
+	defaultKGramSize  = 5
+	defaultWindowSize = 4
+)
+
+// FingerprintSet returns the unique fingerprint hashes of an artifact. Without passes
+// these are the stored fingerprints; with passes they are the k-grams of the normalized
+// tokens, taken from NormalizedFingerprints when they were built with the same passes
+// and winnowed again otherwise, so the worthy-pair filter and the fingerprint layer
+// see the same canonical code.
+func FingerprintSet(artifact *model.Artifact, passes ...NormalizationPass) map[string]bool {
+	if len(passes) == 0 {
+		return hashSet(artifact.Fingerprints)
+	}
+	if artifact.NormalizedFingerprints != nil && HasFingerprintsFor(artifact, passes) {
+		return hashSet(artifact.NormalizedFingerprints)
+	}
+	tokens, _ := NormalizeTokens(artifact.NormalizedTokens, passes)
+	return hashSet(winnowArtifact(artifact, tokens))
+}
+
+// NormalizeFingerprints sets the artifact's NormalizedFingerprints from its normalized
+// tokens after the passes, or clears them when there are none
+func NormalizeFingerprints(artifact *model.Artifact, passes []NormalizationPass) {
+	artifact.NormalizedFingerprints = nil
+	artifact.FingerprintPasses = nil
+	if len(passes) == 0 {
+		return
+	}
+
+	tokens, _ := NormalizeTokens(artifact.NormalizedTokens, passes)
+	artifact.NormalizedFingerprints = winnowArtifact(artifact, tokens)
+	artifact.FingerprintPasses = make([]string, len(passes))
+	for i, pass := range passes {
+		artifact.FingerprintPasses[i] = string(pass)
+	}
+}
+
+// HasFingerprintsFor reports whether the artifact's NormalizedFingerprints were built
+// with exactly the passes, in any order; with no passes, whether there are none
+func HasFingerprintsFor(artifact *model.Artifact, passes []NormalizationPass) bool {
+	built := make(map[string]bool, len(artifact.FingerprintPasses))
+	for _, pass := range artifact.FingerprintPasses {
+		built[pass] = true
+	}
+	wanted := make(map[string]bool, len(passes))
+	for _, pass := range passes {
+		wanted[string(pass)] = true
+	}
+	if len(built) != len(wanted) {
+		return false
+	}
+	for pass := range wanted {
+		if !built[pass] {
+			return false
+		}
+	}
+	return true
+}
+
+func hashSet(fingerprints *model.Fingerprints) map[string]bool {
+	if fingerprints == nil {
+		return nil
+	}
+	hashes := make(map[string]bool, len(fingerprints.Hashes))
+	for _, hashEntry := range fingerprints.Hashes {
+		hashes[hashEntry.Hash] = true
+	}
+	return hashes
+}
+
+// winnowArtifact winnows tokens with the k-gram and window sizes of the artifact's stored
+// fingerprints; without tokens the stored fingerprints are kept
+func winnowArtifact(artifact *model.Artifact, tokens []string) *model.Fingerprints {
+	if len(tokens) == 0 {
+		return artifact.Fingerprints
+	}
+	k, window := defaultKGramSize, defaultWindowSize
+	if artifact.Fingerprints != nil && artifact.Fingerprints.KGramSize > 0 && artifact.Fingerprints.WindowSize > 0 {
+		k, window = artifact.Fingerprints.KGramSize, artifact.Fingerprints.WindowSize
+	}
+	return winnow(tokens, k, window)
+}
+
+// winnow selects the minimum k-gram hash of every window, as the local preprocessor does
+func winnow(tokens []string, k, window int) *model.Fingerprints {
+	fingerprints := &model.Fingerprints{
+		Method:     "winnowing",
+		KGramSize:  k,
+		WindowSize: window,
+		Hashes:     make([]model.HashEntry, 0),
+	}
+	if len(tokens) < k {
+		return fingerprints
+	}
+
+	hashes := make([]uint64, 0, len(tokens)-k+1)
+	for i := 0; i+k <= len(tokens); i++ {
+		h := fnv.New64a()
+		h.Write([]byte(strings.Join(tokens[i:i+k], " ")))
+		hashes = append(hashes, h.Sum64())
+	}
+
+	lastPosition := -1
+	for start := 0; start+window <= max(len(hashes), window); start++ {
+		end := min(start+window, len(hashes))
+
+		// Rightmost minimum, as in the original winnowing paper
+		position := start
+		for i := start; i < end; i++ {
+			if hashes[i] <= hashes[position] {
+				position = i
+			}
+		}
+		if position != lastPosition {
+			fingerprints.Hashes = append(fingerprints.Hashes, model.HashEntry{
+				Hash:     fmt.Sprintf("%016x", hashes[position]),
+				Position: position,
+			})
+			lastPosition = position
+		}
+		if end == len(hashes) {
+			break
+		}
+	}
+
+	return fingerprints
+}